@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"AIAgent/internal/agent"
+	"AIAgent/internal/config"
+)
+
+// batchTask — строка входного JSONL-файла batch.
+type batchTask struct {
+	ID       string `json:"id,omitempty"`
+	Task     string `json:"task"`
+	StartURL string `json:"start_url,omitempty"`
+	// Account — учётная запись, чьи куки и localStorage получает контекст задачи
+	// (только с -parallel, см. execParallel).
+	Account string `json:"account,omitempty"`
+}
+
+// batchResult — строка выходного JSONL.
+type batchResult struct {
+	ID string `json:"id,omitempty"`
+	agent.Result
+}
+
+// runCmd: agent run -task "..." — одна задача, результат одним JSON-объектом.
+func runCmd(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	task := fs.String("task", "", "текст задачи")
+	startURL := fs.String("url", "", "открыть URL перед началом задачи")
+	outPath := fs.String("out", "", "файл для JSON-результата (по умолчанию stdout)")
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+	if strings.TrimSpace(*task) == "" {
+		fmt.Fprintln(os.Stderr, "run: нужен -task")
+		return exitUsage
+	}
+	return execTasks(cfg, []batchTask{{Task: *task, StartURL: *startURL}}, *outPath, isolateNone)
+}
+
+// batchCmd: agent batch tasks.jsonl — задачи по одной на строку, результаты в JSONL.
+func batchCmd(args []string) int {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	outPath := fs.String("out", "", "файл для JSONL-результатов (по умолчанию stdout)")
+	isolate := fs.String("isolate", isolateNone, "изоляция задач: none, page, context")
+	parallel := fs.Int("parallel", 1, "выполнять до N задач одновременно, каждую во временном контексте общего браузера: "+
+		"куки и localStorage берутся из storage state учётной записи задачи (поле account), постоянный профиль не открывается; "+
+		"не сочетается с -isolate. Без флага задачи идут по одной в постоянном профиле")
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "использование: agent batch [флаги] tasks.jsonl")
+		return exitUsage
+	}
+	switch *isolate {
+	case isolateNone, isolatePage, isolateContext:
+	default:
+		fmt.Fprintf(os.Stderr, "batch: неизвестный режим -isolate=%s\n", *isolate)
+		return exitUsage
+	}
+	tasks, err := readTasks(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "batch:", err)
+		return exitUsage
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	switch {
+	case !set["parallel"]:
+		if hasAccounts(tasks) {
+			fmt.Fprintln(os.Stderr, "batch: поле account работает только с -parallel: без него задачи идут в постоянном профиле, а не в контекстах учётных записей")
+			return exitUsage
+		}
+		return execTasks(cfg, tasks, *outPath, *isolate)
+	case *parallel < 1:
+		fmt.Fprintln(os.Stderr, "batch: -parallel должен быть не меньше 1")
+		return exitUsage
+	case set["isolate"]:
+		fmt.Fprintln(os.Stderr, "batch: -isolate не сочетается с -parallel: каждая задача и так получает свой временный контекст")
+		return exitUsage
+	}
+	return execParallel(cfg, tasks, *outPath, *parallel)
+}
+
+func hasAccounts(tasks []batchTask) bool {
+	for _, t := range tasks {
+		if t.Account != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func readTasks(path string) ([]batchTask, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tasks []batchTask
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var t batchTask
+		if err := json.Unmarshal([]byte(line), &t); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if strings.TrimSpace(t.Task) == "" {
+			return nil, fmt.Errorf("%s:%d: empty task", path, n)
+		}
+		if t.ID == "" {
+			t.ID = fmt.Sprint(n)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, sc.Err()
+}
+
+// execTasks выполняет задачи последовательно и пишет по JSON-строке на задачу.
+// Ход выполнения печатается в stderr, чтобы stdout оставался машиночитаемым.
+func execTasks(cfg config.Config, tasks []batchTask, outPath, isolate string) int {
+	// Ctrl+C/SIGTERM отменяет текущую задачу; оставшиеся не запускаются,
+	// браузер закрывается штатно.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var out io.Writer = os.Stdout
+	if outPath != "" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		defer f.Close()
+		out = f
+	}
+	cfg.Agent.Log = os.Stderr
+
+	sess, err := openSession(ctx, cfg.Browser)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось запустить браузер:", err)
+		return exitBrowser
+	}
+	defer sess.close()
+
+	enc := json.NewEncoder(out)
+	code := exitOK
+	for i, t := range tasks {
+		var (
+			res    agent.Result
+			runErr error
+		)
+		if i > 0 {
+			if err := sess.fresh(ctx, isolate); err != nil {
+				fmt.Fprintln(os.Stderr, "Не удалось подготовить браузер:", err)
+				return exitBrowser
+			}
+		}
+		if t.StartURL != "" {
+			_, runErr = sess.page.Goto(t.StartURL)
+		}
+		if runErr == nil {
+			res, runErr = agent.Run(ctx, cfg.Agent, sess.page, t.Task)
+			if res.Page != nil {
+				sess.page = res.Page
+			}
+		} else {
+			res = agent.Result{Task: t.Task, Status: agent.StatusError, Errors: []string{runErr.Error()}}
+		}
+		if runErr != nil || res.Status != agent.StatusDone {
+			code = exitTaskFailed
+		}
+		if err := enc.Encode(batchResult{ID: t.ID, Result: res}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitTaskFailed
+		}
+		if errors.Is(runErr, context.Canceled) {
+			break
+		}
+	}
+	return code
+}
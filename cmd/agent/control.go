@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"AIAgent/internal/agent"
+)
+
+const controlHelp = `Команды во время задачи:
+  :pause            пауза после текущего шага
+  :resume           продолжить
+  :step             выполнить один шаг и снова встать на паузу
+  :hint <текст>     подсказка планировщику
+  :takeover         взять браузер в свои руки (агент ждёт)
+  :handback         вернуть управление агенту
+  :stop             прервать задачу
+  :click <N|css>    следующее действие — клик по кандидату #N или селектору
+  :type <N|css> <текст>
+  :goto <url>
+  :press <клавиша>
+  :scroll <dy>
+  :do <tool> {json-аргументы}`
+
+// controlCommand применяет строку-команду оператора к ctrl.
+// stop вызывается для :stop. Возвращает сообщение для пользователя.
+func controlCommand(ctrl *agent.Control, line string, stop func()) (string, error) {
+	cmd, rest, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch cmd {
+	case "help", "h", "?":
+		return controlHelp, nil
+	case "pause", "p":
+		ctrl.Pause()
+		return "пауза после текущего шага", nil
+	case "resume", "continue", "c":
+		ctrl.Resume()
+		return "", nil
+	case "step", "s":
+		ctrl.Step()
+		return "", nil
+	case "hint":
+		if rest == "" {
+			return "", fmt.Errorf(":hint: нужен текст")
+		}
+		ctrl.Hint(rest)
+		return "подсказка будет учтена на следующем шаге", nil
+	case "takeover":
+		ctrl.TakeOver()
+		return "", nil
+	case "handback":
+		ctrl.HandBack()
+		return "", nil
+	case "stop":
+		stop()
+		return "", nil
+	case "click":
+		args, err := targetArgs(rest)
+		if err != nil {
+			return "", err
+		}
+		ctrl.Override("click", args)
+	case "type":
+		target, text, _ := strings.Cut(rest, " ")
+		args, err := targetArgs(target)
+		if err != nil {
+			return "", err
+		}
+		args["text"] = text
+		ctrl.Override("type", args)
+	case "goto":
+		if rest == "" {
+			return "", fmt.Errorf(":goto: нужен url")
+		}
+		ctrl.Override("goto_url", map[string]any{"url": rest})
+	case "press":
+		ctrl.Override("press", map[string]any{"key": rest})
+	case "scroll":
+		dy, err := strconv.ParseFloat(rest, 64)
+		if err != nil {
+			return "", fmt.Errorf(":scroll: %w", err)
+		}
+		ctrl.Override("scroll", map[string]any{"y": dy})
+	case "do":
+		tool, raw, _ := strings.Cut(rest, " ")
+		args := map[string]any{}
+		if strings.TrimSpace(raw) != "" {
+			if err := json.Unmarshal([]byte(raw), &args); err != nil {
+				return "", fmt.Errorf(":do: аргументы должны быть JSON-объектом: %w", err)
+			}
+		}
+		ctrl.Override(tool, args)
+	default:
+		return "", fmt.Errorf("неизвестная команда :%s (:help — список)", cmd)
+	}
+	return "действие выполнится следующим шагом", nil
+}
+
+// targetArgs: число — ссылка на кандидата, иначе CSS-селектор.
+func targetArgs(s string) (map[string]any, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("нужен номер кандидата или селектор")
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(s, "#")); err == nil {
+		return map[string]any{"ref": n}, nil
+	}
+	return map[string]any{"selector": s}, nil
+}
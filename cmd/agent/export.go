@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"AIAgent/internal/config"
+	"AIAgent/internal/export"
+)
+
+// exportCmd: agent export <run-id|каталог> — превратить успешную траекторию в скрипт на playwright-go.
+func exportCmd(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("o", "", "файл для результата (по умолчанию stdout)")
+	asTest := fs.Bool("test", false, "сгенерировать _test.go вместо программы с main")
+	pkg := fs.String("package", "", "имя пакета (по умолчанию main или flow_test)")
+	name := fs.String("name", "", "имя теста: TestИмя (по умолчанию Flow)")
+	force := fs.Bool("force", false, "экспортировать и неуспешную траекторию")
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "использование: agent export [флаги] <run-id|каталог траектории>")
+		return exitUsage
+	}
+	t, err := loadTrajectory(cfg, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	src, err := export.GoScript(t, export.Options{
+		Test:     *asTest,
+		Package:  *pkg,
+		Name:     *name,
+		Headless: cfg.Browser.Headless,
+		Force:    *force,
+		Warn:     func(msg string) { fmt.Fprintln(os.Stderr, "export: warning:", msg) },
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v (статус записи: %q)\n", err, t.Meta.Status)
+		return exitTaskFailed
+	}
+	if *out == "" {
+		_, _ = os.Stdout.Write(src)
+		return exitOK
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitTaskFailed
+	}
+	fmt.Fprintln(os.Stderr, "Скрипт записан в", *out)
+	return exitOK
+}
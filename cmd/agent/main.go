@@ -3,50 +3,209 @@ package main
 import (
 	"bufio"
 	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 
 	"AIAgent/internal/agent"
-	"AIAgent/internal/browser"
+	"AIAgent/internal/config"
+)
 
-	"github.com/playwright-community/playwright-go"
+// Коды выхода для run/batch.
+const (
+	exitOK         = 0
+	exitTaskFailed = 1
+	exitUsage      = 2
+	exitBrowser    = 3
 )
 
 func main() {
-	ctx := context.Background()
+	args := os.Args[1:]
+	cmd := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
 
-	pdir, err := browser.DefaultProfileDir("aiagent")
-	if err != nil {
-		panic(err)
+	switch cmd {
+	case "":
+		os.Exit(repl(args))
+	case "run":
+		os.Exit(runCmd(args))
+	case "batch":
+		os.Exit(batchCmd(args))
+	case "serve":
+		os.Exit(serveCmd(args))
+	case "resume":
+		os.Exit(resumeCmd(args))
+	case "runs":
+		os.Exit(runsCmd(args))
+	case "replay":
+		os.Exit(replayCmd(args))
+	case "export":
+		os.Exit(exportCmd(args))
+	case "workflow":
+		os.Exit(workflowCmd(args))
+	case "skills":
+		os.Exit(skillsCmd(args))
+	case "spam":
+		os.Exit(spamCmd(args))
+	case "storage":
+		os.Exit(storageCmd(args))
+	default:
+		fmt.Fprintf(os.Stderr, "неизвестная команда %q (доступны: run, batch, serve, resume, runs, replay, export, workflow, skills, spam, storage)\n", cmd)
+		os.Exit(exitUsage)
 	}
-	pw, bctx, page, err := browser.LaunchPersistent(ctx, pdir, true)
+}
+
+// stdinLines читает stdin построчно в канал, чтобы чтение можно было
+// прерывать отменой контекста. Канал закрывается на EOF.
+func stdinLines() <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			ch <- strings.TrimSpace(sc.Text())
+		}
+	}()
+	return ch
+}
+
+func repl(args []string) int {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	cfg, err := config.Parse(fs, args)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
 	}
-	defer func() {
-		_ = bctx.Close()
-		_ = pw.Stop()
+
+	// Ctrl+C во время задачи отменяет только её; в ожидании ввода — завершает программу.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	quit, stop := context.WithCancel(context.Background())
+	defer stop()
+	var (
+		mu         sync.Mutex
+		cancelTask context.CancelFunc
+	)
+	go func() {
+		for sig := range sigs {
+			mu.Lock()
+			c := cancelTask
+			mu.Unlock()
+			if c != nil && sig == os.Interrupt {
+				fmt.Println("\n[agent] Прерывание: останавливаю текущую задачу…")
+				c()
+				continue
+			}
+			stop()
+			return
+		}
 	}()
 
-	fmt.Println("AI-браузер запущен. Опишите задачу (одной строкой).")
-	reader := bufio.NewReader(os.Stdin)
+	sess, err := openSession(quit, cfg.Browser)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось запустить браузер:", err)
+		return exitBrowser
+	}
+	defer sess.close()
+
+	fmt.Println("AI-браузер запущен. Опишите задачу (одной строкой). Во время задачи доступны команды, :help — список.")
+	lines := stdinLines()
+	answers := make(chan string, 1)
+	var asking atomic.Bool
+	cfg.Agent.AskUser = func(ctx context.Context, question string) (string, error) {
+		fmt.Printf("\n[agent] %s\n? ", question)
+		asking.Store(true)
+		defer asking.Store(false)
+		select {
+		case answer := <-answers:
+			return answer, nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
 
 	for {
 		fmt.Print("\n> ")
-		task, _ := reader.ReadString('\n')
-		task = strings.TrimSpace(task)
-		if task == "" {
+		var task string
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return exitOK
+			}
+			task = line
+		case <-quit.Done():
+			fmt.Println("\nПока!")
+			return exitOK
+		}
+		if task == "" || strings.HasPrefix(task, ":") {
 			continue
 		}
 		if strings.EqualFold(task, "exit") || strings.EqualFold(task, "quit") {
 			fmt.Println("Пока!")
-			return
+			return exitOK
 		}
 
-		var br playwright.Browser = nil
-		if err := agent.Run(ctx, br, page, task); err != nil {
-			fmt.Println("Ошибка задачи:", err)
+		ctx, cancel := context.WithCancel(quit)
+		mu.Lock()
+		cancelTask = cancel
+		mu.Unlock()
+
+		ctrl := agent.NewControl()
+		taskCfg := cfg.Agent
+		taskCfg.Control = ctrl
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			res, err := agent.Run(ctx, taskCfg, sess.page, task)
+			if err != nil {
+				fmt.Println("Ошибка задачи:", err)
+			}
+			// Следующая задача начинается на вкладке, где агент закончил.
+			if res.Page != nil {
+				sess.page = res.Page
+			}
+		}()
+
+		// Пока задача идёт, строки stdin — это команды оператора или ответы на вопросы агента.
+		for running := true; running; {
+			select {
+			case line, ok := <-lines:
+				switch {
+				case !ok:
+					cancel()
+					<-done
+					return exitOK
+				case strings.HasPrefix(line, ":"):
+					msg, err := controlCommand(ctrl, line, cancel)
+					if err != nil {
+						fmt.Println("[agent]", err)
+					} else if msg != "" {
+						fmt.Println("[agent]", msg)
+					}
+				case asking.Load():
+					select {
+					case answers <- line:
+					default:
+					}
+				case line != "":
+					fmt.Println("[agent] задача выполняется; команды начинаются с ':' (:help — список)")
+				}
+			case <-done:
+				running = false
+			}
 		}
+
+		mu.Lock()
+		cancelTask = nil
+		mu.Unlock()
+		cancel()
 	}
 }
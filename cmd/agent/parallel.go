@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"AIAgent/internal/agent"
+	"AIAgent/internal/browser"
+	"AIAgent/internal/checkpoint"
+	"AIAgent/internal/config"
+)
+
+// execParallel выполняет задачи одновременно, не больше n за раз: у каждой
+// свой контекст из общего браузера, своя память и инструменты агента.
+// Результаты пишутся в порядке завершения, сводка — в stderr.
+func execParallel(cfg config.Config, tasks []batchTask, outPath string, n int) int {
+	// Ctrl+C/SIGTERM отменяет все идущие задачи; ещё не начатые не запускаются.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var out io.Writer = os.Stdout
+	if outPath != "" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		defer f.Close()
+		out = f
+	}
+	if n > len(tasks) {
+		n = len(tasks)
+	}
+	pool, err := browser.NewPool(ctx, cfg.Browser, n)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось запустить браузер:", err)
+		return exitBrowser
+	}
+	defer pool.Close()
+
+	// Одна строка журнала — одна запись: параллельные задачи не перемешивают вывод.
+	var logMu sync.Mutex
+	// Уникальный RunID на задачу: каталоги загрузок, контрольные точки и
+	// траектории задач, стартовавших в одну секунду, не должны совпасть.
+	base := checkpoint.NewRunID()
+
+	var (
+		mu      sync.Mutex
+		enc     = json.NewEncoder(out)
+		results []batchResult
+		encErr  error
+	)
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	started := time.Now()
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				t := tasks[i]
+				acfg := cfg.Agent
+				acfg.RunID = fmt.Sprintf("%s-%d", base, i+1)
+				// Ручное управление и вопросы пользователю — только для одной задачи в терминале.
+				acfg.Control, acfg.AskUser = nil, nil
+				prefix := "[" + t.ID + "] "
+				if acfg.LogFormat == "json" {
+					prefix = ""
+				}
+				lw := &lineWriter{mu: &logMu, w: os.Stderr, prefix: prefix}
+				acfg.Log = lw
+				res := runPooled(ctx, pool, acfg, t)
+				lw.flush()
+
+				mu.Lock()
+				r := batchResult{ID: t.ID, Result: res}
+				results = append(results, r)
+				if err := enc.Encode(r); err != nil && encErr == nil {
+					encErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range tasks {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	printSummary(os.Stderr, results, len(tasks), time.Since(started))
+	if encErr != nil {
+		fmt.Fprintln(os.Stderr, encErr)
+		return exitTaskFailed
+	}
+	if len(results) < len(tasks) {
+		return exitTaskFailed
+	}
+	for _, r := range results {
+		if r.Status != agent.StatusDone {
+			return exitTaskFailed
+		}
+	}
+	return exitOK
+}
+
+// runPooled выполняет одну задачу в контексте из пула; ошибки подготовки
+// становятся результатом со статусом error.
+func runPooled(ctx context.Context, pool *browser.Pool, cfg agent.Config, t batchTask) agent.Result {
+	failed := func(err error) agent.Result {
+		status := agent.StatusError
+		if errors.Is(err, context.Canceled) {
+			status = agent.StatusCanceled
+		}
+		return agent.Result{RunID: cfg.RunID, Task: t.Task, Status: status, Errors: []string{err.Error()}}
+	}
+	lease, err := pool.Acquire(ctx, t.Account)
+	if err != nil {
+		return failed(err)
+	}
+	defer lease.Release()
+	if t.StartURL != "" {
+		if _, err := lease.Page.Goto(t.StartURL); err != nil {
+			return failed(err)
+		}
+	}
+	res, err := agent.Run(ctx, cfg, lease.Page, t.Task)
+	if err != nil && len(res.Errors) == 0 {
+		res.Errors = []string{err.Error()}
+	}
+	return res
+}
+
+// printSummary печатает итог параллельного запуска: сколько задач в каком статусе.
+func printSummary(w io.Writer, results []batchResult, total int, took time.Duration) {
+	counts := map[string]int{}
+	var busy time.Duration
+	for _, r := range results {
+		counts[r.Status]++
+		busy += r.Duration
+	}
+	if skipped := total - len(results); skipped > 0 {
+		counts["not started"] = skipped
+	}
+	statuses := make([]string, 0, len(counts))
+	for s := range counts {
+		statuses = append(statuses, s)
+	}
+	sort.Strings(statuses)
+	parts := make([]string, len(statuses))
+	for i, s := range statuses {
+		parts[i] = fmt.Sprintf("%s: %d", s, counts[s])
+	}
+	fmt.Fprintf(w, "Итого %d задач за %s (суммарно %s): %s\n",
+		total, took.Round(time.Second), busy.Round(time.Second), strings.Join(parts, ", "))
+}
+
+// lineWriter пишет в общий w целыми строками под общим мьютексом mu, добавляя
+// к каждой prefix — так видно, к какой задаче относится строка журнала.
+type lineWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	i := bytes.LastIndexByte(l.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	l.emit(l.buf[:i+1])
+	l.buf = append(l.buf[:0], l.buf[i+1:]...)
+	return len(p), nil
+}
+
+// flush дописывает незавершённую последнюю строку.
+func (l *lineWriter) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buf) > 0 {
+		l.emit(append(l.buf, '\n'))
+		l.buf = l.buf[:0]
+	}
+}
+
+func (l *lineWriter) emit(lines []byte) {
+	var b bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) > 0 {
+			b.WriteString(l.prefix)
+			b.Write(line)
+		}
+	}
+	_, _ = l.w.Write(b.Bytes())
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"AIAgent/internal/agent"
+	"AIAgent/internal/config"
+	"AIAgent/internal/trajectory"
+)
+
+// replayCmd: agent replay <run-id|каталог> — повторить записанные действия без LLM.
+func replayCmd(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	baseURL := fs.String("base-url", "", "заменить схему и хост записанных адресов (фикстурный сайт)")
+	stopOnDiverge := fs.Bool("stop", false, "остановиться на первом расхождении")
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "использование: agent replay [флаги] <run-id|каталог траектории>")
+		return exitUsage
+	}
+	t, err := loadTrajectory(cfg, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sess, err := openSession(ctx, cfg.Browser)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось запустить браузер:", err)
+		return exitBrowser
+	}
+	defer sess.close()
+
+	rep, err := agent.Replay(ctx, cfg.Agent, sess.page, t, agent.ReplayOptions{BaseURL: *baseURL, StopOnDivergence: *stopOnDiverge})
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(rep)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		return exitTaskFailed
+	}
+	if !rep.OK {
+		return exitTaskFailed
+	}
+	return exitOK
+}
+
+// loadTrajectory принимает путь к каталогу траектории или run-id из каталога по умолчанию.
+func loadTrajectory(cfg config.Config, ref string) (*trajectory.Trajectory, error) {
+	if st, err := os.Stat(ref); err == nil && st.IsDir() {
+		return trajectory.Load(ref)
+	}
+	return trajectory.Load(filepath.Join(cfg.Agent.Trajectory.Dir, ref))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"AIAgent/internal/agent"
+	"AIAgent/internal/checkpoint"
+	"AIAgent/internal/config"
+)
+
+// resumeCmd: agent resume <run-id> — продолжить прерванную задачу.
+func resumeCmd(args []string) int {
+	fs := flag.NewFlagSet("resume", flag.ExitOnError)
+	force := fs.Bool("force", false, "продолжить даже завершённый запуск")
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "использование: agent resume [флаги] <run-id> (список — agent runs)")
+		return exitUsage
+	}
+	store := checkpoint.Store{Dir: cfg.Agent.Checkpoints.Dir}
+	cp, err := store.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if cp.Status == agent.StatusDone && !*force {
+		fmt.Fprintf(os.Stderr, "запуск %s уже завершён (-force, чтобы продолжить)\n", cp.RunID)
+		return exitUsage
+	}
+	if cp.Step >= cfg.Agent.MaxSteps {
+		fmt.Fprintf(os.Stderr, "запуск %s уже сделал %d шагов из %d; увеличьте -max-steps\n", cp.RunID, cp.Step, cfg.Agent.MaxSteps)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sess, err := openSession(ctx, cfg.Browser)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось запустить браузер:", err)
+		return exitBrowser
+	}
+	defer sess.close()
+
+	cfg.Agent.Checkpoints.Enabled = true
+	cfg.Agent.Log = os.Stderr
+	res, err := agent.Resume(ctx, cfg.Agent, sess.page, cp)
+	_ = json.NewEncoder(os.Stdout).Encode(res)
+	if err != nil || res.Status != agent.StatusDone {
+		return exitTaskFailed
+	}
+	return exitOK
+}
+
+// runsCmd: agent runs — список сохранённых запусков.
+func runsCmd(args []string) int {
+	fs := flag.NewFlagSet("runs", flag.ExitOnError)
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+	cps, err := checkpoint.Store{Dir: cfg.Agent.Checkpoints.Dir}.List()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitTaskFailed
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tSTATUS\tSTEP\tUPDATED\tTASK")
+	for _, cp := range cps {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", cp.RunID, cp.Status, cp.Step, cp.Updated.Format("2006-01-02 15:04"), cp.Task)
+	}
+	_ = tw.Flush()
+	return exitOK
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"AIAgent/internal/config"
+	"AIAgent/internal/server"
+)
+
+// serveCmd: agent serve -addr 127.0.0.1:8080 — HTTP API поверх очереди задач.
+func serveCmd(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "адрес HTTP-сервера")
+	queue := fs.Int("queue", 64, "максимальная длина очереди задач")
+	keep := fs.Int("keep", 256, "сколько завершённых задач хранить; более старые забываются")
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	sess, err := openSession(ctx, cfg.Browser)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось запустить браузер:", err)
+		return exitBrowser
+	}
+	defer sess.close()
+
+	cfg.Agent.Log = os.Stderr
+	srv := server.New(cfg.Agent, sess.page, *queue)
+	srv.Keep = *keep
+	fmt.Fprintf(os.Stderr, "API агента слушает http://%s\n", *addr)
+	if err := srv.ListenAndServe(ctx, *addr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitTaskFailed
+	}
+	return exitOK
+}
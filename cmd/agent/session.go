@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"AIAgent/internal/browser"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Режимы изоляции задач друг от друга в run/batch.
+const (
+	isolateNone    = "none"    // все задачи в одной вкладке
+	isolatePage    = "page"    // новая вкладка на каждую задачу
+	isolateContext = "context" // перезапуск контекста браузера на каждую задачу
+)
+
+// session держит запущенный браузер и текущую вкладку.
+type session struct {
+	opts browser.Options
+	pw   *playwright.Playwright
+	bctx playwright.BrowserContext
+	page playwright.Page
+}
+
+func openSession(ctx context.Context, opts browser.Options) (*session, error) {
+	s := &session{opts: opts}
+	if err := s.launch(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *session) launch(ctx context.Context) error {
+	pw, bctx, page, err := browser.Launch(ctx, s.opts)
+	if err != nil {
+		return err
+	}
+	s.pw, s.bctx, s.page = pw, bctx, page
+	return nil
+}
+
+// fresh готовит вкладку под следующую задачу согласно режиму изоляции.
+func (s *session) fresh(ctx context.Context, mode string) error {
+	switch mode {
+	case "", isolateNone:
+		return nil
+	case isolatePage:
+		page, err := s.bctx.NewPage()
+		if err != nil {
+			return err
+		}
+		if s.page != nil {
+			_ = s.page.Close()
+		}
+		s.page = page
+		return nil
+	case isolateContext:
+		s.close()
+		return s.launch(ctx)
+	default:
+		return fmt.Errorf("unknown isolation mode: %s", mode)
+	}
+}
+
+func (s *session) close() {
+	if s.bctx != nil {
+		_ = s.bctx.Close()
+	}
+	if s.pw != nil {
+		_ = s.pw.Stop()
+	}
+	s.pw, s.bctx, s.page = nil, nil, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"AIAgent/internal/config"
+	"AIAgent/internal/skill"
+)
+
+// skillsCmd: agent skills [list | show <имя> | save <run-id>] — библиотека навыков.
+func skillsCmd(args []string) int {
+	sub := "list"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		sub, args = args[0], args[1:]
+	}
+	switch sub {
+	case "list":
+		return skillsList(args)
+	case "show":
+		return skillsShow(args)
+	case "save":
+		return skillsSave(args)
+	default:
+		fmt.Fprintf(os.Stderr, "skills: неизвестная команда %q (доступны: list, show, save)\n", sub)
+		return exitUsage
+	}
+}
+
+func openSkills(fs *flag.FlagSet, args []string) (*skill.Library, int) {
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return nil, exitUsage
+	}
+	lib, err := skill.Open(cfg.Agent.Skills.Dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, exitUsage
+	}
+	return lib, exitOK
+}
+
+func skillsList(args []string) int {
+	fs := flag.NewFlagSet("skills list", flag.ExitOnError)
+	lib, code := openSkills(fs, args)
+	if lib == nil {
+		return code
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SKILL\tSOURCE\tDESCRIPTION")
+	for _, s := range lib.List() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Usage(), s.Source, s.Description)
+	}
+	_ = tw.Flush()
+	return exitOK
+}
+
+func skillsShow(args []string) int {
+	fs := flag.NewFlagSet("skills show", flag.ExitOnError)
+	lib, code := openSkills(fs, args)
+	if lib == nil {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "использование: agent skills show <имя>")
+		return exitUsage
+	}
+	s, err := lib.Get(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitTaskFailed
+	}
+	b, err := skill.Marshal(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitTaskFailed
+	}
+	_, _ = os.Stdout.Write(b)
+	return exitOK
+}
+
+// skillsSave: agent skills save -name имя [-param имя=значение]... <run-id> —
+// сохранить успешный запуск как навык.
+func skillsSave(args []string) int {
+	fs := flag.NewFlagSet("skills save", flag.ExitOnError)
+	name := fs.String("name", "", "имя навыка (латиница, цифры, _)")
+	desc := fs.String("description", "", "описание для планировщика (по умолчанию — текст задачи)")
+	params := varsFlag{}
+	fs.Var(params, "param", "сделать значение параметром: имя=значение (можно повторять)")
+	force := fs.Bool("force", false, "сохранить и неуспешный запуск")
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+	if fs.NArg() != 1 || *name == "" {
+		fmt.Fprintln(os.Stderr, "использование: agent skills save -name имя [флаги] <run-id|каталог траектории>")
+		return exitUsage
+	}
+	t, err := loadTrajectory(cfg, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if t.Meta.Status != "done" && !*force {
+		fmt.Fprintf(os.Stderr, "skills save: запуск завершился со статусом %q (используйте -force)\n", t.Meta.Status)
+		return exitTaskFailed
+	}
+	sk, err := skill.FromTrajectory(t, *name, *desc, params)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitTaskFailed
+	}
+	lib, err := skill.Open(cfg.Agent.Skills.Dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	path, err := lib.Save(sk)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitTaskFailed
+	}
+	fmt.Fprintf(os.Stderr, "Навык %s сохранён в %s (шагов: %d)\n", sk.Name, path, len(sk.Steps))
+	return exitOK
+}
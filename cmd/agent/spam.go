@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"AIAgent/internal/config"
+	"AIAgent/internal/spam"
+)
+
+// spamCmd: agent spam [stats | label | import | score] — локальный классификатор писем.
+func spamCmd(args []string) int {
+	sub := "stats"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		sub, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("spam "+sub, flag.ExitOnError)
+	var ex spam.Example
+	if sub == "label" || sub == "score" {
+		fs.StringVar(&ex.From, "from", "", "отправитель (имя и/или адрес)")
+		fs.StringVar(&ex.Subject, "subject", "", "тема")
+		fs.StringVar(&ex.Body, "body", "", "текст письма")
+	}
+	if sub == "label" {
+		fs.StringVar(&ex.Label, "label", "", "метка: spam, ham, important, ...")
+	}
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+	cl, err := spam.Open(cfg.Agent.Spam.Dir, cfg.Agent.Spam.Threshold)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	switch sub {
+	case "stats":
+		total, user := cl.Stats()
+		labels := make([]string, 0, len(total))
+		for l := range total {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "LABEL\tEXAMPLES\tUSER")
+		for _, l := range labels {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", l, total[l], user[l])
+		}
+		_ = tw.Flush()
+	case "label":
+		if ex.Label == "" || (ex.Subject == "" && ex.Body == "") {
+			fmt.Fprintln(os.Stderr, "использование: agent spam label -label spam -from ... -subject ... [-body ...]")
+			return exitUsage
+		}
+		if err := cl.Learn(ex); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitTaskFailed
+		}
+	case "import":
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "использование: agent spam import examples.jsonl")
+			return exitUsage
+		}
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		defer f.Close()
+		dec := json.NewDecoder(f)
+		n := 0
+		for dec.More() {
+			var e spam.Example
+			if err := dec.Decode(&e); err != nil {
+				fmt.Fprintf(os.Stderr, "пример %d: %v\n", n+1, err)
+				return exitUsage
+			}
+			if err := cl.Learn(e); err != nil {
+				fmt.Fprintf(os.Stderr, "пример %d: %v\n", n+1, err)
+				return exitTaskFailed
+			}
+			n++
+		}
+		fmt.Fprintf(os.Stderr, "Добавлено примеров: %d\n", n)
+	case "score":
+		v := cl.Score(ex)
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(struct {
+			spam.Verdict
+			ProposeDelete bool `json:"propose_delete"`
+		}{v, cl.ProposeDelete(v)})
+	default:
+		fmt.Fprintf(os.Stderr, "spam: неизвестная команда %q (доступны: stats, label, import, score)\n", sub)
+		return exitUsage
+	}
+	return exitOK
+}
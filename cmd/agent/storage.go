@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"AIAgent/internal/browser"
+	"AIAgent/internal/config"
+)
+
+// storageCmd: agent storage [export | import | accounts] — перенос куки и
+// localStorage между постоянным профилем и файлом storage state.
+func storageCmd(args []string) int {
+	sub := "accounts"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		sub, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("storage "+sub, flag.ExitOnError)
+	out := fs.String("o", "", "файл storage state (по умолчанию -storage или ~/.<profile>/storage/<account>.json)")
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+	opts := cfg.Browser
+	// Команды работают с профилем на диске; окно для этого не нужно.
+	opts.Ephemeral, opts.Headless = false, true
+	path := *out
+	if path == "" && fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if path == "" {
+		if path, err = opts.StorageFile(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+
+	switch sub {
+	case "accounts":
+		return listAccounts(opts)
+	case "export", "import":
+	default:
+		fmt.Fprintf(os.Stderr, "storage: неизвестная команда %q (доступны: export, import, accounts)\n", sub)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	pw, bctx, _, err := browser.LaunchPersistent(ctx, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось запустить браузер:", err)
+		return exitBrowser
+	}
+	defer pw.Stop()
+	defer bctx.Close()
+
+	if sub == "export" {
+		st, err := browser.ExportStorageState(bctx)
+		if err == nil {
+			err = browser.SaveStorageState(path, st)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "storage export:", err)
+			return exitTaskFailed
+		}
+		fmt.Fprintf(os.Stderr, "Сохранено %d куки и localStorage %d сайтов в %s\n", len(st.Cookies), len(st.Origins), path)
+		return exitOK
+	}
+	st, err := browser.LoadStorageState(path)
+	if err == nil {
+		err = browser.WriteStorageState(bctx, st)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "storage import:", err)
+		return exitTaskFailed
+	}
+	dir, _ := opts.UserDataDir()
+	fmt.Fprintf(os.Stderr, "Импортировано %d куки и localStorage %d сайтов в профиль %s\n", len(st.Cookies), len(st.Origins), dir)
+	return exitOK
+}
+
+// listAccounts печатает именованные профили и файлы storage state.
+func listAccounts(opts browser.Options) int {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	root := filepath.Join(home, "."+opts.Profile)
+	kinds := map[string][]string{}
+	if es, err := os.ReadDir(filepath.Join(root, "accounts")); err == nil {
+		for _, e := range es {
+			if e.IsDir() {
+				kinds[e.Name()] = append(kinds[e.Name()], "profile")
+			}
+		}
+	}
+	if es, err := os.ReadDir(filepath.Join(root, "storage")); err == nil {
+		for _, e := range es {
+			if name, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+				kinds[name] = append(kinds[name], "storage")
+			}
+		}
+	}
+	names := make([]string, 0, len(kinds))
+	for n := range kinds {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACCOUNT\tHAS")
+	for _, n := range names {
+		fmt.Fprintf(tw, "%s\t%s\n", n, strings.Join(kinds[n], ", "))
+	}
+	_ = tw.Flush()
+	return exitOK
+}
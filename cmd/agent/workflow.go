@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"AIAgent/internal/agent"
+	"AIAgent/internal/config"
+	"AIAgent/internal/workflow"
+)
+
+// varsFlag — повторяемый флаг -var имя=значение.
+type varsFlag map[string]string
+
+func (v varsFlag) String() string { return fmt.Sprint(map[string]string(v)) }
+
+func (v varsFlag) Set(s string) error {
+	name, val, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("ожидается имя=значение, получено %q", s)
+	}
+	v[name] = val
+	return nil
+}
+
+// workflowCmd: agent workflow [-var k=v]... flow.yaml — выполнить YAML-сценарий.
+func workflowCmd(args []string) int {
+	fs := flag.NewFlagSet("workflow", flag.ExitOnError)
+	vars := varsFlag{}
+	fs.Var(vars, "var", "переменная сценария имя=значение (можно повторять)")
+	noFallback := fs.Bool("no-fallback", false, "не восстанавливать упавшие шаги через планировщик")
+	outPath := fs.String("out", "", "файл для JSON-результата (по умолчанию stdout)")
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка конфигурации:", err)
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "использование: agent workflow [флаги] flow.yaml")
+		return exitUsage
+	}
+	wf, err := workflow.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "workflow:", err)
+		return exitUsage
+	}
+	if *noFallback {
+		wf.Fallback.Enabled = false
+	}
+	cfg.Agent.Log = os.Stderr
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sess, err := openSession(ctx, cfg.Browser)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось запустить браузер:", err)
+		return exitBrowser
+	}
+	defer sess.close()
+
+	res, runErr := agent.RunWorkflow(ctx, cfg.Agent, sess.page, wf, vars)
+	out := os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(res)
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, "workflow:", runErr)
+		return exitTaskFailed
+	}
+	return exitOK
+}
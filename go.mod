@@ -2,10 +2,18 @@ module AIAgent
 
 go 1.23.5
 
-require github.com/playwright-community/playwright-go v0.5200.1
+require (
+	github.com/emersion/go-imap v1.2.1
+	github.com/emersion/go-message v0.18.2
+	github.com/playwright-community/playwright-go v0.5200.1
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/deckarep/golang-set/v2 v2.7.0 // indirect
+	github.com/emersion/go-sasl v0.0.0-20200509203442-7bfe0ed36a21 // indirect
 	github.com/go-jose/go-jose/v3 v3.0.4 // indirect
 	github.com/go-stack/stack v1.8.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -4,123 +4,581 @@ package agent
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"hash/fnv"
+	"maps"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"AIAgent/internal/browser"
+	"AIAgent/internal/checkpoint"
 	"AIAgent/internal/dom"
+	"AIAgent/internal/events"
+	"AIAgent/internal/mail"
 	"AIAgent/internal/memory"
+	"AIAgent/internal/redact"
+	"AIAgent/internal/skill"
+	"AIAgent/internal/trajectory"
 
 	"github.com/playwright-community/playwright-go"
 )
 
-func Run(ctx context.Context, _ playwright.Browser, page playwright.Page, userTask string) error {
-	mem := memory.New()
-	tools := &Tools{Page: page}
+// Статусы завершения задачи.
+const (
+	StatusDone      = "done"
+	StatusNeedInput = "needs_input"
+	StatusStepLimit = "step_limit"
+	StatusCanceled  = "canceled"
+	StatusError     = "error"
+)
 
-	fmt.Println("\n[agent] Задача:", userTask)
+// Result — итог выполнения одной задачи.
+type Result struct {
+	RunID    string        `json:"run_id,omitempty"`
+	Task     string        `json:"task"`
+	Status   string        `json:"status"`
+	Answer   string        `json:"answer,omitempty"`
+	Steps    int           `json:"steps"`
+	Duration time.Duration `json:"-"`
+	// DurationMS дублирует Duration в миллисекундах для JSON-вывода.
+	DurationMS int64    `json:"duration_ms"`
+	FinalURL   string   `json:"final_url,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+	// Classified — вердикты классификатора писем, если задача к нему обращалась.
+	Classified []Classified `json:"classified,omitempty"`
+	// Downloads — файлы, скачанные во время задачи.
+	Downloads []Download `json:"downloads,omitempty"`
+	// Page — вкладка, активная в конце задачи: агент мог переключиться на
+	// другую или закрыть исходную. Следующую задачу продолжают на ней.
+	Page playwright.Page `json:"-"`
+}
 
-	obs, _ := observe(ctx, page, 24)
-	fmt.Printf("[agent] Текущая страница: %s | %s\n", obs.URL, obs.Title)
+func Run(ctx context.Context, cfg Config, page playwright.Page, userTask string) (Result, error) {
+	return start(ctx, cfg, page, userTask, memory.New(), 0)
+}
 
-	lastURL := obs.URL
-	lastHash := hashSnap(obs.Snapshot)
-	noProgress := 0
+// Resume продолжает прерванный запуск из контрольной точки: переносит куки
+// и localStorage в контекст страницы, открывает последний URL и продолжает
+// планирование с сохранённой памятью и историей шагов.
+func Resume(ctx context.Context, cfg Config, page playwright.Page, cp checkpoint.Checkpoint) (Result, error) {
+	if err := browser.ImportStorageState(page.Context(), cp.Storage); err != nil {
+		return Result{Task: cp.Task, Status: StatusError}, err
+	}
+	if cp.URL != "" && cp.URL != "about:blank" {
+		if _, err := page.Goto(cp.URL); err != nil {
+			return Result{Task: cp.Task, Status: StatusError}, fmt.Errorf("resume: %w", err)
+		}
+	}
+	cfg.RunID = cp.RunID
+	return start(ctx, cfg, page, cp.Task, memory.FromState(cp.Memory), cp.Step)
+}
 
-	const maxSteps = 40
-	for step := 1; step <= maxSteps; step++ {
-		act, err := decide(ctx, userTask, obs, mem)
+func start(ctx context.Context, cfg Config, page playwright.Page, userTask string, mem *memory.Memory, doneSteps int) (res Result, err error) {
+	if cfg.RunID == "" && (cfg.Checkpoints.Enabled || cfg.Trajectory.Enabled) {
+		cfg.RunID = checkpoint.NewRunID()
+	}
+	r := &runner{
+		cfg:     cfg,
+		page:    page,
+		task:    userTask,
+		mem:     mem,
+		emit:    cfg.emitter(),
+		started: time.Now(),
+	}
+	if cfg.Checkpoints.Enabled {
+		r.store = &checkpoint.Store{Dir: cfg.Checkpoints.Dir}
+	}
+	if cfg.Trajectory.Enabled {
+		rec, err := trajectory.Create(cfg.Trajectory.Dir, trajectory.Meta{
+			RunID: cfg.RunID, Task: userTask, StartURL: page.URL(), Model: cfg.LLM.Model, Started: r.started,
+		})
 		if err != nil {
-			return fmt.Errorf("ошибка планирования: %w", err)
+			return Result{RunID: cfg.RunID, Task: userTask, Status: StatusError}, fmt.Errorf("trajectory: %w", err)
 		}
-
-		fmt.Printf("\n[agent] Шаг %d\n", step)
-		fmt.Printf("[agent] → инструмент: %s\n", act.Tool)
-		if len(act.Args) != 0 {
-			js, _ := json.Marshal(act.Args)
-			fmt.Printf("[agent] → аргументы: %s\n", js)
+		r.rec = rec
+	}
+	res = Result{RunID: cfg.RunID, Task: userTask, Status: StatusError, Steps: doneSteps}
+	defer func() {
+		res.Duration = time.Since(r.started)
+		res.DurationMS = res.Duration.Milliseconds()
+		if r.tools != nil && r.tools.Page != nil {
+			r.page = r.tools.Page
 		}
-		if s := strings.TrimSpace(act.Comment); s != "" {
-			fmt.Printf("[agent] → комментарий: %s\n", s)
+		res.Page = r.page
+		res.FinalURL = r.page.URL()
+		res.Downloads = r.tools.Downloads()
+		if r.tools != nil && len(r.tools.classified) > 0 {
+			res.Classified = r.tools.classified
+			if res.Status == StatusDone {
+				res.Answer = strings.TrimSpace(res.Answer + "\n\n" + explainClassified(res.Classified))
+			}
 		}
-
-		res, err := tools.Call(ctx, act.Tool, act.Args)
 		if err != nil {
-			fmt.Printf("[agent] ⚠ ошибка инструмента: %v\n", err)
-			mem.SetLastAction("error: " + err.Error())
-		} else {
-			mem.SetLastAction(act.Tool + ": " + res)
+			res.Errors = append(res.Errors, err.Error())
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				res.Status = StatusCanceled
+			}
 		}
+		// Отменённый или упавший запуск остаётся доступным для resume.
+		if res.Status != StatusCanceled && res.Status != StatusError {
+			r.checkpoint(res.Steps, res.Status)
+		}
+		if r.rec != nil {
+			_ = r.rec.Close(res.Status, res.Answer)
+		}
+		_ = r.tools.Close()
+		r.emit(events.Event{Kind: events.KindRunFinished, Step: res.Steps, Data: events.RunFinished{
+			Status: res.Status, Answer: res.Answer, Steps: res.Steps, DurationMS: res.DurationMS, Errors: res.Errors,
+		}})
+	}()
+
+	r.red, err = redact.FromConfig(cfg.Redact)
+	if err != nil {
+		return res, err
+	}
+	if r.tools, err = cfg.newTools(page); err != nil {
+		return res, err
+	}
+	ctx, cancel := withDeadline(ctx, cfg.Timeouts.Run, "run")
+	defer cancel()
+	err = r.loop(ctx, &res, doneSteps+1)
+	return res, err
+}
+
+// runner — состояние одного запуска цикла «наблюдение → решение → действие».
+type runner struct {
+	cfg     Config
+	page    playwright.Page
+	task    string
+	mem     *memory.Memory
+	tools   *Tools
+	red     *redact.Redactor
+	emit    func(events.Event)
+	store   *checkpoint.Store
+	rec     *trajectory.Recorder
+	started time.Time
 
-		WaitIdle(page)
+	obs        Observation
+	lastURL    string
+	lastHash   string
+	noProgress int
+	// fallbacks — принудительных действий с последнего прогресса.
+	fallbacks int
+	redacted  int
+}
 
-		newObs, _ := observe(ctx, page, 36)
-		newURL := newObs.URL
-		newHash := hashSnap(newObs.Snapshot)
+// record дописывает действие в траекторию запуска (если запись включена).
+func (r *runner) record(ctx context.Context, step int, before Observation, act llmAction, trace llmTrace, result string, err error, after Observation) {
+	if r.rec == nil {
+		return
+	}
+	// На диск попадает то же, что видела модель: найденные редактором данные — токенами.
+	red := r.red
+	st := trajectory.Step{
+		Step:       step,
+		Time:       time.Now(),
+		Before:     pageState(red, before),
+		Candidates: redactCandidates(red, before.Candidates),
+		Prompt:     trace.Prompt,
+		Response:   trace.Response,
+		Action:     trajectory.Action{Tool: act.Tool, Args: red.RedactArgs(act.Args), Comment: red.Redact(act.Comment), Source: trace.Source},
+		Result:     red.Redact(result),
+		After:      pageState(red, after),
+	}
+	if err != nil {
+		st.Error = red.Redact(err.Error())
+	}
+	var png []byte
+	if r.cfg.Trajectory.Screenshots {
+		png, _ = do(ctx, func() ([]byte, error) {
+			return r.page.Screenshot(playwright.PageScreenshotOptions{Timeout: pwTimeout(ctx)})
+		})
+	}
+	if werr := r.rec.Record(st, png); werr != nil {
+		r.emit(events.Event{Kind: events.KindToolFailed, Step: step, Data: events.ToolFailed{Tool: "trajectory", Error: werr.Error()}})
+	}
+}
+
+// pageState — состояние страницы для траектории; хеш считается по исходному
+// тексту, чтобы replay сравнивал его с живой страницей.
+func pageState(red *redact.Redactor, o Observation) trajectory.PageState {
+	return trajectory.PageState{URL: red.Redact(o.URL), Title: red.Redact(o.Title), Hash: hashSnap(o.Snapshot), Snapshot: red.Redact(o.Snapshot)}
+}
+
+// checkpoint сохраняет состояние после шага. Ошибка сохранения не прерывает задачу.
+func (r *runner) checkpoint(step int, status string) {
+	if r.store == nil {
+		return
+	}
+	cp := checkpoint.Checkpoint{
+		RunID:   r.cfg.RunID,
+		Task:    r.task,
+		Step:    step,
+		Status:  status,
+		URL:     r.page.URL(),
+		Title:   r.obs.Title,
+		Memory:  r.mem.State(),
+		Started: r.started,
+	}
+	if st, err := browser.ExportStorageState(r.page.Context()); err == nil {
+		cp.Storage = st
+	}
+	if err := r.store.Save(cp); err != nil {
+		r.emit(events.Event{Kind: events.KindToolFailed, Step: step, Data: events.ToolFailed{Tool: "checkpoint", Error: err.Error()}})
+	}
+}
+
+func (r *runner) observe(ctx context.Context, step, maxCandidates int) Observation {
+	obs := r.observePage(ctx, maxCandidates)
+	r.emit(events.Event{Kind: events.KindObservation, Step: step, Data: events.Observation{
+		URL: obs.URL, Title: obs.Title, Candidates: len(obs.Candidates), SnapshotLen: len(obs.Snapshot), Tabs: len(obs.Tabs),
+	}})
+	return obs
+}
 
-		if newURL == lastURL {
-			fmt.Println("[agent] URL не изменился")
-		} else {
-			fmt.Printf("[agent] URL изменился: %s → %s\n", lastURL, newURL)
+// observePage снимает наблюдение, если страницу не блокирует диалог. Диалог,
+// открывшийся во время наблюдения, прерывает его: страница не ответит, пока он открыт.
+func (r *runner) observePage(ctx context.Context, maxCandidates int) Observation {
+	if d := r.tools.dlg.Pending(); d != nil {
+		return Observation{URL: r.page.URL(), Title: r.obs.Title, Dialog: d}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-r.tools.dlg.waitCh():
+			cancel()
+		case <-ctx.Done():
 		}
-		if newHash == lastHash {
-			fmt.Println("[agent] Контент почти не изменился (hash)")
-			noProgress++
-		} else {
-			noProgress = 0
+	}()
+	obs, _ := observe(ctx, r.page, maxCandidates, r.cfg.SnapshotChars)
+	if d := r.tools.dlg.Pending(); d != nil {
+		return Observation{URL: r.page.URL(), Title: obs.Title, Dialog: d}
+	}
+	obs.History = r.tools.History(obs.Title)
+	if r.cfg.Vision.Enabled {
+		r.screenshot(ctx, &obs)
+	}
+	return obs
+}
+
+// screenshot добавляет к наблюдению снимок видимой области и её размер:
+// по ним планировщик выбирает координаты для click_at.
+func (r *runner) screenshot(ctx context.Context, obs *Observation) {
+	if vp, err := r.tools.viewport(ctx); err == nil {
+		obs.Viewport = &vp
+	}
+	img, err := do(ctx, func() ([]byte, error) {
+		return r.page.Screenshot(playwright.PageScreenshotOptions{
+			Type: playwright.ScreenshotTypeJpeg, Quality: playwright.Int(r.cfg.Vision.Quality), Timeout: pwTimeout(ctx),
+		})
+	})
+	if err == nil {
+		obs.Screenshot = img
+	}
+}
+
+// reset делает obs текущей точкой отсчёта для проверки прогресса.
+func (r *runner) reset(obs Observation) {
+	r.obs = obs
+	r.lastURL = obs.URL
+	r.lastHash = hashSnap(obs.Snapshot)
+}
+
+func (r *runner) loop(ctx context.Context, res *Result, first int) error {
+	cfg := r.cfg
+	r.reset(r.observe(ctx, first-1, cfg.InitialCandidates))
+	r.emit(events.Event{Kind: events.KindRunStarted, Step: first - 1, Data: events.RunStarted{
+		Task: r.task, URL: r.obs.URL, Title: r.obs.Title, Resumed: first > 1,
+	}})
+
+	for step := first; step <= cfg.MaxSteps; step++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.gate(ctx, step); err != nil {
+			return err
 		}
+		res.Steps = step
+		if err := r.step(ctx, step, res); err != nil {
+			if errors.Is(err, errFinished) {
+				return nil
+			}
+			return err
+		}
+		r.checkpoint(step, checkpoint.StatusRunning)
+	}
 
-		if act.Tool == "answer_or_ask_user" {
-			if s := strings.TrimSpace(act.Comment); s != "" {
-				fmt.Println("\n[agent] Ответ/уточнение:")
-				fmt.Println(s)
+	res.Status = StatusStepLimit
+	return ErrStepLimit
+}
+
+// gate применяет команды Control перед шагом: ждёт снятия паузы,
+// передаёт подсказки в память и после паузы заново снимает наблюдение,
+// потому что человек мог изменить страницу.
+func (r *runner) gate(ctx context.Context, step int) error {
+	ctrl := r.cfg.Control
+	if paused, takeover := ctrl.Paused(); paused {
+		action := "paused"
+		if takeover {
+			action = "takeover"
+		}
+		r.emit(events.Event{Kind: events.KindControl, Step: step, Data: events.Control{Action: action}})
+	}
+	waited, err := ctrl.gate(ctx)
+	if err != nil {
+		return err
+	}
+	if waited {
+		r.emit(events.Event{Kind: events.KindControl, Step: step, Data: events.Control{Action: "resumed"}})
+		r.reset(r.observe(ctx, step, r.cfg.Candidates))
+		r.noProgress = 0
+	}
+	for _, h := range ctrl.takeHints() {
+		r.emit(events.Event{Kind: events.KindControl, Step: step, Data: events.Control{Action: "hint", Detail: h}})
+		r.mem.AddHint(h)
+	}
+	return nil
+}
+
+// refArgs — пары «номер кандидата → селектор»: ref для элемента действия,
+// target_ref для цели перетаскивания.
+var refArgs = [][2]string{{"ref", "selector"}, {"target_ref", "target"}}
+
+// resolveRef превращает аргументы ref и target_ref (номер кандидата #N из
+// наблюдения) в selector и target.
+func resolveRef(args map[string]any, obs Observation) map[string]any {
+	if args == nil {
+		return args
+	}
+	out, copied := args, false
+	for _, pair := range refArgs {
+		ref, key := pair[0], pair[1]
+		if sel, _ := args[key].(string); sel != "" {
+			continue
+		}
+		var n int
+		switch v := args[ref].(type) {
+		case float64:
+			n = int(v)
+		case int:
+			n = v
+		case string:
+			n, _ = strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(v), "#"))
+		default:
+			continue
+		}
+		if n < 1 || n > len(obs.Candidates) {
+			continue
+		}
+		if !copied {
+			out, copied = maps.Clone(args), true
+		}
+		out[key] = obs.Candidates[n-1].Selector
+	}
+	return out
+}
+
+// errFinished — внутренний сигнал step: задача завершена, статус уже записан в Result.
+var errFinished = errors.New("finished")
+
+// step выполняет один шаг цикла с дедлайном Timeouts.Step. Ожидание ответа
+// пользователя дедлайном шага не ограничивается.
+func (r *runner) step(runCtx context.Context, step int, res *Result) error {
+	cfg := r.cfg
+	ctx, cancel := withDeadline(runCtx, cfg.Timeouts.Step, "step")
+	defer cancel()
+
+	var trace llmTrace
+	act, manual := cfg.Control.nextOverride()
+	if manual {
+		trace.Source = "operator"
+		r.emit(events.Event{Kind: events.KindControl, Step: step, Data: events.Control{Action: "override", Detail: act.Tool}})
+	} else {
+		var err error
+		act, err = decide(ctx, cfg, r.task, r.obs, r.mem, r.red, r.tools.Skills, &trace)
+		if err != nil {
+			if runCtx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("ошибка планирования: шаг не уложился в %s: %w", cfg.Timeouts.Step, err)
 			}
-			return nil
+			return fmt.Errorf("ошибка планирования: %w", err)
 		}
+	}
+	act.Args = resolveRef(act.Args, r.obs)
+	if n := len(r.red.Log()); n > r.redacted {
+		r.emit(events.Event{Kind: events.KindRedacted, Step: step, Data: events.Redacted{Summary: r.red.Summary()}})
+		r.redacted = n
+	}
+	r.emit(events.Event{Kind: events.KindDecision, Step: step, Data: events.Decision{Tool: act.Tool, Args: act.Args, Comment: act.Comment}})
 
-		if noProgress >= 2 {
-			fmt.Println("[agent] Нет прогресса два шага подряд → принудительно open_first_main_item")
-			if _, err := tools.Call(ctx, "open_first_main_item", map[string]any{}); err == nil {
-				WaitIdle(page)
-				forcedObs, _ := observe(ctx, page, 36)
-				lastURL = forcedObs.URL
-				lastHash = hashSnap(forcedObs.Snapshot)
-				noProgress = 0
-				obs = forcedObs
-				continue
+	if act.Tool == "answer_or_ask_user" {
+		question, _ := act.Args["question"].(string)
+		if question = strings.TrimSpace(question); question != "" {
+			if cfg.AskUser == nil {
+				res.Answer = question
+				res.Status = StatusNeedInput
+				return errFinished
+			}
+			r.emit(events.Event{Kind: events.KindUserAsked, Step: step, Data: events.UserAsked{Question: question}})
+			answer, err := cfg.AskUser(runCtx, question)
+			if err != nil {
+				return fmt.Errorf("ответ пользователя: %w", err)
 			}
-			_, _ = tools.Call(ctx, "scroll", map[string]any{"y": 800})
-			WaitIdle(page)
+			r.emit(events.Event{Kind: events.KindUserAsked, Step: step, Data: events.UserAsked{Question: question, Answer: answer}})
+			r.mem.AddUserAnswer(question, answer)
+			r.mem.AddStep(memory.Step{N: step, Tool: act.Tool, Args: act.Args, Result: "user answered: " + answer, URL: r.obs.URL})
+			r.record(ctx, step, r.obs, act, trace, "user answered: "+answer, nil, r.obs)
+			r.mem.SetLastAction("answer_or_ask_user: user answered " + strconv.Quote(answer))
+			return nil
 		}
+	}
 
-		lastURL = newURL
-		lastHash = newHash
-		obs = newObs
+	toolRes, err := r.tools.Call(ctx, act.Tool, act.Args)
+	hist := memory.Step{N: step, Tool: act.Tool, Args: act.Args, Result: toolRes, URL: r.obs.URL}
+	if err != nil {
+		hist.Error = err.Error()
+	}
+	r.mem.AddStep(hist)
+	if err != nil {
+		if runCtx.Err() != nil {
+			return runCtx.Err()
+		}
+		r.emit(events.Event{Kind: events.KindToolFailed, Step: step, Data: events.ToolFailed{Tool: act.Tool, Args: act.Args, Error: err.Error()}})
+		r.mem.SetLastAction("error: " + err.Error())
+		res.Errors = append(res.Errors, fmt.Sprintf("step %d %s: %v", step, act.Tool, err))
+	} else {
+		r.emit(events.Event{Kind: events.KindToolInvoked, Step: step, Data: events.ToolInvoked{Tool: act.Tool, Args: act.Args, Result: toolRes}})
+		r.mem.SetLastAction(act.Tool + ": " + toolRes)
+	}
 
-		time.Sleep(150 * time.Millisecond)
+	// Если шаг исчерпал свой дедлайн на действии, наблюдение всё равно нужно снять.
+	obsCtx := ctx
+	if ctx.Err() != nil {
+		obsCtx = runCtx
+	}
+	if s := r.tools.WaitIdle(obsCtx); !s.OK {
+		r.mem.SetLastAction(r.mem.LastAction() + "; " + s.String())
+	}
+	// Вкладка или скачивание могли появиться уже после возврата из инструмента.
+	if note := r.tools.syncTabs(obsCtx); note != "" {
+		r.mem.SetLastAction(r.mem.LastAction() + "; " + note)
+		r.tools.WaitIdle(obsCtx)
+	}
+	if note := r.tools.downloadNote(obsCtx, downloadWait); note != "" {
+		r.mem.SetLastAction(r.mem.LastAction() + "; " + note)
+	}
+	r.page = r.tools.Page
+
+	newObs := r.observe(obsCtx, step, cfg.Candidates)
+	newHash := hashSnap(newObs.Snapshot)
+	if newHash == r.lastHash {
+		r.noProgress++
+	} else {
+		r.noProgress = 0
+		r.fallbacks = 0
+	}
+	r.emit(events.Event{Kind: events.KindProgress, Step: step, Data: events.Progress{
+		PrevURL: r.lastURL, URL: newObs.URL, Title: newObs.Title,
+		URLChanged: newObs.URL != r.lastURL, ContentChanged: newHash != r.lastHash, NoProgress: r.noProgress,
+	}})
+	r.record(obsCtx, step, r.obs, act, trace, toolRes, err, newObs)
+
+	if act.Tool == "answer_or_ask_user" {
+		res.Answer = strings.TrimSpace(act.Comment)
+		res.Status = StatusDone
+		return errFinished
+	}
+
+	if cfg.Policy.StallFallback && r.noProgress >= cfg.Policy.NoProgressLimit {
+		// Принудительные действия уже не помогли — откатываемся туда, где агент не застревал.
+		if cfg.Policy.Backtrack && r.fallbacks > 0 && r.backtrack(ctx, step, newObs) {
+			return nil
+		}
+		r.fallbacks++
+		reason := fmt.Sprintf("Нет прогресса %d шага подряд", r.noProgress)
+		r.emit(events.Event{Kind: events.KindFallback, Step: step, Data: events.Fallback{Reason: reason, Tool: "open_first_main_item"}})
+		forced := llmAction{Tool: "open_first_main_item", Args: map[string]any{}}
+		fallback := llmTrace{Source: "fallback"}
+		if fres, err := r.tools.Call(ctx, forced.Tool, forced.Args); err == nil {
+			r.page = r.tools.Page
+			r.tools.WaitIdle(ctx)
+			forcedObs := r.observe(ctx, step, cfg.Candidates)
+			r.record(ctx, step, newObs, forced, fallback, fres, nil, forcedObs)
+			r.reset(forcedObs)
+			r.noProgress = 0
+			return nil
+		}
+		r.emit(events.Event{Kind: events.KindFallback, Step: step, Data: events.Fallback{Reason: "open_first_main_item не сработал", Tool: "scroll"}})
+		scroll := llmAction{Tool: "scroll", Args: map[string]any{"y": 800.0}}
+		sres, serr := r.tools.Call(ctx, scroll.Tool, scroll.Args)
+		r.page = r.tools.Page
+		r.tools.WaitIdle(ctx)
+		if r.rec != nil {
+			r.record(ctx, step, newObs, scroll, fallback, sres, serr, r.observe(ctx, step, cfg.Candidates))
+		}
 	}
 
-	return errors.New("достигнут лимит шагов")
+	r.reset(newObs)
+	return sleepCtx(runCtx, cfg.StepDelay)
 }
 
+// backtrack возвращает вкладку по истории на последнюю страницу, где агент не
+// застревал, и делает её новой точкой отсчёта. false — возвращаться некуда.
+func (r *runner) backtrack(ctx context.Context, step int, obs Observation) bool {
+	n := r.tools.nav.backtrackSteps(r.page)
+	if n == 0 {
+		return false
+	}
+	r.emit(events.Event{Kind: events.KindFallback, Step: step, Data: events.Fallback{
+		Reason: fmt.Sprintf("Нет прогресса и после принудительных действий, откат на %d назад", n), Tool: "go_back",
+	}})
+	act := llmAction{Tool: "go_back", Args: map[string]any{"steps": float64(n)}}
+	res, err := r.tools.Call(ctx, act.Tool, act.Args)
+	if err != nil {
+		return false
+	}
+	r.page = r.tools.Page
+	r.tools.WaitIdle(ctx)
+	back := r.observe(ctx, step, r.cfg.Candidates)
+	r.record(ctx, step, obs, act, llmTrace{Source: "fallback"}, res, nil, back)
+	r.mem.SetLastAction("backtracked after a loop: " + res)
+	r.reset(back)
+	r.noProgress, r.fallbacks = 0, 0
+	return true
+}
+
+// ErrStepLimit — задача не завершилась за cfg.MaxSteps шагов.
+var ErrStepLimit = errors.New("достигнут лимит шагов")
+
 type Observation struct {
 	Title      string
 	URL        string
 	Snapshot   string
 	Candidates []dom.Candidate
+	// Tabs — все вкладки контекста; активная — та, с которой снято наблюдение.
+	Tabs []Tab
+	// Dialog — открытый диалог, ждущий handle_dialog; пока он открыт, DOM недоступен.
+	Dialog *Dialog
+	// History — переходы активной вкладки, включая pushState и смену #хэша.
+	History []Visit
+	// Screenshot — JPEG видимой области для планировщика (только при включённом Vision).
+	Screenshot []byte
+	Viewport   *Viewport
 }
 
-func observe(ctx context.Context, page playwright.Page, maxCandidates int) (Observation, error) {
-	title, _ := page.Title()
+func observe(ctx context.Context, page playwright.Page, maxCandidates, snapshotChars int) (Observation, error) {
+	title, _ := do(ctx, page.Title)
 	url := page.URL()
-	body, _ := page.TextContent("body")
-	if len(body) > 3000 {
-		body = body[:3000] + "…"
+	body, _ := do(ctx, func() (string, error) {
+		return page.TextContent("body", playwright.PageTextContentOptions{Timeout: pwTimeout(ctx)})
+	})
+	if snapshotChars > 0 && len(body) > snapshotChars {
+		body = body[:snapshotChars] + "…"
 	}
 	cands, _ := dom.CollectCandidates(ctx, page, maxCandidates)
 	return Observation{
@@ -128,6 +586,7 @@ func observe(ctx context.Context, page playwright.Page, maxCandidates int) (Obse
 		URL:        url,
 		Snapshot:   safeTrim(body),
 		Candidates: cands,
+		Tabs:       tabs(ctx, page),
 	}, nil
 }
 
@@ -157,10 +616,26 @@ type llmAction struct {
 	Comment string         `json:"comment,omitempty"`
 }
 
-func decide(ctx context.Context, task string, obs Observation, mem *memory.Memory) (llmAction, error) {
-	if apiKey := os.Getenv("OPENAI_API_KEY"); strings.TrimSpace(apiKey) != "" {
-		return decideWithOpenAI(ctx, apiKey, task, obs, mem.LastAction())
+// llmTrace — промпт и сырой ответ модели для записи траектории.
+type llmTrace struct {
+	Source   string
+	Prompt   string
+	Response string
+}
+
+func decide(ctx context.Context, cfg Config, task string, obs Observation, mem *memory.Memory, red *redact.Redactor, skills *skill.Library, trace *llmTrace) (llmAction, error) {
+	if cfg.useLLM() {
+		trace.Source = "llm"
+		act, err := decideWithOpenAI(ctx, cfg, task, obs, mem, red, skills, trace)
+		if err != nil {
+			return act, err
+		}
+		// Модель видела только токены — возвращаем реальные значения в аргументы.
+		act.Args = red.RestoreArgs(act.Args)
+		act.Comment = red.Restore(act.Comment)
+		return act, nil
 	}
+	trace.Source = "heuristic"
 	return simpleHeuristicDecision(task, obs, mem), nil
 }
 
@@ -169,59 +644,147 @@ You are a web-automation AI agent that controls a real browser page.
 You must choose EXACTLY ONE next tool call in JSON, no extra text.
 Pick selectors ONLY from the provided candidates.
 Prefer stable selectors: #id, [data-qa], a[href*=... ], placeholders/aria-labels. Avoid raw text unless necessary.
-If user task requires reading emails and classifying spam, you must navigate the mailbox UI, open Inbox, read latest messages (subject/sender/preview), decide spam vs important, move spam to Trash/Spam, and then summarize to the user.
-If you need user input (e.g., missing info or login), return tool=answer_or_ask_user with a short question.
+If user task requires reading emails and classifying spam, open Inbox, read latest messages (subject/sender/preview), decide spam vs important, move spam to Trash/Spam, and then summarize to the user.
+To classify mail, call mail_classify and follow its verdicts: delete or move to spam only messages with propose_delete; for spam verdicts below that confidence ask the user before deleting. When the user corrects a verdict, call mail_label. In the final comment list each decision with its confidence and reasons.
+For mail tasks prefer the mail_* tools: they know the markup of the mail client named in "mail" (yandex, gmail). If a mail_* tool fails or reports the operation is unsupported, do the same with click/type on the UI.
+If "mailbox" is present in the input, the mailbox is reachable directly over IMAP/Maildir: prefer mailbox_* tools for listing, reading, moving and deleting mail, they need no page at all. Use mail_* and UI tools only when "mailbox" is absent or a mailbox_* tool fails.
+If you need user input (e.g., missing info or login), return tool=answer_or_ask_user with args {"question": "..."}; the user's reply will appear in user_answers.
+Follow operator_hints: they come from a human supervising the run and override your own plan.
+When the task is complete, return tool=answer_or_ask_user with empty args and the final answer in comment.
+"history" lists recent navigations of the active tab including in-page (SPA) route changes, each with its offset from the current page (+0): after a wrong click use go_back (steps = the offset) instead of goto_url, so the app keeps its state; avoid pages marked stuck.
+Links and popups that open a new tab become the active tab automatically; "tabs" lists open tabs when there are several, use switch_tab to go back and close_tab to close a finished popup.
+If "dialog" is present, the page is blocked by a JavaScript alert/confirm/prompt: nothing else works until you call handle_dialog. Accept only when it matches the task (e.g. confirming a deletion the user asked for), otherwise dismiss; ask the user when unsure.
+After every action the agent waits until the page settles (no DOM changes, requests or animations); "page still busy" in last_action means it gave up. When the next step depends on something slow — search results, a toast, a redirect after login — call wait_for instead of acting on a half-loaded page.
+Use click_at, move_mouse and type_keys only when no candidate fits (canvas, maps, obfuscated markup without selectors): coordinates are CSS pixels of the viewport, take them from the screenshot when one is attached or from at= of a candidate; the result names the element that was hit, check it.
+Files downloaded by any action are saved automatically; the result of the action reports name, size, type and path.
 If a navigation item like Inbox is already selected, do NOT click it again. Instead call open_first_main_item to open the newest message from the main content area.
 
 Available tools:
 - goto_url {url}
-- click {selector}
+- click {selector} (here and in every tool taking selector you may pass ref: the candidate number #N instead)
 - type {selector, text, pressEnter?}
+- select_option {selector, option} (option: value or visible label, or a list for multi-select; works on <select> and ARIA comboboxes, see options= in candidates)
+- check {selector} / uncheck {selector} (checkboxes, radios, switches; see checked= in candidates)
+- hover {selector} (reveals controls shown on hover, e.g. row actions in mail lists)
+- dblclick {selector}
+- right_click {selector} (opens the context menu)
+- drag {selector, target} (drag and drop, e.g. a message onto a folder; target_ref for the target candidate number)
+- wait_for {selector? or text? or url_pattern?, state?, timeout?} (wait for an element or text: state visible (default), hidden, attached or detached; or for the URL: url_pattern is a substring, a glob with * or /regexp/; timeout in seconds, 10 by default; no target waits for the page to settle)
+- go_back {steps?} / go_forward {steps?} (browser history, see "history")
+- reload {}
+- click_at {x, y, button?, clicks?} (click a point of the viewport; button left/right/middle, clicks 2 for a double click)
+- move_mouse {x, y, steps?} (move the pointer, e.g. to reveal a hover menu on a canvas)
+- type_keys {text?, keys?} (raw keyboard input into the focused element: text is typed as is, keys is a list like ["Control+A", "Backspace", "Enter"])
 - press {key}
 - scroll {y? or selector?}
 - extract {}
 - open_first_main_item {}
+- mail_status {} (mail client and page type: message_list, message, compose, login, ...)
+- mail_list {limit?, folder?} (messages of the current or given folder: index, id, from, subject, snippet, unread)
+- mail_open {index? or id?}
+- mail_read {} (from, subject, date and body of the open message)
+- mail_open_folder {folder} (inbox, spam, trash, sent, drafts or a folder name)
+- mail_delete {} / mail_mark_spam {} (the open message)
+- mail_move {folder}
+- mail_classify {limit?} (local spam classifier verdicts for the open message or the list: label, confidence, reasons, propose_delete)
+- mail_label {label, index? or id?} (store the user's label, e.g. spam/ham/important, for a message and retrain the classifier)
+- mailbox_folders {} (folders of the configured mailbox)
+- mailbox_list {folder?, limit?} (newest messages of a folder, inbox by default: index, id, from, subject, snippet, unread)
+- mailbox_read {id, folder?}
+- mailbox_move {id, to, folder?}
+- mailbox_delete {id, folder?} (moves to trash; deletes for good inside trash)
+- mailbox_classify {folder?, limit?} (like mail_classify, for mailbox_list messages)
+- handle_dialog {action, text?} (answer the open JavaScript dialog: accept or dismiss; text is the reply to a prompt)
+- upload_file {selector, path} (attach a file from the upload directory: path is relative to it, or pass paths for several; selector is the file input or the button that opens the file dialog)
+- switch_tab {index} (index from "tabs")
+- new_tab {url?}
+- close_tab {index?} (the active tab by default)
+- run_skill {name, params?} (a saved routine from the skills list, params marked * are required; prefer it when it matches the next sub-goal)
+- answer_or_ask_user {question?}
 
 
 Return strictly:
 {"tool":"...", "args":{...}, "comment":"...optional human-readable note..."}
 `
 
-func decideWithOpenAI(ctx context.Context, apiKey string, task string, obs Observation, lastAction string) (llmAction, error) {
+func decideWithOpenAI(ctx context.Context, cfg Config, task string, obs Observation, mem *memory.Memory, red *redact.Redactor, skills *skill.Library, trace *llmTrace) (llmAction, error) {
 	// Собираем компактное представление кандидатов
 	var b strings.Builder
 	for i, c := range obs.Candidates {
-		fmt.Fprintf(&b, "- #%d sel=%q | %s\n", i+1, c.Selector, c.Desc)
-		if i >= 80 {
+		if i >= cfg.PromptCandidates {
 			break
 		}
+		fmt.Fprintf(&b, "- #%d sel=%q | %s\n", i+1, c.Selector, c.Desc)
 	}
 
+	// Персональные данные заменяются токенами до того, как покинут машину.
 	userPrompt := map[string]any{
-		"task":          task,
-		"page":          map[string]string{"url": obs.URL, "title": obs.Title},
-		"last_action":   lastAction,
-		"candidates":    b.String(),
-		"page_snapshot": obs_snapshot(obs),
+		"task":           red.Redact(task),
+		"page":           map[string]string{"url": red.Redact(obs.URL), "title": red.Redact(obs.Title)},
+		"last_action":    red.Redact(mem.LastAction()),
+		"user_answers":   redactAnswers(red, mem.UserAnswers()),
+		"operator_hints": redactAll(red, mem.Hints()),
+		"recent_steps":   redactAll(red, formatSteps(mem.Recent(8))),
+		"candidates":     red.Redact(b.String()),
+		"page_snapshot":  red.Redact(obs_snapshot(obs, cfg.PromptSnapshotChars)),
+	}
+	if obs.Dialog != nil {
+		userPrompt["dialog"] = map[string]string{
+			"type": obs.Dialog.Type, "message": red.Redact(obs.Dialog.Message), "default": red.Redact(obs.Dialog.Default),
+		}
+	}
+	if len(obs.Tabs) > 1 {
+		userPrompt["tabs"] = redactTabs(red, obs.Tabs)
+	}
+	if len(obs.History) > 1 {
+		userPrompt["history"] = redactHistory(red, obs.History, 10)
+	}
+	if obs.Viewport != nil {
+		userPrompt["viewport"] = obs.Viewport
+	}
+	if mc := mailContext(obs.URL); mc != nil {
+		userPrompt["mail"] = mc
+	}
+	if cfg.Mailbox.Enabled() {
+		userPrompt["mailbox"] = map[string]string{"backend": cfg.Mailbox.Backend}
+	}
+	if list := skills.List(); len(list) > 0 {
+		sigs := make([]string, 0, len(list))
+		for _, sk := range list {
+			sigs = append(sigs, sk.Signature())
+		}
+		userPrompt["skills"] = sigs
 	}
 	uj, _ := json.Marshal(userPrompt)
+	trace.Prompt = string(uj)
 
+	// Со снимком страницы сообщение становится составным: текст и картинка.
+	var user any = string(uj)
+	if len(obs.Screenshot) > 0 {
+		user = []map[string]any{
+			{"type": "text", "text": string(uj)},
+			{"type": "image_url", "image_url": map[string]string{"url": "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(obs.Screenshot)}},
+		}
+	}
 	body := map[string]any{
-		"model": "gpt-4o",
-		"messages": []map[string]string{
+		"model": cfg.LLM.Model,
+		"messages": []map[string]any{
 			{"role": "system", "content": systemPrompt},
-			{"role": "user", "content": string(uj)},
+			{"role": "user", "content": user},
 		},
 		"response_format": map[string]string{"type": "json_object"},
-		"temperature":     0.2,
+		"temperature":     cfg.LLM.Temperature,
 	}
 	reqBytes, _ := json.Marshal(body)
 
-	httpReq, _ := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(reqBytes))
-	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", cfg.LLM.Endpoint, bytes.NewReader(reqBytes))
+	if err != nil {
+		return llmAction{}, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+cfg.LLM.APIKey)
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	httpCli := &http.Client{Timeout: 45 * time.Second}
+	httpCli := &http.Client{Timeout: cfg.LLM.Timeout}
 	resp, err := httpCli.Do(httpReq)
 	if err != nil {
 		return llmAction{}, err
@@ -245,6 +808,7 @@ func decideWithOpenAI(ctx context.Context, apiKey string, task string, obs Obser
 		return llmAction{}, errors.New("empty LLM response")
 	}
 
+	trace.Response = out.Choices[0].Message.Content
 	var act llmAction
 	if err := json.Unmarshal([]byte(out.Choices[0].Message.Content), &act); err != nil {
 		act = tryExtractJSON(out.Choices[0].Message.Content)
@@ -255,13 +819,102 @@ func decideWithOpenAI(ctx context.Context, apiKey string, task string, obs Obser
 	return act, nil
 }
 
-func obs_snapshot(obs Observation) string {
+func redactAnswers(red *redact.Redactor, qa []memory.UserAnswer) []map[string]string {
+	out := make([]map[string]string, 0, len(qa))
+	for _, a := range qa {
+		out = append(out, map[string]string{"question": red.Redact(a.Question), "answer": red.Redact(a.Answer)})
+	}
+	return out
+}
+
+// formatSteps — компактная история для промпта: «3. click {...} → ok».
+func formatSteps(steps []memory.Step) []string {
+	out := make([]string, 0, len(steps))
+	for _, st := range steps {
+		args, _ := json.Marshal(st.Args)
+		outcome := "ok"
+		if st.Error != "" {
+			outcome = "error: " + st.Error
+		} else if st.Result != "" {
+			outcome = crop(st.Result, 120)
+		}
+		out = append(out, fmt.Sprintf("%d. %s %s → %s", st.N, st.Tool, args, outcome))
+	}
+	return out
+}
+
+func crop(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+func redactAll(red *redact.Redactor, ss []string) []string {
+	out := make([]string, 0, len(ss))
+	for _, s := range ss {
+		out = append(out, red.Redact(s))
+	}
+	return out
+}
+
+// redactCandidates — кандидаты для траектории с токенами вместо найденных данных.
+func redactCandidates(red *redact.Redactor, cs []dom.Candidate) []dom.Candidate {
+	if red == nil {
+		return cs
+	}
+	out := make([]dom.Candidate, len(cs))
+	for i, c := range cs {
+		c.Text, c.Desc, c.Href, c.Value = red.Redact(c.Text), red.Redact(c.Desc), red.Redact(c.Href), red.Redact(c.Value)
+		c.Options = redactAll(red, c.Options)
+		out[i] = c
+	}
+	return out
+}
+
+// redactTabs — список вкладок для промпта: «#2* Заголовок — url», * — активная.
+func redactTabs(red *redact.Redactor, ts []Tab) []string {
+	out := make([]string, 0, len(ts))
+	for _, t := range ts {
+		mark := ""
+		if t.Active {
+			mark = "*"
+		}
+		out = append(out, red.Redact(fmt.Sprintf("#%d%s %s — %s", t.Index, mark, t.Title, t.URL)))
+	}
+	return out
+}
+
+// redactHistory — последние limit переходов для промпта: «-2 Заголовок — url»,
+// смещение от текущей записи (0) — столько шагов для go_back или go_forward.
+func redactHistory(red *redact.Redactor, vs []Visit, limit int) []string {
+	cur := 0
+	for i, v := range vs {
+		if v.Current {
+			cur = i
+		}
+	}
+	from := max(0, cur-limit+2)
+	to := min(len(vs), from+limit)
+	out := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		v := vs[i]
+		mark := ""
+		if v.Stuck {
+			mark = " (stuck here before)"
+		}
+		out = append(out, red.Redact(fmt.Sprintf("%+d %s — %s%s", i-cur, v.Title, v.URL, mark)))
+	}
+	return out
+}
+
+func obs_snapshot(obs Observation, limit int) string {
 	// Короткий отрывок контента страницы для контекста
 	if obs.Snapshot == "" {
 		return ""
 	}
-	if len(obs.Snapshot) > 1000 {
-		return obs.Snapshot[:1000] + "…"
+	if limit > 0 && len(obs.Snapshot) > limit {
+		return obs.Snapshot[:limit] + "…"
 	}
 	return obs.Snapshot
 }
@@ -277,7 +930,12 @@ func tryExtractJSON(s string) llmAction {
 	return act
 }
 
+// alreadyInInbox — открыт список писем: по адресу у известных клиентов,
+// по тексту страницы у остальных.
 func alreadyInInbox(obs Observation) bool {
+	if _, kind := mail.KindOf(obs.URL); kind != mail.PageUnknown {
+		return kind == mail.PageMessageList
+	}
 	s := strings.ToLower(obs.Title + " " + obs.URL + " " + obs.Snapshot)
 	return strings.Contains(s, "входящие") || strings.Contains(s, "inbox")
 }
@@ -286,7 +944,19 @@ func simpleHeuristicDecision(task string, obs Observation, mem *memory.Memory) l
 	lowTask := strings.ToLower(task)
 	last := strings.ToLower(mem.LastAction())
 
+	// Страница заблокирована диалогом, который не закрыли правила: безопаснее отказаться.
+	if obs.Dialog != nil {
+		return llmAction{Tool: "handle_dialog", Args: map[string]any{"action": "dismiss"}, Comment: "Закрываю диалог " + obs.Dialog.Type}
+	}
+
 	if alreadyInInbox(obs) {
+		if client, _ := mail.KindOf(obs.URL); client != "generic" && !strings.HasPrefix(last, "error") {
+			return llmAction{
+				Tool:    "mail_open",
+				Args:    map[string]any{"index": 1.0},
+				Comment: "Открываю первое письмо в списке",
+			}
+		}
 		return llmAction{
 			Tool:    "open_first_main_item",
 			Args:    map[string]any{},
@@ -316,8 +986,8 @@ func simpleHeuristicDecision(task string, obs Observation, mem *memory.Memory) l
 
 	return llmAction{
 		Tool:    "answer_or_ask_user",
-		Args:    map[string]any{},
-		Comment: "Нужна доп. информация (вы уже авторизованы и открыт Inbox?).",
+		Args:    map[string]any{"question": "Нужна доп. информация (вы уже авторизованы и открыт Inbox?)."},
+		Comment: "Не хватает данных для следующего шага",
 	}
 }
 
@@ -356,11 +1026,5 @@ func findByContains(cands []dom.Candidate, qs []string, fields ...string) string
 }
 
 func guessMailURL(task string) string {
-	if strings.Contains(task, "яндекс") || strings.Contains(task, "yandex") {
-		return "https://mail.yandex.ru/"
-	}
-	if strings.Contains(task, "gmail") || strings.Contains(task, "google") {
-		return "https://mail.google.com/mail/u/0/#inbox"
-	}
-	return "https://mail.yandex.ru/"
+	return mail.ForTask(task).InboxURL()
 }
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"AIAgent/internal/dom"
+)
+
+func TestResolveRef(t *testing.T) {
+	obs := Observation{Candidates: []dom.Candidate{{Selector: "#inbox"}, {Selector: "#trash"}}}
+	tests := []struct {
+		name string
+		args map[string]any
+		want map[string]any
+	}{
+		{"nil", nil, nil},
+		{"number", map[string]any{"ref": 1.0}, map[string]any{"ref": 1.0, "selector": "#inbox"}},
+		{"int", map[string]any{"ref": 2}, map[string]any{"ref": 2, "selector": "#trash"}},
+		{"hash string", map[string]any{"ref": " #2 "}, map[string]any{"ref": " #2 ", "selector": "#trash"}},
+		{"selector wins", map[string]any{"ref": 1.0, "selector": ".x"}, map[string]any{"ref": 1.0, "selector": ".x"}},
+		{"out of range", map[string]any{"ref": 3.0}, map[string]any{"ref": 3.0}},
+		{"zero", map[string]any{"ref": "#0"}, map[string]any{"ref": "#0"}},
+		{"not a number", map[string]any{"ref": true}, map[string]any{"ref": true}},
+		{
+			"drag",
+			map[string]any{"ref": 1.0, "target_ref": "#2"},
+			map[string]any{"ref": 1.0, "target_ref": "#2", "selector": "#inbox", "target": "#trash"},
+		},
+		{
+			// Пустые ключи уже есть: второй ref не должен затереть первый.
+			"drag with empty keys",
+			map[string]any{"ref": 1.0, "selector": "", "target_ref": 2.0, "target": ""},
+			map[string]any{"ref": 1.0, "selector": "#inbox", "target_ref": 2.0, "target": "#trash"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var orig map[string]any
+			if tt.args != nil {
+				orig = make(map[string]any, len(tt.args))
+				for k, v := range tt.args {
+					orig[k] = v
+				}
+			}
+			got := resolveRef(tt.args, obs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveRef = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.args, orig) {
+				t.Errorf("args modified: %v", tt.args)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"AIAgent/internal/checkpoint"
+	"AIAgent/internal/events"
+	"AIAgent/internal/mailbox"
+	"AIAgent/internal/redact"
+	"AIAgent/internal/skill"
+	"AIAgent/internal/spam"
+	"AIAgent/internal/trajectory"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Config — все настраиваемые параметры цикла агента.
+type Config struct {
+	MaxSteps int `yaml:"max_steps"`
+	// Сколько кандидатов собирать на первом и последующих наблюдениях.
+	InitialCandidates int `yaml:"initial_candidates"`
+	Candidates        int `yaml:"candidates"`
+	// Сколько кандидатов передавать модели.
+	PromptCandidates int `yaml:"prompt_candidates"`
+
+	// Размеры текстового снимка страницы (в байтах).
+	SnapshotChars       int `yaml:"snapshot_chars"`
+	PromptSnapshotChars int `yaml:"prompt_snapshot_chars"`
+	ExtractChars        int `yaml:"extract_chars"`
+
+	// Пауза между шагами сверх ожидания, пока страница успокоится.
+	StepDelay time.Duration `yaml:"step_delay"`
+
+	// Settle — ожидание после действия: тишина DOM, сети и анимаций.
+	Settle SettleConfig `yaml:"settle"`
+
+	// LogFormat — формат журнала в Log: text, json или none.
+	LogFormat string `yaml:"log_format"`
+	// Log — куда писать журнал (по умолчанию os.Stdout).
+	Log io.Writer `yaml:"-"`
+	// Events — шина для внешних подписчиков (HTTP API, пользовательские хуки).
+	Events *events.Bus `yaml:"-"`
+	// Control — ручное управление запуском (пауза, шаг, подсказки); может быть nil.
+	Control *Control `yaml:"-"`
+	// RunID проставляется во все события запуска.
+	RunID string `yaml:"-"`
+	// AskUser задаёт вопрос пользователю и ждёт ответа. Если nil, вопрос
+	// модели завершает задачу со статусом StatusNeedInput.
+	AskUser func(ctx context.Context, question string) (string, error) `yaml:"-"`
+	// OnPage вызывается, когда агент делает активной другую вкладку; может быть nil.
+	OnPage func(playwright.Page) `yaml:"-"`
+
+	Timeouts Timeouts `yaml:"timeouts"`
+
+	Checkpoints CheckpointConfig `yaml:"checkpoints"`
+
+	Trajectory TrajectoryConfig `yaml:"trajectory"`
+
+	Skills SkillsConfig `yaml:"skills"`
+
+	Spam SpamConfig `yaml:"spam"`
+
+	Files FilesConfig `yaml:"files"`
+
+	Dialogs DialogsConfig `yaml:"dialogs"`
+
+	Vision VisionConfig `yaml:"vision"`
+
+	// Mailbox — доступ к ящику по IMAP или Maildir для инструментов mailbox_*.
+	Mailbox mailbox.Config `yaml:"mailbox"`
+
+	LLM    LLMConfig     `yaml:"llm"`
+	Policy Policy        `yaml:"policy"`
+	Redact redact.Config `yaml:"redact"`
+}
+
+// LLMConfig — провайдер планировщика.
+type LLMConfig struct {
+	// Provider: "openai" (любой OpenAI-совместимый endpoint) или "heuristic".
+	Provider    string        `yaml:"provider"`
+	Model       string        `yaml:"model"`
+	Endpoint    string        `yaml:"endpoint"`
+	APIKey      string        `yaml:"api_key"`
+	Temperature float64       `yaml:"temperature"`
+	Timeout     time.Duration `yaml:"timeout"`
+}
+
+// Timeouts — дедлайны выполнения; 0 отключает соответствующее ограничение.
+type Timeouts struct {
+	// Run — на всю задачу.
+	Run time.Duration `yaml:"run"`
+	// Step — на один шаг: решение LLM, действие и наблюдение.
+	Step time.Duration `yaml:"step"`
+	// Tool — на один вызов инструмента; PerTool переопределяет его по имени инструмента.
+	Tool    time.Duration            `yaml:"tool"`
+	PerTool map[string]time.Duration `yaml:"per_tool"`
+}
+
+// CheckpointConfig — сохранение состояния после каждого шага для agent resume.
+type CheckpointConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Dir     string `yaml:"dir"`
+}
+
+// TrajectoryConfig — запись траектории запуска для воспроизведения и экспорта.
+type TrajectoryConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Dir         string `yaml:"dir"`
+	Screenshots bool   `yaml:"screenshots"`
+}
+
+// SkillsConfig — библиотека навыков, доступных планировщику через run_skill.
+type SkillsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Dir     string `yaml:"dir"`
+}
+
+// FilesConfig — обмен файлами со страницей: upload_file и сохранение скачиваний.
+type FilesConfig struct {
+	// UploadDir — каталог-песочница для upload_file; пусто — загрузка файлов запрещена.
+	UploadDir string `yaml:"upload_dir"`
+	// Downloads — сохранять скачивания в DownloadDir/<run-id>.
+	Downloads   bool   `yaml:"downloads"`
+	DownloadDir string `yaml:"download_dir"`
+}
+
+// VisionConfig — снимок видимой области в каждом запросе к модели, для
+// click_at на холстах и страницах без селекторов. Снимок уходит без маскирования данных.
+type VisionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Quality — качество JPEG, 1–100.
+	Quality int `yaml:"quality"`
+}
+
+// SpamConfig — локальный классификатор писем для mail_classify.
+type SpamConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Dir     string `yaml:"dir"`
+	// Threshold — уверенность, начиная с которой спам предлагается удалить.
+	Threshold float64 `yaml:"threshold"`
+}
+
+// Policy — правила реакции цикла на отсутствие прогресса.
+type Policy struct {
+	// После скольких шагов без изменения контента включается принудительный fallback.
+	NoProgressLimit int `yaml:"no_progress_limit"`
+	// Разрешено ли принудительно открывать первый элемент списка / скроллить.
+	StallFallback bool `yaml:"stall_fallback"`
+	// Backtrack — если и принудительные действия не помогли, вернуться по
+	// истории на последнюю страницу, где агент не застревал.
+	Backtrack bool `yaml:"backtrack"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MaxSteps:            40,
+		InitialCandidates:   24,
+		Candidates:          36,
+		PromptCandidates:    80,
+		SnapshotChars:       3000,
+		PromptSnapshotChars: 1000,
+		ExtractChars:        6000,
+		Settle:              DefaultSettle(),
+		LogFormat:           "text",
+		LLM: LLMConfig{
+			Provider:    "openai",
+			Model:       "gpt-4o",
+			Endpoint:    "https://api.openai.com/v1/chat/completions",
+			APIKey:      strings.TrimSpace(os.Getenv("OPENAI_API_KEY")),
+			Temperature: 0.2,
+			Timeout:     45 * time.Second,
+		},
+		Timeouts: Timeouts{
+			Run:  15 * time.Minute,
+			Step: 2 * time.Minute,
+			Tool: 30 * time.Second,
+		},
+		// Контрольная точка содержит куки и localStorage, то есть живую сессию:
+		// пишется на диск только по явному -checkpoints.
+		Checkpoints: CheckpointConfig{
+			Dir: defaultCheckpointDir(),
+		},
+		// Скриншоты редактор не маскирует, поэтому они пишутся только по -screenshots.
+		Trajectory: TrajectoryConfig{
+			Enabled: true,
+			Dir:     defaultTrajectoryDir(),
+		},
+		Skills: SkillsConfig{
+			Enabled: true,
+			Dir:     defaultSkillsDir(),
+		},
+		Spam: SpamConfig{
+			Enabled:   true,
+			Dir:       defaultSpamDir(),
+			Threshold: 0.8,
+		},
+		Dialogs: DialogsConfig{
+			Mode:    "planner",
+			Default: "dismiss",
+		},
+		Vision: VisionConfig{
+			Quality: 60,
+		},
+		Files: FilesConfig{
+			Downloads:   true,
+			DownloadDir: defaultDownloadDir(),
+		},
+		Mailbox: mailbox.Config{
+			TLS:     true,
+			Timeout: 30 * time.Second,
+		},
+		Policy: Policy{
+			NoProgressLimit: 2,
+			StallFallback:   true,
+			Backtrack:       true,
+		},
+		Redact: redact.DefaultConfig(),
+	}
+}
+
+// useLLM — есть ли смысл обращаться к модели; иначе работает эвристика.
+func (c Config) useLLM() bool {
+	return c.LLM.Provider != "heuristic" && c.LLM.APIKey != ""
+}
+
+// newTools создаёт инструменты для страницы, загружает библиотеку навыков и
+// классификатор писем и готовит подключение к ящику. Вызывающий закрывает их через Close.
+func (c Config) newTools(page playwright.Page) (*Tools, error) {
+	t := &Tools{Page: page, ExtractChars: c.ExtractChars, Timeout: c.Timeouts.Tool, PerTool: c.Timeouts.PerTool}
+	t.UploadDir, t.Settle = c.Files.UploadDir, c.Settle
+	t.onPage = c.OnPage
+	t.rememberTabs()
+	t.trackNetwork()
+	t.watchHistory()
+	if c.Files.Downloads {
+		t.watchDownloads(downloadDir(c.Files.DownloadDir, c.RunID))
+	}
+	// Без LLM решать некому: диалоги без правила закрываются действием по умолчанию.
+	if err := t.watchDialogs(c.Dialogs, !c.useLLM()); err != nil {
+		return t, err
+	}
+	if c.Skills.Enabled {
+		lib, err := skill.Open(c.Skills.Dir)
+		if err != nil {
+			return t, fmt.Errorf("skills: %w", err)
+		}
+		t.Skills = lib
+	}
+	if c.Spam.Enabled {
+		cl, err := spam.Open(c.Spam.Dir, c.Spam.Threshold)
+		if err != nil {
+			return t, fmt.Errorf("spam: %w", err)
+		}
+		t.Spam = cl
+	}
+	mb, err := mailbox.NewClient(c.Mailbox)
+	if err != nil {
+		return t, err
+	}
+	t.Mailbox = mb
+	return t, nil
+}
+
+// emitter возвращает функцию публикации событий запуска: в журнал
+// согласно LogFormat и во внешнюю шину Events.
+func (c Config) emitter() func(events.Event) {
+	out := c.Log
+	if out == nil {
+		out = os.Stdout
+	}
+	var log events.Handler
+	switch c.LogFormat {
+	case "json":
+		log = events.JSONLines(out)
+	case "none":
+	default:
+		log = events.Console(out)
+	}
+	return func(e events.Event) {
+		e.Run = c.RunID
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		if log != nil {
+			log(e)
+		}
+		c.Events.Publish(e)
+	}
+}
+
+func defaultCheckpointDir() string {
+	dir, err := checkpoint.DefaultDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "aiagent-runs")
+	}
+	return dir
+}
+
+func defaultDownloadDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "aiagent-downloads")
+	}
+	return filepath.Join(home, ".aiagent", "downloads")
+}
+
+func defaultSkillsDir() string {
+	dir, err := skill.DefaultDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "aiagent-skills")
+	}
+	return dir
+}
+
+func defaultSpamDir() string {
+	dir, err := spam.DefaultDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "aiagent-spam")
+	}
+	return dir
+}
+
+func defaultTrajectoryDir() string {
+	dir, err := trajectory.DefaultDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "aiagent-trajectories")
+	}
+	return dir
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// Control — ручное управление запущенной задачей: пауза после текущего шага,
+// пошаговое выполнение, подсказки планировщику, подмена следующего действия
+// и передача браузера человеку. Методы безопасны для вызова из других горутин.
+type Control struct {
+	mu       sync.Mutex
+	paused   bool
+	takeover bool
+	steps    int // сколько шагов разрешено выполнить в режиме паузы
+	hints    []string
+	override []llmAction
+	wake     chan struct{}
+}
+
+func NewControl() *Control {
+	return &Control{wake: make(chan struct{})}
+}
+
+// notify будит ожидающий gate; вызывается под c.mu.
+func (c *Control) notify() {
+	close(c.wake)
+	c.wake = make(chan struct{})
+}
+
+// Pause останавливает цикл перед следующим шагом.
+func (c *Control) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = true
+	c.notify()
+}
+
+// Resume снимает паузу и возвращает управление агенту.
+func (c *Control) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused, c.takeover, c.steps = false, false, 0
+	c.notify()
+}
+
+// Step выполняет один шаг и снова встаёт на паузу.
+func (c *Control) Step() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = true
+	c.takeover = false
+	c.steps++
+	c.notify()
+}
+
+// TakeOver ставит цикл на паузу, пока человек работает в браузере сам.
+// HandBack (или Resume) возвращает управление; агент заново снимет наблюдение.
+func (c *Control) TakeOver() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused, c.takeover = true, true
+	c.notify()
+}
+
+func (c *Control) HandBack() { c.Resume() }
+
+// Hint передаёт планировщику подсказку оператора (учитывается на следующем шаге).
+func (c *Control) Hint(text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hints = append(c.hints, text)
+}
+
+// Override заменяет решение планировщика на следующем шаге.
+// Аргумент ref (номер кандидата из наблюдения) превращается в selector.
+func (c *Control) Override(tool string, args map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if args == nil {
+		args = map[string]any{}
+	}
+	c.override = append(c.override, llmAction{Tool: tool, Args: args, Comment: "ручное действие оператора"})
+	c.notify()
+}
+
+// Paused сообщает, стоит ли цикл на паузе.
+func (c *Control) Paused() (paused, takeover bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused, c.takeover
+}
+
+// gate блокирует цикл, пока он на паузе. Ручное действие при паузе
+// выполняется как один шаг. waited=true, если цикл действительно ждал
+// (страница могла измениться и наблюдение нужно снять заново).
+func (c *Control) gate(ctx context.Context) (waited bool, err error) {
+	if c == nil {
+		return false, nil
+	}
+	for {
+		c.mu.Lock()
+		switch {
+		case !c.paused:
+			c.mu.Unlock()
+			return waited, nil
+		case !c.takeover && c.steps > 0:
+			c.steps--
+			c.mu.Unlock()
+			return waited, nil
+		case !c.takeover && len(c.override) > 0:
+			c.mu.Unlock()
+			return waited, nil
+		}
+		wake := c.wake
+		c.mu.Unlock()
+
+		waited = true
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return waited, ctx.Err()
+		}
+	}
+}
+
+func (c *Control) nextOverride() (llmAction, bool) {
+	if c == nil {
+		return llmAction{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.override) == 0 {
+		return llmAction{}, false
+	}
+	act := c.override[0]
+	c.override = c.override[1:]
+	return act, true
+}
+
+func (c *Control) takeHints() []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hints
+	c.hints = nil
+	return h
+}
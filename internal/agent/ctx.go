@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// do выполняет блокирующий вызов Playwright (который не принимает context)
+// и возвращается досрочно при отмене ctx. Сам вызов при этом дорабатывает
+// в фоне, но ограничен таймаутом из pwTimeout; поэтому fn не должна менять
+// Tools — действия инструментов идут через callPage.
+func do[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+	type result struct {
+		v   T
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		ch <- result{v, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.v, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// pwTimeout переводит дедлайн ctx в таймаут Playwright (мс); nil — таймаут по умолчанию контекста браузера.
+func pwTimeout(ctx context.Context) *float64 {
+	dl, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	ms := float64(time.Until(dl).Milliseconds())
+	if ms < 1 {
+		ms = 1
+	}
+	return &ms
+}
+
+// sleepCtx — time.Sleep, прерываемый отменой ctx.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withTimeout — context.WithTimeout, не трогающий ctx при d <= 0.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// deadlineError — причина отмены по дедлайну запуска или шага (context.Cause):
+// по ней инструмент сообщает, какой именно дедлайн истёк.
+type deadlineError struct {
+	scope string
+	d     time.Duration
+}
+
+func (e deadlineError) Error() string {
+	return fmt.Sprintf("%s deadline of %s exceeded", e.scope, e.d)
+}
+
+// withDeadline — withTimeout, который записывает в причину отмены, чей это дедлайн.
+func withDeadline(ctx context.Context, d time.Duration, scope string) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, d, deadlineError{scope, d})
+}
+
+// timeoutError объясняет модели, почему вызов name прерван по времени: истёк
+// таймаут самого инструмента d или дедлайн шага/запуска из ctx.
+func timeoutError(ctx context.Context, name string, d time.Duration) error {
+	var de deadlineError
+	if errors.As(context.Cause(ctx), &de) {
+		return fmt.Errorf("%s: %w", name, de)
+	}
+	if d > 0 {
+		return fmt.Errorf("%s: timeout after %s", name, d)
+	}
+	return fmt.Errorf("%s: deadline exceeded", name)
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeoutError(t *testing.T) {
+	expired := func(parent context.Context, d time.Duration, scope string) context.Context {
+		ctx, cancel := withDeadline(parent, d, scope)
+		t.Cleanup(cancel)
+		<-ctx.Done()
+		return ctx
+	}
+	tests := []struct {
+		name string
+		// ctx — контекст вызова инструмента после истечения дедлайна.
+		ctx  func() context.Context
+		tool time.Duration
+		want string
+	}{
+		{
+			"tool timeout",
+			func() context.Context {
+				ctx, cancel := withTimeout(context.Background(), time.Millisecond)
+				t.Cleanup(cancel)
+				<-ctx.Done()
+				return ctx
+			},
+			time.Millisecond,
+			"click: timeout after 1ms",
+		},
+		{
+			"step deadline before tool timeout",
+			func() context.Context {
+				ctx, cancel := withTimeout(expired(context.Background(), time.Millisecond, "step"), time.Minute)
+				t.Cleanup(cancel)
+				return ctx
+			},
+			time.Minute,
+			"click: step deadline of 1ms exceeded",
+		},
+		{
+			"run deadline, tool without timeout",
+			func() context.Context {
+				step, cancel := withDeadline(expired(context.Background(), 2*time.Millisecond, "run"), time.Minute, "step")
+				t.Cleanup(cancel)
+				ctx, cancel := withTimeout(step, 0)
+				t.Cleanup(cancel)
+				return ctx
+			},
+			0,
+			"click: run deadline of 2ms exceeded",
+		},
+		{
+			"unknown deadline",
+			func() context.Context {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+				t.Cleanup(cancel)
+				<-ctx.Done()
+				return ctx
+			},
+			0,
+			"click: deadline exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx()
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				t.Fatalf("ctx.Err() = %v", ctx.Err())
+			}
+			if got := timeoutError(ctx, "click", tt.tool).Error(); got != tt.want {
+				t.Errorf("timeoutError = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// DialogsConfig — обработка диалогов alert/confirm/prompt/beforeunload и запросов разрешений.
+type DialogsConfig struct {
+	// Mode: "planner" — диалог без подходящего правила ждёт решения
+	// планировщика (handle_dialog); "auto" — сразу применяется Default.
+	// Без LLM всегда действует "auto".
+	Mode string `yaml:"mode"`
+	// Default — действие для диалогов без правила в режиме auto: accept или dismiss.
+	Default string       `yaml:"default"`
+	Rules   []DialogRule `yaml:"rules"`
+	// Permissions выдаются контексту браузера при старте (geolocation,
+	// notifications, clipboard-read…); остальные запросы отклоняются браузером.
+	Permissions []string `yaml:"permissions"`
+}
+
+// DialogRule — автоматический ответ на диалог: первый подходящий по типу и тексту.
+type DialogRule struct {
+	// Type: alert, confirm, prompt, beforeunload; пусто — любой.
+	Type string `yaml:"type"`
+	// Match — регулярное выражение по тексту диалога (без учёта регистра); пусто — любой текст.
+	Match  string `yaml:"match"`
+	Action string `yaml:"action"`
+	// Text — ответ для prompt.
+	Text string `yaml:"text"`
+}
+
+// DefaultDialogRules — alert и уход со страницы подтверждаются без вопросов.
+func DefaultDialogRules() []DialogRule {
+	return []DialogRule{
+		{Type: "alert", Action: "accept"},
+		{Type: "beforeunload", Action: "accept"},
+	}
+}
+
+// Dialog — открытый диалог, блокирующий страницу.
+type Dialog struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// Default — предложенное значение prompt.
+	Default string `json:"default,omitempty"`
+}
+
+type dialogRule struct {
+	DialogRule
+	re *regexp.Regexp
+}
+
+// dialogs следит за диалогами всех вкладок контекста.
+type dialogs struct {
+	// w — подписки контекста, через которые приходят диалоги.
+	w      *watch
+	auto   bool
+	def    string
+	rules  []dialogRule
+	closed bool
+
+	mu      sync.Mutex
+	pending playwright.Dialog
+	// opened закрывается, когда появляется ожидающий диалог; после ответа заменяется новым.
+	opened chan struct{}
+	notes  []string
+}
+
+func newDialogs(cfg DialogsConfig, auto bool) (*dialogs, error) {
+	d := &dialogs{auto: auto || cfg.Mode == "auto", def: cfg.Default, opened: make(chan struct{})}
+	switch cfg.Mode {
+	case "", "planner", "auto":
+	default:
+		return nil, fmt.Errorf("dialogs: unknown mode %q (planner, auto)", cfg.Mode)
+	}
+	if d.def == "" {
+		d.def = "dismiss"
+	}
+	for _, r := range append(append([]DialogRule{}, cfg.Rules...), DefaultDialogRules()...) {
+		if r.Action != "accept" && r.Action != "dismiss" {
+			return nil, fmt.Errorf("dialogs: rule %q: action must be accept or dismiss", r.Match)
+		}
+		dr := dialogRule{DialogRule: r}
+		if r.Match != "" {
+			re, err := regexp.Compile("(?i)" + r.Match)
+			if err != nil {
+				return nil, fmt.Errorf("dialogs: rule %q: %w", r.Match, err)
+			}
+			dr.re = re
+		}
+		d.rules = append(d.rules, dr)
+	}
+	return d, nil
+}
+
+// watchDialogs направляет этому запуску диалоги всех вкладок контекста, в том
+// числе будущих, и выдаёт контексту разрешения из конфигурации.
+func (t *Tools) watchDialogs(cfg DialogsConfig, auto bool) error {
+	if t.Page == nil {
+		return nil
+	}
+	d, err := newDialogs(cfg, auto)
+	if err != nil {
+		return err
+	}
+	t.dlg = d
+	if len(cfg.Permissions) > 0 {
+		if err := t.Page.Context().GrantPermissions(cfg.Permissions); err != nil {
+			return fmt.Errorf("dialogs: permissions: %w", err)
+		}
+	}
+	d.w = watchContext(t.Page.Context())
+	d.w.addDialogs(d)
+	return nil
+}
+
+func (d *dialogs) match(dg playwright.Dialog) (DialogRule, bool) {
+	for _, r := range d.rules {
+		if r.Type != "" && r.Type != dg.Type() {
+			continue
+		}
+		if r.re != nil && !r.re.MatchString(dg.Message()) {
+			continue
+		}
+		return r.DialogRule, true
+	}
+	if d.auto {
+		return DialogRule{Action: d.def}, true
+	}
+	return DialogRule{}, false
+}
+
+// open вызывается из обработчика события: ответ отправляется в отдельной
+// горутине, иначе он ждал бы сам себя в цикле событий Playwright.
+func (d *dialogs) open(dg playwright.Dialog) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		// Запуск закончился, пока диалог шёл к нему.
+		go respond(dg, "dismiss", "")
+		return
+	}
+	if r, ok := d.match(dg); ok {
+		d.notes = append(d.notes, fmt.Sprintf("auto-%sed %s %q", r.Action, dg.Type(), crop(dg.Message(), 120)))
+		go respond(dg, r.Action, r.Text)
+		return
+	}
+	if d.pending != nil {
+		// Второй диалог при уже открытом невозможен; на всякий случай не блокируем страницу.
+		go respond(dg, d.def, "")
+		return
+	}
+	d.pending = dg
+	close(d.opened)
+}
+
+func respond(dg playwright.Dialog, action, text string) error {
+	if action == "accept" {
+		if text == "" && dg.Type() == "prompt" {
+			text = dg.DefaultValue()
+		}
+		return dg.Accept(text)
+	}
+	return dg.Dismiss()
+}
+
+// waitCh — канал, закрытый при открытом диалоге (или закроющийся, когда диалог появится).
+func (d *dialogs) waitCh() <-chan struct{} {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.opened
+}
+
+// Pending — диалог, ожидающий решения планировщика, или nil.
+func (d *dialogs) Pending() *Dialog {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pending == nil {
+		return nil
+	}
+	return &Dialog{Type: d.pending.Type(), Message: d.pending.Message(), Default: d.pending.DefaultValue()}
+}
+
+// handle отвечает на ожидающий диалог.
+func (d *dialogs) handle(action, text string) (string, error) {
+	if d == nil {
+		return "", errors.New("handle_dialog: dialogs are not watched")
+	}
+	d.mu.Lock()
+	dg := d.pending
+	if dg != nil {
+		d.pending = nil
+		d.opened = make(chan struct{})
+	}
+	d.mu.Unlock()
+	if dg == nil {
+		return "", errors.New("handle_dialog: no open dialog")
+	}
+	if err := respond(dg, action, text); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%sed %s %q", action, dg.Type(), crop(dg.Message(), 120)), nil
+}
+
+// takeNotes — автоматически обработанные диалоги с прошлого вызова.
+func (d *dialogs) takeNotes() string {
+	if d == nil {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := strings.Join(d.notes, "; ")
+	d.notes = nil
+	return s
+}
+
+// close отключает запуск от диалогов контекста: ожидающий диалог закрывается действием по умолчанию,
+// чтобы страница не осталась заблокированной для следующего запуска.
+func (d *dialogs) close() {
+	if d == nil {
+		return
+	}
+	d.w.removeDialogs(d)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	if d.pending != nil {
+		go respond(d.pending, d.def, "")
+		d.pending = nil
+	}
+}
+
+// handleDialog — инструмент handle_dialog {action, text?}.
+func (t *Tools) handleDialog(args map[string]any) (string, error) {
+	action, _ := args["action"].(string)
+	action = strings.ToLower(strings.TrimSpace(action))
+	switch action {
+	case "accept", "ok", "yes":
+		action = "accept"
+	case "dismiss", "cancel", "no":
+		action = "dismiss"
+	default:
+		return "", errors.New("handle_dialog: action must be accept or dismiss")
+	}
+	text, _ := args["text"].(string)
+	return t.dlg.handle(action, text)
+}
+
+// dialogFree — инструменты, которые можно вызывать, пока страница заблокирована диалогом.
+func dialogFree(name string) bool {
+	return name == "handle_dialog" || name == "answer_or_ask_user" || isMailboxTool(name)
+}
+
+// callGrace — сколько после отмены ждать действие, которое не принимает
+// таймаут Playwright (Evaluate, Keyboard): дольше оно держит вызов только на
+// зависшей странице.
+const callGrace = 5 * time.Second
+
+// callPage выполняет действие, но возвращается сразу, как только оно открыло
+// диалог: Playwright не завершает действие, пока диалог не закрыт. Такое
+// действие доработает в фоне; следующий вызов и syncTabs дождутся его, чтобы
+// Page и known не менялись из двух горутин сразу.
+func (t *Tools) callPage(ctx context.Context, name string, args map[string]any) (string, error) {
+	if dialogFree(name) {
+		return do(ctx, func() (string, error) { return t.call(ctx, name, args) })
+	}
+	if p := t.dlg.Pending(); p != nil {
+		return "", fmt.Errorf("%s: page is blocked by %s dialog %q, call handle_dialog first", name, p.Type, crop(p.Message, 120))
+	}
+	if err := t.waitInflight(ctx); err != nil {
+		return "", fmt.Errorf("%s: previous action is still running: %w", name, err)
+	}
+	opened := t.dlg.waitCh()
+	var (
+		v    string
+		err  error
+		done = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		v, err = t.call(ctx, name, args)
+	}()
+	select {
+	case <-done:
+		return v, err
+	case <-opened:
+		p := t.dlg.Pending()
+		if p == nil {
+			// Диалог уже закрыт — действие доработает само.
+			<-done
+			return v, err
+		}
+		t.setInflight(done)
+		return fmt.Sprintf("%s opened %s dialog %q; the page waits for handle_dialog", name, p.Type, crop(p.Message, 120)), nil
+	case <-ctx.Done():
+		// Вызовы Playwright ограничены тем же дедлайном (pwTimeout) и скоро вернутся.
+		select {
+		case <-done:
+		case <-opened:
+			t.setInflight(done)
+		case <-time.After(callGrace):
+			t.setInflight(done)
+		}
+		return "", ctx.Err()
+	}
+}
+
+func (t *Tools) setInflight(done chan struct{}) {
+	t.mu.Lock()
+	t.inflight = done
+	t.mu.Unlock()
+}
+
+// waitInflight ждёт действие, оставшееся работать в фоне после callPage.
+func (t *Tools) waitInflight(ctx context.Context) error {
+	t.mu.Lock()
+	done := t.inflight
+	t.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		t.setInflight(nil)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// idle — в фоне не работает ни одно действие.
+func (t *Tools) idle() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inflight == nil {
+		return true
+	}
+	select {
+	case <-t.inflight:
+		t.inflight = nil
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"AIAgent/internal/checkpoint"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Download — файл, скачанный в результате действия агента.
+type Download struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	MIME string `json:"mime,omitempty"`
+	URL  string `json:"url,omitempty"`
+	// Error — скачивание не удалось; Path и Size тогда пусты.
+	Error string `json:"error,omitempty"`
+}
+
+// downloads — скачивания инструментов: файлы сохраняются в отдельный каталог
+// запуска по мере появления, а планировщику сообщаются после действия.
+type downloads struct {
+	// dir — каталог запуска; создаётся при первом скачивании.
+	dir string
+	// w — подписки контекста, через которые приходят скачивания.
+	w *watch
+
+	mu       sync.Mutex
+	closed   bool
+	inflight int
+	done     []Download
+	reported int
+}
+
+// downloadWait — сколько после шага ждать начатые скачивания, прежде чем сообщить о них как о незавершённых.
+const downloadWait = 30 * time.Second
+
+// watchDownloads направляет этому запуску скачивания всех вкладок контекста, в
+// том числе будущих: ссылка на файл часто открывает вкладку, которая сразу закрывается.
+func (t *Tools) watchDownloads(dir string) {
+	if t.Page == nil || dir == "" {
+		return
+	}
+	t.dl = &downloads{dir: dir, w: watchContext(t.Page.Context())}
+	t.dl.w.addDownloads(t.dl)
+}
+
+func (dl *downloads) start(d playwright.Download) {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if dl.closed {
+		return
+	}
+	dl.inflight++
+	// SaveAs ждёт конца скачивания; в обработчике события это заблокировало бы соединение с браузером.
+	go func() {
+		r := dl.save(d)
+		dl.mu.Lock()
+		dl.inflight--
+		dl.done = append(dl.done, r)
+		dl.mu.Unlock()
+	}()
+}
+
+func (dl *downloads) save(d playwright.Download) Download {
+	r := Download{Name: safeFileName(d.SuggestedFilename()), URL: d.URL()}
+	if err := os.MkdirAll(dl.dir, 0o755); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	path, err := createUnique(filepath.Join(dl.dir, r.Name))
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if err := d.SaveAs(path); err != nil {
+		_ = os.Remove(path)
+		r.Error = err.Error()
+		return r
+	}
+	r.Path, r.Name = path, filepath.Base(path)
+	if st, err := os.Stat(path); err == nil {
+		r.Size = st.Size()
+	}
+	r.MIME = detectMIME(path)
+	return r
+}
+
+// take ждёт незавершённые скачивания не дольше wait (и ctx) и возвращает
+// ещё не сообщённые планировщику.
+func (dl *downloads) take(ctx context.Context, wait time.Duration) ([]Download, int) {
+	if dl == nil {
+		return nil, 0
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		dl.mu.Lock()
+		inflight := dl.inflight
+		if inflight == 0 || time.Now().After(deadline) || ctx.Err() != nil {
+			out := append([]Download(nil), dl.done[dl.reported:]...)
+			dl.reported = len(dl.done)
+			dl.mu.Unlock()
+			return out, inflight
+		}
+		dl.mu.Unlock()
+		_ = sleepCtx(ctx, 100*time.Millisecond)
+	}
+}
+
+// all — все скачивания запуска.
+func (dl *downloads) all() []Download {
+	if dl == nil {
+		return nil
+	}
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	return append([]Download(nil), dl.done...)
+}
+
+func (dl *downloads) close() {
+	if dl == nil {
+		return
+	}
+	dl.w.removeDownloads(dl)
+	dl.mu.Lock()
+	dl.closed = true
+	dl.mu.Unlock()
+}
+
+// downloadNote — сообщение о новых скачиваниях для результата действия.
+func (t *Tools) downloadNote(ctx context.Context, wait time.Duration) string {
+	got, pending := t.dl.take(ctx, wait)
+	var parts []string
+	for _, d := range got {
+		if d.Error != "" {
+			parts = append(parts, fmt.Sprintf("download %s failed: %s", d.Name, d.Error))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("downloaded %s (%d bytes, %s) to %s", d.Name, d.Size, d.MIME, d.Path))
+	}
+	if pending > 0 {
+		parts = append(parts, fmt.Sprintf("%d download(s) still in progress", pending))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Downloads — все файлы, скачанные за время работы инструментов.
+func (t *Tools) Downloads() []Download {
+	if t == nil {
+		return nil
+	}
+	return t.dl.all()
+}
+
+// downloadDir — каталог скачиваний запуска: <dir>/<run-id>.
+func downloadDir(dir, runID string) string {
+	if dir == "" {
+		return ""
+	}
+	if runID == "" {
+		runID = checkpoint.NewRunID()
+	}
+	return filepath.Join(dir, runID)
+}
+
+func safeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" || name == "" || name == ".." {
+		return "download"
+	}
+	return name
+}
+
+// createUnique создаёт пустой файл path, а если он есть — path с -1, -2… перед
+// расширением. Создание с O_EXCL резервирует имя, так что два одновременных
+// скачивания с одинаковым именем не запишут один файл.
+func createUnique(path string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 0; ; i++ {
+		p := path
+		if i > 0 {
+			p = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return p, f.Close()
+	}
+}
+
+// detectMIME — тип по расширению, иначе по первым байтам файла.
+func detectMIME(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, _ := f.Read(buf)
+	return http.DetectContentType(buf[:n])
+}
+
+// sandboxPath разрешает путь для upload_file внутри каталога dir; выход за
+// его пределы (../, абсолютный путь, символическая ссылка наружу) — ошибка.
+func sandboxPath(dir, p string) (string, error) {
+	if dir == "" {
+		return "", errors.New("upload_file: upload directory is not configured (-upload-dir)")
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("upload_file: %w", err)
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(root, p)
+	}
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", fmt.Errorf("upload_file: %w", err)
+	}
+	rel, err := filepath.Rel(root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("upload_file: %s is outside the upload directory %s", p, dir)
+	}
+	if st, err := os.Stat(real); err != nil {
+		return "", err
+	} else if st.IsDir() {
+		return "", fmt.Errorf("upload_file: %s is a directory", p)
+	}
+	return real, nil
+}
+
+// uploadFile прикрепляет файлы из каталога загрузок: к input[type=file]
+// напрямую, к любой другой кнопке — через диалог выбора файла, который она открывает.
+func (t *Tools) uploadFile(ctx context.Context, args map[string]any) (string, error) {
+	var paths []string
+	if p, _ := args["path"].(string); p != "" {
+		paths = append(paths, p)
+	}
+	if ps, ok := args["paths"].([]any); ok {
+		for _, p := range ps {
+			paths = append(paths, fmt.Sprint(p))
+		}
+	}
+	if len(paths) == 0 {
+		return "", errors.New("upload_file: path is required")
+	}
+	files := make([]string, 0, len(paths))
+	for _, p := range paths {
+		f, err := sandboxPath(t.UploadDir, p)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, f)
+	}
+	loc, sel, err := t.locate("upload_file", "selector", args)
+	if err != nil {
+		return "", err
+	}
+	timeout := pwTimeout(ctx)
+	isInput, _ := loc.Evaluate(`(el) => el.tagName === 'INPUT' && el.type === 'file'`, nil)
+	if isInput == true {
+		err = loc.SetInputFiles(files, playwright.LocatorSetInputFilesOptions{Timeout: timeout})
+	} else {
+		var fc playwright.FileChooser
+		fc, err = t.Page.ExpectFileChooser(func() error {
+			return loc.Click(playwright.LocatorClickOptions{Timeout: timeout})
+		}, playwright.PageExpectFileChooserOptions{Timeout: timeout})
+		if err == nil {
+			err = fc.SetFiles(files, playwright.FileChooserSetFilesOptions{Timeout: timeout})
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = filepath.Base(f)
+	}
+	return fmt.Sprintf("uploaded %s via %s", strings.Join(names, ", "), sel), nil
+}
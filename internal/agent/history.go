@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Visit — запись истории переходов вкладки.
+type Visit struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	// Current — позиция вкладки в истории: go_back и go_forward сдвигают её.
+	Current bool `json:"current,omitempty"`
+	// Stuck — здесь агент зациклился и откатился назад.
+	Stuck bool `json:"stuck,omitempty"`
+}
+
+// historyLimit — сколько переходов вкладки помнить.
+const historyLimit = 50
+
+// history — стек переходов одной вкладки, как у кнопок «назад» и «вперёд» браузера.
+type history struct {
+	visits []Visit
+	pos    int
+}
+
+// navigated учитывает переход основного фрейма, в том числе pushState и смену
+// #хэша. Переход на соседнюю запись считается шагом назад или вперёд: отличить
+// его от клика по ссылке на ту же страницу браузер всё равно не даёт.
+func (h *history) navigated(url string) {
+	switch {
+	case len(h.visits) == 0:
+	case url == h.visits[h.pos].URL:
+		return
+	case h.pos > 0 && url == h.visits[h.pos-1].URL:
+		h.pos--
+		return
+	case h.pos+1 < len(h.visits) && url == h.visits[h.pos+1].URL:
+		h.pos++
+		return
+	}
+	if len(h.visits) > 0 {
+		h.visits = h.visits[:h.pos+1]
+	}
+	h.visits = append(h.visits, Visit{URL: url})
+	if len(h.visits) > historyLimit {
+		h.visits = h.visits[len(h.visits)-historyLimit:]
+	}
+	h.pos = len(h.visits) - 1
+}
+
+// navigation — истории всех вкладок контекста.
+type navigation struct {
+	mu    sync.Mutex
+	pages map[playwright.Page]*history
+}
+
+// watchHistory начинает вести историю переходов всех вкладок контекста, в том числе будущих.
+func (t *Tools) watchHistory() {
+	if t.Page == nil {
+		return
+	}
+	t.nav = &navigation{pages: map[playwright.Page]*history{}}
+	nav := t.nav
+	attach := func(p playwright.Page) {
+		if u := p.URL(); u != "" && u != "about:blank" {
+			nav.navigated(p, u)
+		}
+		p.OnFrameNavigated(func(f playwright.Frame) {
+			if f.ParentFrame() == nil {
+				nav.navigated(p, f.URL())
+			}
+		})
+	}
+	for _, p := range t.Page.Context().Pages() {
+		attach(p)
+	}
+	t.Page.Context().OnPage(attach)
+}
+
+func (n *navigation) navigated(p playwright.Page, url string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	h := n.pages[p]
+	if h == nil {
+		h = &history{}
+		n.pages[p] = h
+	}
+	h.navigated(url)
+}
+
+// visits — история вкладки p; заголовок текущей записи обновляется из наблюдения.
+func (n *navigation) visits(p playwright.Page, url, title string) []Visit {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	h := n.pages[p]
+	if h == nil || len(h.visits) == 0 {
+		return nil
+	}
+	if cur := &h.visits[h.pos]; cur.URL == url && title != "" {
+		cur.Title = title
+	}
+	out := append([]Visit(nil), h.visits...)
+	out[h.pos].Current = true
+	return out
+}
+
+// backtrackSteps помечает текущую запись вкладки p как тупик и возвращает,
+// на сколько шагов назад лежит последняя запись, где агент не застревал (0 — такой нет).
+func (n *navigation) backtrackSteps(p playwright.Page) int {
+	if n == nil {
+		return 0
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	h := n.pages[p]
+	if h == nil || len(h.visits) == 0 {
+		return 0
+	}
+	h.visits[h.pos].Stuck = true
+	for i := h.pos - 1; i >= 0; i-- {
+		if !h.visits[i].Stuck {
+			return h.pos - i
+		}
+	}
+	return 0
+}
+
+// History — история переходов активной вкладки.
+func (t *Tools) History(title string) []Visit {
+	if t == nil || t.Page == nil {
+		return nil
+	}
+	return t.nav.visits(t.Page, t.Page.URL(), title)
+}
+
+// navigate исполняет go_back {steps?}, go_forward и reload.
+func (t *Tools) navigate(ctx context.Context, name string, args map[string]any) (string, error) {
+	before := t.Page.URL()
+	timeout := pwTimeout(ctx)
+	switch name {
+	case "go_back", "go_forward":
+		steps := 1
+		if v, ok := args["steps"].(float64); ok && v > 1 {
+			steps = int(v)
+		}
+		for i := 0; i < steps; i++ {
+			var err error
+			if name == "go_back" {
+				_, err = t.Page.GoBack(playwright.PageGoBackOptions{Timeout: timeout, WaitUntil: playwright.WaitUntilStateDomcontentloaded})
+			} else {
+				_, err = t.Page.GoForward(playwright.PageGoForwardOptions{Timeout: timeout, WaitUntil: playwright.WaitUntilStateDomcontentloaded})
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+		if t.Page.URL() == before {
+			dir := "previous"
+			if name == "go_forward" {
+				dir = "next"
+			}
+			return "", fmt.Errorf("%s: no %s page in history, still at %s", name, dir, before)
+		}
+		return fmt.Sprintf("%s to %s", map[string]string{"go_back": "went back", "go_forward": "went forward"}[name], t.Page.URL()), nil
+
+	case "reload":
+		resp, err := t.Page.Reload(playwright.PageReloadOptions{Timeout: timeout, WaitUntil: playwright.WaitUntilStateDomcontentloaded})
+		if err != nil {
+			return "", err
+		}
+		if resp != nil && resp.Status() >= 400 {
+			return fmt.Sprintf("reloaded %s (HTTP %d)", t.Page.URL(), resp.Status()), nil
+		}
+		return "reloaded " + t.Page.URL(), nil
+	}
+	return "", errors.New("unknown tool: " + name)
+}
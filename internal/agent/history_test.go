@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestHistoryNavigated(t *testing.T) {
+	tests := []struct {
+		name string
+		urls []string
+		want []string
+		pos  int
+	}{
+		{"linear", []string{"a", "b", "c"}, []string{"a", "b", "c"}, 2},
+		{"reload is not a visit", []string{"a", "a", "b", "b"}, []string{"a", "b"}, 1},
+		{"back", []string{"a", "b", "c", "b"}, []string{"a", "b", "c"}, 1},
+		{"back and forward", []string{"a", "b", "c", "b", "a", "b"}, []string{"a", "b", "c"}, 1},
+		{"new page drops forward entries", []string{"a", "b", "c", "b", "d"}, []string{"a", "b", "d"}, 2},
+		{"hash change", []string{"a", "a#x", "a#y"}, []string{"a", "a#x", "a#y"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h history
+			for _, u := range tt.urls {
+				h.navigated(u)
+			}
+			var got []string
+			for _, v := range h.visits {
+				got = append(got, v.URL)
+			}
+			if !reflect.DeepEqual(got, tt.want) || h.pos != tt.pos {
+				t.Errorf("visits = %v pos = %d, want %v pos = %d", got, h.pos, tt.want, tt.pos)
+			}
+		})
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	var h history
+	for i := 0; i < historyLimit+10; i++ {
+		h.navigated(fmt.Sprintf("https://x.example/%d", i))
+	}
+	if len(h.visits) != historyLimit || h.pos != historyLimit-1 {
+		t.Fatalf("len = %d pos = %d", len(h.visits), h.pos)
+	}
+	if first := h.visits[0].URL; first != "https://x.example/10" {
+		t.Errorf("oldest visit = %s", first)
+	}
+}
+
+func TestNavigationBacktrack(t *testing.T) {
+	// Ключ — вкладка; для проверки учёта достаточно nil.
+	n := &navigation{pages: map[playwright.Page]*history{}}
+	for _, u := range []string{"a", "b", "c"} {
+		n.navigated(nil, u)
+	}
+	tests := []struct {
+		name  string
+		back  []string // переходы агента перед очередным тупиком
+		steps int
+	}{
+		{"previous page", []string{"b"}, 1},
+		{"skip stuck entries", []string{"c"}, 2},
+		{"no way back", []string{"b", "a"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, u := range tt.back {
+				n.navigated(nil, u)
+			}
+			if got := n.backtrackSteps(nil); got != tt.steps {
+				t.Errorf("backtrackSteps = %d, want %d", got, tt.steps)
+			}
+		})
+	}
+	vs := n.visits(nil, "a", "Начало")
+	want := []Visit{{URL: "a", Title: "Начало", Current: true, Stuck: true}, {URL: "b", Stuck: true}, {URL: "c", Stuck: true}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("visits = %+v, want %+v", vs, want)
+	}
+	if (*navigation)(nil).visits(nil, "a", "") != nil || (*navigation)(nil).backtrackSteps(nil) != 0 {
+		t.Error("nil navigation is not empty")
+	}
+}
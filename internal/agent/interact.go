@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// locate — первый элемент по селектору из аргумента key (после resolveRef там уже селектор кандидата).
+func (t *Tools) locate(name, key string, args map[string]any) (playwright.Locator, string, error) {
+	sel, _ := args[key].(string)
+	sel = normalizeSelector(sel)
+	if sel == "" {
+		return nil, "", fmt.Errorf("%s: empty %s", name, key)
+	}
+	loc := t.Page.Locator(sel).First()
+	if n, err := loc.Count(); err != nil {
+		return nil, sel, err
+	} else if n == 0 {
+		return nil, sel, fmt.Errorf("%s: element not found: %s", name, sel)
+	}
+	return loc, sel, nil
+}
+
+// interact исполняет select_option, check, uncheck, hover, dblclick, right_click и drag.
+func (t *Tools) interact(ctx context.Context, name string, args map[string]any) (string, error) {
+	loc, sel, err := t.locate(name, "selector", args)
+	if err != nil {
+		return "", err
+	}
+	timeout := pwTimeout(ctx)
+	switch name {
+	case "select_option":
+		opts := optionArgs(args)
+		if len(opts) == 0 {
+			return "", errors.New("select_option: option is required")
+		}
+		tag, _ := loc.Evaluate("(el) => el.tagName.toLowerCase()", nil)
+		if tag == "select" {
+			got, err := loc.SelectOption(playwright.SelectOptionValues{ValuesOrLabels: &opts}, playwright.LocatorSelectOptionOptions{Timeout: timeout})
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("selected %s in %s", strings.Join(got, ", "), sel), nil
+		}
+		// ARIA-комбобокс: раскрываем список и выбираем пункт по имени.
+		if err := loc.Click(playwright.LocatorClickOptions{Timeout: timeout}); err != nil {
+			return "", err
+		}
+		for _, o := range opts {
+			opt := t.Page.Locator(fmt.Sprintf("role=option[name=%q]", o)).First()
+			if err := opt.Click(playwright.LocatorClickOptions{Timeout: timeout}); err != nil {
+				return "", fmt.Errorf("select_option: option %q: %w", o, err)
+			}
+		}
+		return fmt.Sprintf("selected %s in %s", strings.Join(opts, ", "), sel), nil
+
+	case "check":
+		return "checked " + sel, loc.Check(playwright.LocatorCheckOptions{Timeout: timeout})
+
+	case "uncheck":
+		return "unchecked " + sel, loc.Uncheck(playwright.LocatorUncheckOptions{Timeout: timeout})
+
+	case "hover":
+		return "hovered " + sel, loc.Hover(playwright.LocatorHoverOptions{Timeout: timeout})
+
+	case "dblclick":
+		return "double-clicked " + sel, loc.Dblclick(playwright.LocatorDblclickOptions{Timeout: timeout})
+
+	case "right_click":
+		return "right-clicked " + sel, loc.Click(playwright.LocatorClickOptions{Button: playwright.MouseButtonRight, Timeout: timeout})
+
+	case "drag":
+		target, tsel, err := t.locate(name, "target", args)
+		if err != nil {
+			return "", err
+		}
+		if err := loc.DragTo(target, playwright.LocatorDragToOptions{Timeout: timeout}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("dragged %s to %s", sel, tsel), nil
+	}
+	return "", errors.New("unknown tool: " + name)
+}
+
+// optionArgs — выбираемые пункты: option (строка или список), value, label.
+func optionArgs(args map[string]any) []string {
+	var out []string
+	for _, k := range []string{"option", "options", "value", "label"} {
+		switch v := args[k].(type) {
+		case string:
+			if v != "" {
+				out = append(out, v)
+			}
+		case []any:
+			for _, x := range v {
+				if s := fmt.Sprint(x); s != "" {
+					out = append(out, s)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,235 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"AIAgent/internal/mail"
+	"AIAgent/internal/spam"
+)
+
+// mailTool исполняет высокоуровневые почтовые инструменты mail_* адаптером
+// клиента, открытого на странице.
+func (t *Tools) mailTool(name string, args map[string]any) (string, error) {
+	ad := mail.For(t.Page.URL())
+	switch name {
+	case "mail_status":
+		st, err := ad.Detect(t.Page)
+		if err != nil {
+			return "", err
+		}
+		return jsonResult(st)
+
+	case "mail_list":
+		limit := 20
+		if v, ok := args["limit"].(float64); ok && v > 0 {
+			limit = int(v)
+		}
+		if f, _ := args["folder"].(string); f != "" {
+			if err := ad.OpenFolder(t.Page, f); err != nil {
+				return "", err
+			}
+			waitMailList(t)
+		}
+		msgs, err := ad.List(t.Page, limit)
+		if err != nil {
+			return "", err
+		}
+		return jsonResult(msgs)
+
+	case "mail_open":
+		ref, _ := args["id"].(string)
+		switch v := args["index"].(type) {
+		case float64:
+			ref = strconv.Itoa(int(v))
+		case string:
+			if ref == "" {
+				ref = v
+			}
+		}
+		if ref == "" {
+			ref = "1"
+		}
+		if err := ad.Open(t.Page, ref); err != nil {
+			return "", err
+		}
+		return "opened message " + ref, nil
+
+	case "mail_read":
+		c, err := ad.Read(t.Page)
+		if err != nil {
+			return "", err
+		}
+		if t.ExtractChars > 0 && len(c.Body) > t.ExtractChars {
+			c.Body = c.Body[:t.ExtractChars] + "…"
+		}
+		return jsonResult(c)
+
+	case "mail_open_folder":
+		f, _ := args["folder"].(string)
+		if f == "" {
+			return "", errors.New("mail_open_folder: empty folder")
+		}
+		return "opened folder " + f, ad.OpenFolder(t.Page, f)
+
+	case "mail_delete":
+		return "deleted", ad.Delete(t.Page)
+
+	case "mail_mark_spam":
+		return "marked as spam", ad.MarkSpam(t.Page)
+
+	case "mail_move":
+		f, _ := args["folder"].(string)
+		if f == "" {
+			return "", errors.New("mail_move: empty folder")
+		}
+		return "moved to " + f, ad.Move(t.Page, f)
+
+	case "mail_classify":
+		return t.mailClassify(ad, args)
+
+	case "mail_label":
+		return t.mailLabel(ad, args)
+	}
+	return "", errors.New("unknown tool: " + name)
+}
+
+// Classified — вердикт классификатора для письма из списка или открытого письма.
+type Classified struct {
+	Index   int    `json:"index,omitempty"`
+	ID      string `json:"id,omitempty"`
+	From    string `json:"from"`
+	Subject string `json:"subject"`
+	spam.Verdict
+	// ProposeDelete — спам с уверенностью не ниже порога: кандидат на удаление.
+	ProposeDelete bool `json:"propose_delete,omitempty"`
+}
+
+// mailClassify оценивает открытое письмо или письма текущего списка.
+func (t *Tools) mailClassify(ad mail.Adapter, args map[string]any) (string, error) {
+	if t.Spam == nil {
+		return "", errors.New("mail_classify: classifier is disabled")
+	}
+	var out []Classified
+	st, _ := ad.Detect(t.Page)
+	if st.Page == mail.PageMessage {
+		c, err := ad.Read(t.Page)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, t.classify(c.ID, 0, spam.Example{From: c.From, FromAddr: c.FromAddr, Subject: c.Subject, Body: c.Body}))
+	} else {
+		limit := 20
+		if v, ok := args["limit"].(float64); ok && v > 0 {
+			limit = int(v)
+		}
+		msgs, err := ad.List(t.Page, limit)
+		if err != nil {
+			return "", err
+		}
+		for _, m := range msgs {
+			out = append(out, t.classify(m.ID, m.Index, spam.Example{From: m.From, FromAddr: m.FromAddr, Subject: m.Subject, Body: m.Snippet}))
+		}
+	}
+	return jsonResult(out)
+}
+
+func (t *Tools) classify(id string, index int, ex spam.Example) Classified {
+	v := t.Spam.Score(ex)
+	c := Classified{Index: index, ID: id, From: ex.From, Subject: ex.Subject, Verdict: v, ProposeDelete: t.Spam.ProposeDelete(v)}
+	for i, prev := range t.classified {
+		if (id != "" && prev.ID == id) || (prev.From == c.From && prev.Subject == c.Subject) {
+			t.classified[i] = c
+			return c
+		}
+	}
+	t.classified = append(t.classified, c)
+	return c
+}
+
+// mailLabel сохраняет метку пользователя для письма (по номеру, ID или открытое) и дообучает модель.
+func (t *Tools) mailLabel(ad mail.Adapter, args map[string]any) (string, error) {
+	if t.Spam == nil {
+		return "", errors.New("mail_label: classifier is disabled")
+	}
+	label, _ := args["label"].(string)
+	if label = strings.ToLower(strings.TrimSpace(label)); label == "" {
+		return "", errors.New("mail_label: empty label")
+	}
+	id, _ := args["id"].(string)
+	index, _ := args["index"].(float64)
+	var ex spam.Example
+	if id == "" && index == 0 {
+		c, err := ad.Read(t.Page)
+		if err != nil {
+			return "", err
+		}
+		ex = spam.Example{From: c.From, FromAddr: c.FromAddr, Subject: c.Subject, Body: c.Body}
+	} else {
+		msgs, err := ad.List(t.Page, 50)
+		if err != nil {
+			return "", err
+		}
+		found := false
+		for _, m := range msgs {
+			if (id != "" && m.ID == id) || (id == "" && m.Index == int(index)) {
+				ex = spam.Example{From: m.From, FromAddr: m.FromAddr, Subject: m.Subject, Body: m.Snippet}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("mail_label: message not found in the list")
+		}
+	}
+	ex.Label = label
+	if err := t.Spam.Learn(ex); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("labelled %q as %s", ex.Subject, label), nil
+}
+
+// explainClassified — объяснение вердиктов для итогового ответа.
+func explainClassified(cs []Classified) string {
+	var b strings.Builder
+	b.WriteString("Оценка локального классификатора:")
+	for _, c := range cs {
+		fmt.Fprintf(&b, "\n- «%s» от %s: %s, уверенность %.0f%%", c.Subject, c.From, c.Label, c.Confidence*100)
+		if len(c.Reasons) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(c.Reasons, ", "))
+		}
+		if c.ProposeDelete {
+			b.WriteString(" — предлагается удалить")
+		}
+	}
+	return b.String()
+}
+
+// waitMailList даёт одностраничному клиенту отрисовать список после смены папки.
+func waitMailList(t *Tools) {
+	_ = t.Page.WaitForLoadState()
+	t.Page.WaitForTimeout(500)
+}
+
+func jsonResult(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encode result: %w", err)
+	}
+	return string(b), nil
+}
+
+// mailContext — клиент и тип страницы по адресу, для промпта и эвристики.
+func mailContext(u string) map[string]string {
+	client, kind := mail.KindOf(u)
+	if kind == mail.PageUnknown && client == "generic" {
+		return nil
+	}
+	return map[string]string{"client": client, "page": string(kind)}
+}
+
+// isMailTool — инструмент из семейства mail_*.
+func isMailTool(name string) bool { return strings.HasPrefix(name, "mail_") }
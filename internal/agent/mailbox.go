@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"AIAgent/internal/spam"
+)
+
+// mailboxTool исполняет инструменты mailbox_* напрямую через IMAP или
+// Maildir, минуя веб-клиент.
+func (t *Tools) mailboxTool(name string, args map[string]any) (string, error) {
+	if t.Mailbox == nil {
+		return "", errors.New(name + ": mailbox is not configured, use mail_* or UI tools")
+	}
+	b, err := t.Mailbox.Backend()
+	if err != nil {
+		return "", err
+	}
+	folder, _ := args["folder"].(string)
+	id := mailboxID(args)
+	switch name {
+	case "mailbox_folders":
+		fs, err := b.Folders()
+		if err != nil {
+			return "", err
+		}
+		return jsonResult(fs)
+
+	case "mailbox_list":
+		limit := 20
+		if v, ok := args["limit"].(float64); ok && v > 0 {
+			limit = int(v)
+		}
+		msgs, err := b.List(folder, limit)
+		if err != nil {
+			return "", err
+		}
+		return jsonResult(msgs)
+
+	case "mailbox_read":
+		if id == "" {
+			return "", errors.New("mailbox_read: empty id")
+		}
+		c, err := b.Read(folder, id)
+		if err != nil {
+			return "", err
+		}
+		if t.ExtractChars > 0 && len(c.Body) > t.ExtractChars {
+			c.Body = c.Body[:t.ExtractChars] + "…"
+		}
+		return jsonResult(c)
+
+	case "mailbox_move":
+		to, _ := args["to"].(string)
+		if id == "" || to == "" {
+			return "", errors.New("mailbox_move: id and to are required")
+		}
+		return fmt.Sprintf("moved %s to %s", id, to), b.Move(folder, id, to)
+
+	case "mailbox_delete":
+		if id == "" {
+			return "", errors.New("mailbox_delete: empty id")
+		}
+		return "deleted " + id, b.Delete(folder, id)
+
+	case "mailbox_classify":
+		if t.Spam == nil {
+			return "", errors.New("mailbox_classify: classifier is disabled")
+		}
+		limit := 20
+		if v, ok := args["limit"].(float64); ok && v > 0 {
+			limit = int(v)
+		}
+		msgs, err := b.List(folder, limit)
+		if err != nil {
+			return "", err
+		}
+		out := make([]Classified, 0, len(msgs))
+		for _, m := range msgs {
+			out = append(out, t.classify(m.ID, m.Index, spam.Example{From: m.From, FromAddr: m.FromAddr, Subject: m.Subject, Body: m.Snippet}))
+		}
+		return jsonResult(out)
+	}
+	return "", errors.New("unknown tool: " + name)
+}
+
+// mailboxID — ID письма; модель иногда присылает UID числом.
+func mailboxID(args map[string]any) string {
+	switch v := args["id"].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return ""
+}
+
+// isMailboxTool — инструмент из семейства mailbox_*.
+func isMailboxTool(name string) bool { return strings.HasPrefix(name, "mailbox_") }
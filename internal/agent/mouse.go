@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// describeJS — короткое описание элемента для результата действия и траектории:
+// тег, id, роль и видимый текст.
+const describeJS = `const describe = (el) => {
+  if (!el || el === document.body || el === document.documentElement) return '';
+  let s = el.tagName.toLowerCase();
+  if (el.id) s += '#' + el.id;
+  const role = el.getAttribute('role');
+  if (role) s += '[role=' + role + ']';
+  const t = (el.innerText || el.value || el.getAttribute('aria-label') || el.getAttribute('title') || '').trim().replace(/\s+/g, ' ');
+  return t ? s + ' "' + (t.length > 60 ? t.slice(0, 60) + '…' : t) + '"' : s;
+};`
+
+// Viewport — размер видимой области страницы в CSS-пикселях: в них же координаты click_at и скриншота.
+type Viewport struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// viewport — размер видимой области; у контекста без фиксированного размера спрашиваем окно.
+func (t *Tools) viewport(ctx context.Context) (Viewport, error) {
+	if s := t.Page.ViewportSize(); s != nil {
+		return Viewport{s.Width, s.Height}, nil
+	}
+	v, err := do(ctx, func() (any, error) {
+		return t.Page.Evaluate(`() => [window.innerWidth, window.innerHeight]`)
+	})
+	if err != nil {
+		return Viewport{}, err
+	}
+	wh, _ := v.([]any)
+	if len(wh) != 2 {
+		return Viewport{}, errors.New("viewport: unknown size")
+	}
+	return Viewport{toInt(wh[0]), toInt(wh[1])}, nil
+}
+
+func toInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// point — координаты x, y из аргументов, проверенные по видимой области.
+func (t *Tools) point(ctx context.Context, name string, args map[string]any) (float64, float64, error) {
+	x, okx := args["x"].(float64)
+	y, oky := args["y"].(float64)
+	if !okx || !oky {
+		return 0, 0, fmt.Errorf("%s: x and y are required", name)
+	}
+	vp, err := t.viewport(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if x < 0 || y < 0 || x >= float64(vp.Width) || y >= float64(vp.Height) {
+		return 0, 0, fmt.Errorf("%s: point (%.0f, %.0f) is outside the viewport %dx%d", name, x, y, vp.Width, vp.Height)
+	}
+	return x, y, nil
+}
+
+// elementAt — описание элемента в точке видимой области.
+func (t *Tools) elementAt(x, y float64) string {
+	v, _ := t.Page.Evaluate(`({x, y}) => { `+describeJS+` return describe(document.elementFromPoint(x, y)); }`, map[string]any{"x": x, "y": y})
+	s, _ := v.(string)
+	return s
+}
+
+// focused — описание элемента с фокусом ввода.
+func (t *Tools) focused() string {
+	v, _ := t.Page.Evaluate(`() => { ` + describeJS + ` return describe(document.activeElement); }`)
+	s, _ := v.(string)
+	return s
+}
+
+// pointer исполняет click_at, move_mouse и type_keys — действия для холстов и
+// разметки без пригодных селекторов. В результате — элемент, которого коснулось
+// действие, чтобы траектория оставалась читаемой.
+func (t *Tools) pointer(ctx context.Context, name string, args map[string]any) (string, error) {
+	switch name {
+	case "click_at":
+		x, y, err := t.point(ctx, name, args)
+		if err != nil {
+			return "", err
+		}
+		opts := playwright.MouseClickOptions{}
+		if b, _ := args["button"].(string); b == "right" {
+			opts.Button = playwright.MouseButtonRight
+		} else if b == "middle" {
+			opts.Button = playwright.MouseButtonMiddle
+		}
+		if n, ok := args["clicks"].(float64); ok && n > 1 {
+			opts.ClickCount = playwright.Int(int(n))
+		}
+		el := t.elementAt(x, y)
+		if err := t.Page.Mouse().Click(x, y, opts); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("clicked at (%.0f, %.0f) on %s", x, y, orNothing(el)), nil
+
+	case "move_mouse":
+		x, y, err := t.point(ctx, name, args)
+		if err != nil {
+			return "", err
+		}
+		steps := 5
+		if n, ok := args["steps"].(float64); ok && n >= 1 {
+			steps = int(n)
+		}
+		if err := t.Page.Mouse().Move(x, y, playwright.MouseMoveOptions{Steps: playwright.Int(steps)}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("mouse at (%.0f, %.0f) over %s", x, y, orNothing(t.elementAt(x, y))), nil
+
+	case "type_keys":
+		text, _ := args["text"].(string)
+		var keys []string
+		switch v := args["keys"].(type) {
+		case string:
+			keys = append(keys, v)
+		case []any:
+			for _, k := range v {
+				keys = append(keys, fmt.Sprint(k))
+			}
+		}
+		if text == "" && len(keys) == 0 {
+			return "", errors.New("type_keys: text or keys is required")
+		}
+		target := t.focused()
+		if target == "" {
+			target = "page (nothing focused)"
+		}
+		if text != "" {
+			if err := t.Page.Keyboard().Type(text, playwright.KeyboardTypeOptions{Delay: playwright.Float(20)}); err != nil {
+				return "", err
+			}
+		}
+		for _, k := range keys {
+			if err := t.Page.Keyboard().Press(k); err != nil {
+				return "", fmt.Errorf("type_keys: %s: %w", k, err)
+			}
+		}
+		var parts []string
+		if text != "" {
+			parts = append(parts, fmt.Sprintf("typed %d chars", len([]rune(text))))
+		}
+		if len(keys) > 0 {
+			parts = append(parts, "pressed "+strings.Join(keys, ", "))
+		}
+		return fmt.Sprintf("%s into %s", strings.Join(parts, " and "), target), nil
+	}
+	return "", errors.New("unknown tool: " + name)
+}
+
+func orNothing(el string) string {
+	if el == "" {
+		return "page background"
+	}
+	return el
+}
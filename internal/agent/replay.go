@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"AIAgent/internal/redact"
+	"AIAgent/internal/trajectory"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// ReplayOptions — параметры детерминированного воспроизведения траектории.
+type ReplayOptions struct {
+	// BaseURL заменяет схему и хост записанных адресов (например, на фикстурный сайт).
+	BaseURL string
+	// StopOnDivergence прекращает воспроизведение на первом расхождении.
+	StopOnDivergence bool
+}
+
+// Статусы шага воспроизведения.
+const (
+	ReplayOK       = "ok"
+	ReplayFailed   = "failed"
+	ReplayDiverged = "diverged"
+	ReplaySkipped  = "skipped"
+	// ReplayRedacted — в аргументах шага токены редактора вместо настоящих
+	// значений; шаг не выполняется и считается расхождением.
+	ReplayRedacted = "redacted"
+)
+
+// ReplayStep — результат повторения одного записанного действия.
+type ReplayStep struct {
+	Seq    int    `json:"seq"`
+	Step   int    `json:"step"`
+	Tool   string `json:"tool"`
+	Status string `json:"status"`
+	// Expected/Actual — URL после действия в записи и при воспроизведении.
+	Expected string `json:"expected_url,omitempty"`
+	Actual   string `json:"actual_url,omitempty"`
+	// ContentChanged — текст страницы после действия отличается от записанного.
+	// Сам по себе не считается расхождением: почта и ленты меняются между запусками.
+	ContentChanged bool   `json:"content_changed,omitempty"`
+	Error          string `json:"error,omitempty"`
+	Note           string `json:"note,omitempty"`
+}
+
+// ReplayReport — итог воспроизведения.
+type ReplayReport struct {
+	RunID string       `json:"run_id"`
+	Task  string       `json:"task"`
+	Steps []ReplayStep `json:"steps"`
+	// FirstDivergence — Seq первого расходящегося или упавшего действия (0 — не было).
+	FirstDivergence int  `json:"first_divergence,omitempty"`
+	OK              bool `json:"ok"`
+}
+
+// Replay повторяет записанные действия без обращения к LLM и сообщает,
+// где страница разошлась с записью.
+func Replay(ctx context.Context, cfg Config, page playwright.Page, t *trajectory.Trajectory, opts ReplayOptions) (ReplayReport, error) {
+	rep := ReplayReport{RunID: t.Meta.RunID, Task: t.Meta.Task, OK: true}
+	cfg.Dialogs.Mode = "auto"
+	tools, err := cfg.newTools(page)
+	if err != nil {
+		return rep, err
+	}
+	defer tools.Close()
+
+	if u := rebase(t.Meta.StartURL, opts.BaseURL); u != "" && u != "about:blank" {
+		if _, err := tools.Call(ctx, "goto_url", map[string]any{"url": u}); err != nil {
+			return rep, fmt.Errorf("replay: start url: %w", err)
+		}
+		tools.WaitIdle(ctx)
+	}
+
+	for _, st := range t.Steps {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		rs := ReplayStep{Seq: st.Seq, Step: st.Step, Tool: st.Action.Tool, Expected: rebase(st.After.URL, opts.BaseURL)}
+
+		if st.Action.Tool == "answer_or_ask_user" {
+			rs.Status = ReplaySkipped
+			rs.Note = "ответ пользователю не воспроизводится"
+			rep.Steps = append(rep.Steps, rs)
+			continue
+		}
+
+		if want := rebase(st.Before.URL, opts.BaseURL); !sameURL(want, page.URL()) {
+			rs.Note = fmt.Sprintf("до действия ожидался %s, а открыт %s", want, page.URL())
+		}
+
+		if toks := redact.ArgTokens(st.Action.Args); len(toks) > 0 {
+			rs.Status = ReplayRedacted
+			rs.Error = fmt.Sprintf("redacted, cannot replay: arguments contain %s", strings.Join(toks, ", "))
+			rep.Steps = append(rep.Steps, rs)
+			rep.OK = false
+			if rep.FirstDivergence == 0 {
+				rep.FirstDivergence = rs.Seq
+			}
+			if opts.StopOnDivergence {
+				break
+			}
+			continue
+		}
+
+		args := st.Action.Args
+		if st.Action.Tool == "goto_url" {
+			args = copyArgs(args)
+			if u, _ := args["url"].(string); u != "" {
+				args["url"] = rebase(u, opts.BaseURL)
+			}
+		}
+		_, err := tools.Call(ctx, st.Action.Tool, args)
+		tools.WaitIdle(ctx)
+		tools.syncTabs(ctx)
+		page = tools.Page
+		obs, _ := observe(ctx, page, 1, cfg.SnapshotChars)
+		rs.Actual = obs.URL
+		rs.ContentChanged = hashSnap(obs.Snapshot) != st.After.Hash
+
+		switch {
+		case err != nil && st.Error == "":
+			rs.Status = ReplayFailed
+			rs.Error = err.Error()
+		case !sameURL(rs.Expected, rs.Actual):
+			rs.Status = ReplayDiverged
+		default:
+			rs.Status = ReplayOK
+		}
+		rep.Steps = append(rep.Steps, rs)
+
+		if rs.Status == ReplayFailed || rs.Status == ReplayDiverged {
+			rep.OK = false
+			if rep.FirstDivergence == 0 {
+				rep.FirstDivergence = rs.Seq
+			}
+			if opts.StopOnDivergence {
+				break
+			}
+		}
+	}
+	return rep, nil
+}
+
+// rebase переносит u на схему и хост base, сохраняя путь, запрос и фрагмент.
+func rebase(u, base string) string {
+	if base == "" || u == "" {
+		return u
+	}
+	pu, err := url.Parse(u)
+	if err != nil || pu.Host == "" {
+		return u
+	}
+	pb, err := url.Parse(base)
+	if err != nil || pb.Host == "" {
+		return u
+	}
+	pu.Scheme, pu.Host = pb.Scheme, pb.Host
+	if p := strings.TrimSuffix(pb.Path, "/"); p != "" {
+		pu.Path = p + pu.Path
+	}
+	return pu.String()
+}
+
+// sameURL сравнивает записанный адрес want с адресом got без учёта
+// завершающего слэша. Токены редактора в want совпадают с любым значением.
+func sameURL(want, got string) bool {
+	return redact.Match(strings.TrimSuffix(want, "/"), strings.TrimSuffix(got, "/"))
+}
+
+func copyArgs(args map[string]any) map[string]any {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		out[k] = v
+	}
+	return out
+}
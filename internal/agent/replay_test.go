@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"AIAgent/internal/trajectory"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestSameURL(t *testing.T) {
+	tests := []struct {
+		want, got string
+		same      bool
+	}{
+		{"https://a.example/inbox/", "https://a.example/inbox", true},
+		{"https://a.example/inbox", "https://a.example/sent", false},
+		{"https://mail.yandex.ru/touch/message/[PHONE_1]", "https://mail.yandex.ru/touch/message/189231004283912", true},
+		{"https://a.example/?to=[EMAIL_1]&u=[PHONE_1]#m", "https://a.example/?to=ivan@mail.ru&u=79123456789#m", true},
+		{"https://a.example/message/[PHONE_1]", "https://a.example/message/", false},
+		{"https://a.example/message/[PHONE_1]", "https://b.example/message/1", false},
+		// Квадратные скобки, не похожие на токен, сравниваются буквально.
+		{"https://a.example/?f[name]=1", "https://a.example/?f[name]=1", true},
+		{"https://a.example/?f[name]=1", "https://a.example/?f[x]=1", false},
+	}
+	for _, tt := range tests {
+		if got := sameURL(tt.want, tt.got); got != tt.same {
+			t.Errorf("sameURL(%q, %q) = %v, want %v", tt.want, tt.got, got, tt.same)
+		}
+	}
+}
+
+// fakeContext и fakePage — ровно столько браузера, сколько нужно Replay, чтобы
+// открыть инструменты и дойти до шагов, которые не выполняются. Вызов любого
+// другого метода паникует на nil-интерфейсе — это и проверяет, что шаг не исполнялся.
+type fakeContext struct {
+	playwright.BrowserContext
+	pages []playwright.Page
+}
+
+func (c *fakeContext) Pages() []playwright.Page                { return c.pages }
+func (c *fakeContext) OnPage(func(playwright.Page))            {}
+func (c *fakeContext) OnClose(func(playwright.BrowserContext)) {}
+func (c *fakeContext) AddInitScript(playwright.Script) error   { return nil }
+func (c *fakeContext) GrantPermissions([]string, ...playwright.BrowserContextGrantPermissionsOptions) error {
+	return nil
+}
+
+type fakePage struct {
+	playwright.Page
+	url string
+	bc  *fakeContext
+}
+
+func (p *fakePage) URL() string                             { return p.url }
+func (p *fakePage) Context() playwright.BrowserContext      { return p.bc }
+func (p *fakePage) Evaluate(string, ...any) (any, error)    { return nil, nil }
+func (p *fakePage) OnDialog(func(playwright.Dialog))        {}
+func (p *fakePage) OnDownload(func(playwright.Download))    {}
+func (p *fakePage) OnFrameNavigated(func(playwright.Frame)) {}
+func (p *fakePage) IsClosed() bool                          { return false }
+
+func newFakePage(url string) *fakePage {
+	bc := &fakeContext{}
+	p := &fakePage{url: url, bc: bc}
+	bc.pages = []playwright.Page{p}
+	return p
+}
+
+func TestReplayRedactedStep(t *testing.T) {
+	inbox := "https://mail.example/inbox"
+	tr := &trajectory.Trajectory{
+		Meta: trajectory.Meta{RunID: "r1", StartURL: "about:blank"},
+		Steps: []trajectory.Step{
+			{Seq: 1, Step: 1, Action: trajectory.Action{Tool: "answer_or_ask_user"}},
+			{
+				Seq: 2, Step: 2,
+				Before: trajectory.PageState{URL: inbox},
+				After:  trajectory.PageState{URL: inbox},
+				Action: trajectory.Action{Tool: "type", Args: map[string]any{"selector": "#to", "text": "[EMAIL_1], [EMAIL_2]"}},
+			},
+			// После расхождения с -stop этот шаг уже не выполняется.
+			{Seq: 3, Step: 3, Action: trajectory.Action{Tool: "click", Args: map[string]any{"selector": "#send"}}},
+		},
+	}
+	var cfg Config
+	rep, err := Replay(context.Background(), cfg, newFakePage(inbox), tr, ReplayOptions{StopOnDivergence: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.OK || rep.FirstDivergence != 2 || len(rep.Steps) != 2 {
+		t.Fatalf("report = %+v", rep)
+	}
+	if st := rep.Steps[0]; st.Status != ReplaySkipped {
+		t.Errorf("step 1 = %+v", st)
+	}
+	st := rep.Steps[1]
+	if st.Status != ReplayRedacted || st.Error != "redacted, cannot replay: arguments contain [EMAIL_1], [EMAIL_2]" || st.Note != "" {
+		t.Errorf("step 2 = %+v", st)
+	}
+}
@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// SettleConfig — когда страница после действия считается успокоившейся:
+// DOM не меняется Quiet, нет незавершённых fetch/XHR, нет конечных анимаций.
+type SettleConfig struct {
+	Quiet time.Duration `yaml:"quiet"`
+	// Max — верхняя граница ожидания: часы, карусели и long polling не затихают никогда.
+	Max time.Duration `yaml:"max"`
+}
+
+// DefaultSettle — настройки ожидания по умолчанию.
+func DefaultSettle() SettleConfig {
+	return SettleConfig{Quiet: 250 * time.Millisecond, Max: 3 * time.Second}
+}
+
+// trackNetJS считает незавершённые fetch и XHR страницы в window.__aiagentNet.
+// Ставится скриптом инициализации контекста, а в уже открытые документы — при первом ожидании.
+const trackNetJS = `(() => {
+  if (window.__aiagentNet) return;
+  const net = window.__aiagentNet = {pending: 0};
+  const done = () => { net.pending = Math.max(0, net.pending - 1); };
+  if (window.fetch) {
+    const orig = window.fetch;
+    window.fetch = function (...a) {
+      net.pending++;
+      let p;
+      try { p = orig.apply(this, a); } catch (e) { done(); throw e; }
+      return p.finally(done);
+    };
+  }
+  if (window.XMLHttpRequest) {
+    const send = XMLHttpRequest.prototype.send;
+    XMLHttpRequest.prototype.send = function (...a) {
+      net.pending++;
+      this.addEventListener('loadend', done, {once: true});
+      return send.apply(this, a);
+    };
+  }
+})();`
+
+// settleJS ждёт тишины DOM (кроме правок style), нуля запросов и конца конечных
+// анимаций, проверяя их раз в пару кадров; бесконечные анимации (спиннеры) не ждёт.
+const settleJS = `async ({quiet, max}) => {
+` + trackNetJS + `
+  const net = window.__aiagentNet;
+  const start = performance.now();
+  let last = start;
+  const mo = new MutationObserver((recs) => {
+    if (recs.some((r) => r.type !== 'attributes' || r.attributeName !== 'style')) last = performance.now();
+  });
+  mo.observe(document, {subtree: true, childList: true, attributes: true, characterData: true});
+  // В фоновой вкладке кадры не рисуются — страхуемся таймером.
+  const frame = () => new Promise((r) => { requestAnimationFrame(() => r()); setTimeout(r, 100); });
+  const animating = () => (document.getAnimations ? document.getAnimations() : []).some((a) =>
+    a.playState === 'running' && a.effect && Number.isFinite(a.effect.getComputedTiming().endTime));
+  try {
+    for (;;) {
+      await frame();
+      await frame();
+      const now = performance.now();
+      const s = {pending: net.pending, mutating: now - last < quiet, animating: animating(), loading: document.readyState === 'loading'};
+      if (!s.pending && !s.mutating && !s.animating && !s.loading) return {settled: true, waited: now - start};
+      if (now - start >= max) return {settled: false, waited: now - start, ...s};
+    }
+  } finally {
+    mo.disconnect();
+  }
+}`
+
+// trackNetwork ставит счётчик запросов во все текущие и будущие документы контекста.
+func (t *Tools) trackNetwork() {
+	if t.Page == nil {
+		return
+	}
+	bc := t.Page.Context()
+	// Контекст переживает несколько запусков, а скрипты инициализации не снимаются.
+	if !watchContext(bc).trackNet() {
+		return
+	}
+	script := trackNetJS
+	_ = bc.AddInitScript(playwright.Script{Content: &script})
+	for _, p := range bc.Pages() {
+		_, _ = p.Evaluate(trackNetJS)
+	}
+}
+
+// Settled — итог ожидания: успокоилась ли страница и что мешало, если нет.
+type Settled struct {
+	OK     bool
+	Waited time.Duration
+	Reason string
+}
+
+func (s Settled) String() string {
+	if s.OK {
+		return fmt.Sprintf("page settled in %s", s.Waited.Round(10*time.Millisecond))
+	}
+	return fmt.Sprintf("page still busy after %s: %s", s.Waited.Round(10*time.Millisecond), s.Reason)
+}
+
+// settle ждёт, пока страница успокоится, но не дольше cfg.Max. Переход во
+// время ожидания уничтожает контекст скрипта — тогда ждём уже новый документ.
+func settle(ctx context.Context, page playwright.Page, cfg SettleConfig) Settled {
+	if cfg.Max <= 0 {
+		cfg = DefaultSettle()
+	}
+	start := time.Now()
+	// Скрипт замирает под открытым диалогом; страхуемся запасом сверх Max.
+	ctx, cancel := context.WithTimeout(ctx, cfg.Max+time.Second)
+	defer cancel()
+	res := Settled{Reason: "timeout"}
+	for attempt := 0; attempt < 3 && ctx.Err() == nil; attempt++ {
+		left := cfg.Max - time.Since(start)
+		if left <= 0 {
+			break
+		}
+		_, _ = do(ctx, func() (struct{}, error) {
+			return struct{}{}, page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{State: playwright.LoadStateDomcontentloaded, Timeout: pwTimeout(ctx)})
+		})
+		v, err := do(ctx, func() (any, error) {
+			return page.Evaluate(settleJS, map[string]any{"quiet": cfg.Quiet.Milliseconds(), "max": left.Milliseconds()})
+		})
+		if err != nil {
+			res.Reason = err.Error()
+			if page.IsClosed() {
+				break
+			}
+			continue
+		}
+		m, _ := v.(map[string]any)
+		if ok, _ := m["settled"].(bool); ok {
+			return Settled{OK: true, Waited: time.Since(start)}
+		}
+		var busy []string
+		if n, _ := m["pending"].(int); n > 0 {
+			busy = append(busy, fmt.Sprintf("%d pending requests", n))
+		} else if f, _ := m["pending"].(float64); f > 0 {
+			busy = append(busy, fmt.Sprintf("%d pending requests", int(f)))
+		}
+		for _, k := range []string{"mutating", "animating", "loading"} {
+			if b, _ := m[k].(bool); b {
+				busy = append(busy, k)
+			}
+		}
+		res.Reason = strings.Join(busy, ", ")
+		break
+	}
+	res.Waited = time.Since(start)
+	return res
+}
+
+// WaitIdle даёт странице успокоиться после действия. Под открытым диалогом
+// страница стоит, ждать нечего.
+func (t *Tools) WaitIdle(ctx context.Context) Settled {
+	if t.Page == nil || t.Page.IsClosed() || t.dlg.Pending() != nil {
+		return Settled{OK: true}
+	}
+	return settle(ctx, t.Page, t.Settle)
+}
+
+// waitFor — инструмент wait_for {selector|text|url_pattern, state?, timeout?}:
+// ждёт элемент, текст или адрес; без цели — успокоения страницы.
+func (t *Tools) waitFor(ctx context.Context, args map[string]any) (string, error) {
+	ctx, cancel := withTimeout(ctx, waitTimeout(args))
+	defer cancel()
+	state, _ := args["state"].(string)
+	state = strings.ToLower(strings.TrimSpace(state))
+	if state == "" {
+		state = "visible"
+	}
+	var st *playwright.WaitForSelectorState
+	switch state {
+	case "visible":
+		st = playwright.WaitForSelectorStateVisible
+	case "hidden":
+		st = playwright.WaitForSelectorStateHidden
+	case "attached":
+		st = playwright.WaitForSelectorStateAttached
+	case "detached":
+		st = playwright.WaitForSelectorStateDetached
+	default:
+		return "", fmt.Errorf("wait_for: unknown state %q (visible, hidden, attached, detached)", state)
+	}
+	sel, _ := args["selector"].(string)
+	sel = normalizeSelector(sel)
+	text, _ := args["text"].(string)
+	pattern, _ := args["url_pattern"].(string)
+	var (
+		loc  playwright.Locator
+		what string
+	)
+	switch {
+	case sel != "":
+		loc, what = t.Page.Locator(sel).First(), sel
+	case text != "":
+		loc, what = t.Page.GetByText(text).First(), fmt.Sprintf("text %q", text)
+	case pattern != "":
+		if err := t.Page.WaitForURL(urlMatcher(pattern), playwright.PageWaitForURLOptions{
+			Timeout: pwTimeout(ctx), WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+		}); err != nil {
+			return "", fmt.Errorf("wait_for: url %q: %w (now at %s)", pattern, err, t.Page.URL())
+		}
+		return "url is " + t.Page.URL(), nil
+	default:
+		return t.WaitIdle(ctx).String(), nil
+	}
+	if err := loc.WaitFor(playwright.LocatorWaitForOptions{State: st, Timeout: pwTimeout(ctx)}); err != nil {
+		return "", fmt.Errorf("wait_for: %s is not %s: %w", what, state, err)
+	}
+	return fmt.Sprintf("%s is %s", what, state), nil
+}
+
+// waitTimeout — timeout из аргументов: секунды числом или строка вида "5s"; по умолчанию 10 с.
+func waitTimeout(args map[string]any) time.Duration {
+	switch v := args["timeout"].(type) {
+	case float64:
+		if v > 0 {
+			return time.Duration(v * float64(time.Second))
+		}
+	case string:
+		if d, err := time.ParseDuration(strings.TrimSpace(v)); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 10 * time.Second
+}
+
+// urlMatcher: шаблон с * — glob Playwright, /…/ — регулярное выражение,
+// иначе подстрока адреса.
+func urlMatcher(p string) any {
+	if len(p) > 2 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+		if re, err := regexp.Compile(p[1 : len(p)-1]); err == nil {
+			return re
+		}
+	}
+	if strings.Contains(p, "*") {
+		return p
+	}
+	return regexp.MustCompile(regexp.QuoteMeta(p))
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestURLMatcher(t *testing.T) {
+	tests := []struct {
+		pattern string
+		glob    bool // ожидается glob Playwright, а не регулярное выражение
+		match   []string
+		noMatch []string
+	}{
+		{pattern: "**/inbox/*", glob: true},
+		{pattern: `/\/msg\/\d+$/`, match: []string{"https://mail.example/msg/42"}, noMatch: []string{"https://mail.example/msg/42?x", "https://mail.example/msg/new"}},
+		{pattern: "/inbox?", match: []string{"https://mail.example/inbox?page=2"}, noMatch: []string{"https://mail.example/inbox"}},
+		{pattern: "a.b", match: []string{"https://a.b/"}, noMatch: []string{"https://axb/"}},
+		// Неверное регулярное выражение ищется как подстрока.
+		{pattern: "/[/", match: []string{"https://x.example/[/"}, noMatch: []string{"https://x.example/"}},
+		{pattern: "//", match: []string{"https://x.example/"}, noMatch: []string{"x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			m := urlMatcher(tt.pattern)
+			if tt.glob {
+				if s, ok := m.(string); !ok || s != tt.pattern {
+					t.Errorf("urlMatcher = %#v, want glob %q", m, tt.pattern)
+				}
+				return
+			}
+			re, ok := m.(*regexp.Regexp)
+			if !ok {
+				t.Fatalf("urlMatcher = %#v, want *regexp.Regexp", m)
+			}
+			for _, u := range tt.match {
+				if !re.MatchString(u) {
+					t.Errorf("%s does not match %s", re, u)
+				}
+			}
+			for _, u := range tt.noMatch {
+				if re.MatchString(u) {
+					t.Errorf("%s matches %s", re, u)
+				}
+			}
+		})
+	}
+}
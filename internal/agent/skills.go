@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"AIAgent/internal/events"
+	"AIAgent/internal/skill"
+	"AIAgent/internal/workflow"
+)
+
+// maxSkillDepth ограничивает вложенность навыков, вызывающих run_skill.
+const maxSkillDepth = 3
+
+type skillDepthKey struct{}
+
+// runSkill исполняет навык из библиотеки: проверяет предусловие, выполняет
+// шаги без восстановления через LLM (навык и так вызван планировщиком,
+// который увидит ошибку) и проверяет постусловие.
+func (t *Tools) runSkill(ctx context.Context, args map[string]any) (string, error) {
+	name, _ := args["name"].(string)
+	if name == "" {
+		return "", errors.New("run_skill: empty name")
+	}
+	sk, err := t.Skills.Get(name)
+	if err != nil {
+		return "", fmt.Errorf("run_skill: %w", err)
+	}
+	params, _ := args["params"].(map[string]any)
+	vars, err := sk.Vars(params)
+	if err != nil {
+		return "", fmt.Errorf("run_skill: %w", err)
+	}
+	depth, _ := ctx.Value(skillDepthKey{}).(int)
+	if depth >= maxSkillDepth {
+		return "", fmt.Errorf("run_skill %s: nesting deeper than %d", name, maxSkillDepth)
+	}
+	ctx = context.WithValue(ctx, skillDepthKey{}, depth+1)
+
+	w := &workflowRunner{
+		cfg:   Config{ExtractChars: t.ExtractChars, Timeouts: Timeouts{Tool: t.Timeout, PerTool: t.PerTool}},
+		wf:    &workflow.Workflow{Name: sk.Name},
+		tools: t,
+		emit:  func(events.Event) {},
+		vars:  vars,
+	}
+	if sk.Pre != nil {
+		if _, err := w.assert(ctx, sk.Pre); err != nil {
+			return "", fmt.Errorf("run_skill %s: предусловие: %w", name, err)
+		}
+	}
+	if err := w.run(ctx, sk.Steps, ""); err != nil {
+		return "", fmt.Errorf("run_skill %s: %w", name, err)
+	}
+	if sk.Post != nil {
+		if _, err := w.assert(ctx, sk.Post); err != nil {
+			return "", fmt.Errorf("run_skill %s: постусловие: %w", name, err)
+		}
+	}
+	return skillSummary(sk, len(w.res.Steps), w.vars), nil
+}
+
+// skillSummary — результат навыка для памяти планировщика: имя, число шагов
+// и извлечённые значения.
+func skillSummary(sk *skill.Skill, steps int, vars map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "skill %s: ok (%d steps)", sk.Name, steps)
+	for _, st := range sk.Steps {
+		if st.Extract != nil {
+			fmt.Fprintf(&b, "\n%s = %s", st.Extract.As, crop(vars[st.Extract.As], 300))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Tab — вкладка браузерного контекста в наблюдении; Index — номер для switch_tab и close_tab, с 1.
+type Tab struct {
+	Index  int    `json:"index"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Active bool   `json:"active,omitempty"`
+}
+
+// tabs — открытые вкладки контекста страницы page в порядке открытия.
+func tabs(ctx context.Context, page playwright.Page) []Tab {
+	var out []Tab
+	for i, p := range page.Context().Pages() {
+		title, _ := do(ctx, p.Title)
+		out = append(out, Tab{Index: i + 1, Title: title, URL: p.URL(), Active: p == page})
+	}
+	return out
+}
+
+// rememberTabs запоминает уже открытые вкладки, чтобы syncTabs отличал от них новые.
+func (t *Tools) rememberTabs() {
+	t.known = map[playwright.Page]bool{}
+	if t.Page == nil {
+		return
+	}
+	for _, p := range t.Page.Context().Pages() {
+		t.known[p] = true
+	}
+}
+
+// syncTabs переключает Page на вкладку, открытую последним действием (ссылка
+// с target=_blank, всплывающее окно OAuth), а если активная вкладка закрылась
+// сама — на открывшую её или последнюю оставшуюся; если не осталось ни одной,
+// открывает пустую, чтобы агенту и следующей задаче было где работать.
+// Возвращает описание переключения или "".
+func (t *Tools) syncTabs(ctx context.Context) string {
+	if t.Page == nil {
+		return ""
+	}
+	if t.known == nil {
+		t.rememberTabs()
+		return ""
+	}
+	pages := t.Page.Context().Pages()
+	var fresh playwright.Page
+	for _, p := range pages {
+		if !t.known[p] {
+			t.known[p] = true
+			fresh = p
+		}
+	}
+	if fresh != nil && !fresh.IsClosed() {
+		t.activate(ctx, fresh)
+		return fmt.Sprintf("switched to new tab #%d: %s", tabIndex(pages, fresh), fresh.URL())
+	}
+	if !t.Page.IsClosed() {
+		return ""
+	}
+	p := t.fallbackTab(pages)
+	if p == nil {
+		np, err := t.Page.Context().NewPage()
+		if err != nil {
+			return "active tab closed, no tabs left: " + err.Error()
+		}
+		t.known[np] = true
+		t.activate(ctx, np)
+		return "active tab closed, no tabs left, opened a blank tab"
+	}
+	t.activate(ctx, p)
+	return fmt.Sprintf("active tab closed, switched to tab #%d: %s", tabIndex(pages, p), p.URL())
+}
+
+// fallbackTab — куда вернуться после закрытия активной вкладки: к открывшей её, иначе к последней.
+func (t *Tools) fallbackTab(pages []playwright.Page) playwright.Page {
+	if op, err := t.Page.Opener(); err == nil && op != nil && !op.IsClosed() {
+		return op
+	}
+	for i := len(pages) - 1; i >= 0; i-- {
+		if !pages[i].IsClosed() && pages[i] != t.Page {
+			return pages[i]
+		}
+	}
+	return nil
+}
+
+// activate делает p активной вкладкой и ждёт, пока в ней появится документ.
+func (t *Tools) activate(ctx context.Context, p playwright.Page) {
+	t.Page = p
+	if t.onPage != nil {
+		t.onPage(p)
+	}
+	_ = p.WaitForLoadState(playwright.PageWaitForLoadStateOptions{State: playwright.LoadStateDomcontentloaded, Timeout: pwTimeout(ctx)})
+	_ = p.BringToFront()
+}
+
+func tabIndex(pages []playwright.Page, p playwright.Page) int {
+	for i, x := range pages {
+		if x == p {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// tab — вкладка по аргументу index; без него — активная.
+func (t *Tools) tab(name string, args map[string]any) (playwright.Page, []playwright.Page, error) {
+	pages := t.Page.Context().Pages()
+	v, ok := args["index"].(float64)
+	if !ok {
+		return t.Page, pages, nil
+	}
+	if n := int(v); n >= 1 && n <= len(pages) {
+		return pages[n-1], pages, nil
+	}
+	return nil, pages, fmt.Errorf("%s: no tab #%v (open tabs: %d)", name, v, len(pages))
+}
+
+// tabTool исполняет switch_tab, new_tab и close_tab.
+func (t *Tools) tabTool(ctx context.Context, name string, args map[string]any) (string, error) {
+	if t.known == nil {
+		t.rememberTabs()
+	}
+	switch name {
+	case "switch_tab":
+		if _, ok := args["index"].(float64); !ok {
+			return "", errors.New("switch_tab: index is required")
+		}
+		p, pages, err := t.tab(name, args)
+		if err != nil {
+			return "", err
+		}
+		t.activate(ctx, p)
+		return fmt.Sprintf("switched to tab #%d: %s", tabIndex(pages, p), p.URL()), nil
+
+	case "new_tab":
+		p, err := t.Page.Context().NewPage()
+		if err != nil {
+			return "", err
+		}
+		t.known[p] = true
+		t.activate(ctx, p)
+		if u, _ := args["url"].(string); u != "" {
+			if _, err := p.Goto(u, playwright.PageGotoOptions{Timeout: pwTimeout(ctx)}); err != nil {
+				return "opened new tab", err
+			}
+		}
+		return fmt.Sprintf("opened new tab #%d: %s", len(t.Page.Context().Pages()), p.URL()), nil
+
+	case "close_tab":
+		p, pages, err := t.tab(name, args)
+		if err != nil {
+			return "", err
+		}
+		if len(pages) == 1 {
+			return "", errors.New("close_tab: cannot close the last tab")
+		}
+		n := tabIndex(pages, p)
+		if p == t.Page {
+			next := t.fallbackTab(pages)
+			if err := p.Close(); err != nil {
+				return "", err
+			}
+			t.activate(ctx, next)
+		} else if err := p.Close(); err != nil {
+			return "", err
+		}
+		delete(t.known, p)
+		return fmt.Sprintf("closed tab #%d, active: %s", n, t.Page.URL()), nil
+	}
+	return "", errors.New("unknown tool: " + name)
+}
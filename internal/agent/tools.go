@@ -6,14 +6,54 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"AIAgent/internal/mailbox"
+	"AIAgent/internal/skill"
+	"AIAgent/internal/spam"
+
 	"github.com/playwright-community/playwright-go"
 )
 
 // Tools — обёртка над страницей браузера для вызова действий агентом.
 type Tools struct {
 	Page playwright.Page
+	// ExtractChars ограничивает объём текста, возвращаемого extract (0 — без ограничения).
+	ExtractChars int
+	// Timeout — дедлайн одного вызова инструмента; PerTool переопределяет его по имени.
+	Timeout time.Duration
+	PerTool map[string]time.Duration
+	// Skills — библиотека навыков для run_skill; nil — навыки недоступны.
+	Skills *skill.Library
+	// Spam — классификатор для mail_classify и mail_label; nil — недоступен.
+	Spam *spam.Classifier
+	// Mailbox — ящик по IMAP или Maildir для mailbox_*; nil — не настроен.
+	Mailbox *mailbox.Client
+
+	// Settle — когда после действия страница считается успокоившейся; нулевое значение — DefaultSettle.
+	Settle SettleConfig
+
+	// UploadDir — единственный каталог, из которого upload_file берёт файлы ("" — загрузка запрещена).
+	UploadDir string
+
+	// dl — скачивания запуска; nil — не сохраняются.
+	dl *downloads
+	// dlg — диалоги страницы; nil — не перехватываются.
+	dlg *dialogs
+	// nav — история переходов вкладок для наблюдения и отката при зацикливании.
+	nav *navigation
+	// onPage узнаёт о смене активной вкладки; nil — никто не следит.
+	onPage func(playwright.Page)
+	// known — вкладки, которые агент уже видел; новые открыты последним действием.
+	known map[playwright.Page]bool
+	// classified — вердикты mail_classify за запуск, для объяснения в итоговом ответе.
+	classified []Classified
+
+	mu sync.Mutex
+	// inflight закрывается, когда доработает действие, от которого callPage
+	// вернулся раньше (диалог, отмена); nil — такого нет.
+	inflight chan struct{}
 }
 
 // normalizeSelector приводит селектор к валидному CSS:
@@ -54,14 +94,48 @@ func normalizeSelector(s string) string {
 
 // Call исполняет действие по имени и аргументам.
 // Используется агентом, который решает какой шаг сделать.
+// Вызов ограничен таймаутом инструмента и прерывается отменой ctx.
 func (t *Tools) Call(ctx context.Context, name string, args map[string]any) (string, error) {
+	d := t.Timeout
+	if name == "run_skill" {
+		// Каждый шаг навыка ограничен таймаутом инструмента сам по себе.
+		d = 0
+	}
+	if v, ok := t.PerTool[name]; ok {
+		d = v
+	}
+	ctx, cancel := withTimeout(ctx, d)
+	defer cancel()
+	res, err := t.callPage(ctx, name, args)
+	if t.idle() {
+		if note := t.syncTabs(ctx); note != "" {
+			res = strings.TrimSpace(res + "; " + note)
+		}
+	}
+	if note := t.downloadNote(ctx, 0); note != "" {
+		res = strings.TrimSpace(res + "; " + note)
+	}
+	if note := t.dlg.takeNotes(); note != "" {
+		res = strings.TrimSpace(res + "; " + note)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		if isMailboxTool(name) {
+			// Зависший запрос может ещё держать соединение — следующий вызов откроет новое.
+			t.Mailbox.Reset()
+		}
+		return "", timeoutError(ctx, name, d)
+	}
+	return res, err
+}
+
+func (t *Tools) call(ctx context.Context, name string, args map[string]any) (string, error) {
 	switch name {
 	case "goto_url":
 		url, _ := args["url"].(string)
 		if url == "" {
 			return "", errors.New("goto_url: empty url")
 		}
-		_, err := t.Page.Goto(url)
+		_, err := t.Page.Goto(url, playwright.PageGotoOptions{Timeout: pwTimeout(ctx)})
 		return "navigated", err
 
 	case "click":
@@ -79,7 +153,7 @@ func (t *Tools) Call(ctx context.Context, name string, args map[string]any) (str
 		if el == nil {
 			return "", fmt.Errorf("click: element not found: %s", selector)
 		}
-		if err := el.Click(); err != nil {
+		if err := el.Click(playwright.ElementHandleClickOptions{Timeout: pwTimeout(ctx)}); err != nil {
 			return "", err
 		}
 		return "clicked selector=" + selector, nil
@@ -110,6 +184,9 @@ func (t *Tools) Call(ctx context.Context, name string, args map[string]any) (str
 		best := item{y: 1e12}
 
 		for _, e := range elems {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
 			vis, _ := e.IsVisible()
 			if !vis {
 				continue
@@ -139,7 +216,7 @@ func (t *Tools) Call(ctx context.Context, name string, args map[string]any) (str
 			return "", fmt.Errorf("open_first_main_item: no element in main region")
 		}
 
-		if err := best.el.Click(); err != nil {
+		if err := best.el.Click(playwright.ElementHandleClickOptions{Timeout: pwTimeout(ctx)}); err != nil {
 			return "", err
 		}
 		return "opened_first_main_item", nil
@@ -161,11 +238,11 @@ func (t *Tools) Call(ctx context.Context, name string, args map[string]any) (str
 		if el == nil {
 			return "", fmt.Errorf("type: element not found: %s", selector)
 		}
-		if err := el.Fill(text); err != nil {
+		if err := el.Fill(text, playwright.ElementHandleFillOptions{Timeout: pwTimeout(ctx)}); err != nil {
 			return "", err
 		}
 		if pressEnter {
-			if err := el.Press("Enter"); err != nil {
+			if err := el.Press("Enter", playwright.ElementHandlePressOptions{Timeout: pwTimeout(ctx)}); err != nil {
 				return "", err
 			}
 		}
@@ -176,11 +253,13 @@ func (t *Tools) Call(ctx context.Context, name string, args map[string]any) (str
 		if key == "" {
 			key = "Escape"
 		}
+		// У Keyboard.Press нет таймаута: он ничего не ждёт, кроме диалога, а диалог ловит callPage.
 		return "pressed", t.Page.Keyboard().Press(key)
 
 	case "scroll":
 		if sel, ok := args["selector"].(string); ok && sel != "" {
-			_, err := t.Page.Evaluate(`(sel)=>{document.querySelector(sel)?.scrollIntoView({behavior:'instant',block:'center'})}`, sel)
+			_, err := t.Page.Locator("html").Evaluate(`(_, sel)=>{document.querySelector(sel)?.scrollIntoView({behavior:'instant',block:'center'})}`, sel,
+				playwright.LocatorEvaluateOptions{Timeout: pwTimeout(ctx)})
 			if err != nil {
 				return "", err
 			}
@@ -190,27 +269,67 @@ func (t *Tools) Call(ctx context.Context, name string, args map[string]any) (str
 		if v, ok := args["y"].(float64); ok && v != 0 {
 			y = v
 		}
-		_, err := t.Page.Evaluate(`(dy)=>{window.scrollBy(0,dy)}`, y)
+		_, err := t.Page.Locator("html").Evaluate(`(_, dy)=>{window.scrollBy(0,dy)}`, y, playwright.LocatorEvaluateOptions{Timeout: pwTimeout(ctx)})
 		return "scrolled", err
 
 	case "extract":
-		title, _ := t.Page.Title()
-		body, _ := t.Page.TextContent("body")
-		if len(body) > 6000 {
-			body = body[:6000] + "…"
+		v, _ := t.Page.Locator("html").Evaluate(`() => document.title`, nil, playwright.LocatorEvaluateOptions{Timeout: pwTimeout(ctx)})
+		title, _ := v.(string)
+		body, _ := t.Page.TextContent("body", playwright.PageTextContentOptions{Timeout: pwTimeout(ctx)})
+		if t.ExtractChars > 0 && len(body) > t.ExtractChars {
+			body = body[:t.ExtractChars] + "…"
 		}
 		return fmt.Sprintf("TITLE: %s\nSNAPSHOT:\n%s", title, body), nil
 
+	case "run_skill":
+		return t.runSkill(ctx, args)
+
+	case "select_option", "check", "uncheck", "hover", "dblclick", "right_click", "drag":
+		return t.interact(ctx, name, args)
+
+	case "handle_dialog":
+		return t.handleDialog(args)
+
+	case "click_at", "move_mouse", "type_keys":
+		return t.pointer(ctx, name, args)
+
+	case "go_back", "go_forward", "reload":
+		return t.navigate(ctx, name, args)
+
+	case "wait_for":
+		return t.waitFor(ctx, args)
+
+	case "upload_file":
+		return t.uploadFile(ctx, args)
+
+	case "switch_tab", "new_tab", "close_tab":
+		return t.tabTool(ctx, name, args)
+
 	case "answer_or_ask_user":
 		return "done", nil
 
 	default:
+		if isMailTool(name) {
+			return t.mailTool(name, args)
+		}
+		if isMailboxTool(name) {
+			return t.mailboxTool(name, args)
+		}
 		return "", errors.New("unknown tool: " + name)
 	}
 }
 
-// WaitIdle — короткое ожидание тишины сети, чтобы дать SPA догрузиться после действия.
-func WaitIdle(page playwright.Page) {
-	_ = page.WaitForLoadState()
-	time.Sleep(200 * time.Millisecond)
+// Close освобождает ресурсы инструментов: перестаёт сохранять скачивания,
+// закрывает ожидающий диалог и соединение с почтовым ящиком.
+func (t *Tools) Close() error {
+	if t == nil {
+		return nil
+	}
+	t.dl.close()
+	t.dlg.close()
+	// Действие, ждавшее диалог, после его закрытия доработает быстро.
+	ctx, cancel := context.WithTimeout(context.Background(), callGrace)
+	_ = t.waitInflight(ctx)
+	cancel()
+	return t.Mailbox.Close()
 }
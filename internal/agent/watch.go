@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// watch — подписки на события одного контекста браузера. Снять обработчик
+// Playwright надёжно не даёт (RemoveListener сравнивает указатели на код
+// функции, общие у всех замыканий), поэтому обработчики ставятся на контекст
+// один раз, а события получает последний открытый на нём запуск: вложенный
+// запуск восстановления — раньше внешнего.
+type watch struct {
+	mu        sync.Mutex
+	dialogs   []*dialogs
+	downloads []*downloads
+	// netTracked — контексту уже добавлен trackNetJS.
+	netTracked bool
+}
+
+// watches — контекст браузера → *watch; запись удаляется при закрытии контекста.
+var watches sync.Map
+
+// watchContext — подписки контекста bc, при первом обращении ставит обработчики на все его вкладки, в том числе будущие.
+func watchContext(bc playwright.BrowserContext) *watch {
+	if w, ok := watches.Load(bc); ok {
+		return w.(*watch)
+	}
+	w := &watch{}
+	if v, loaded := watches.LoadOrStore(bc, w); loaded {
+		return v.(*watch)
+	}
+	bc.OnClose(func(playwright.BrowserContext) { watches.Delete(bc) })
+	attach := func(p playwright.Page) {
+		p.OnDialog(w.dialog)
+		p.OnDownload(w.download)
+	}
+	for _, p := range bc.Pages() {
+		attach(p)
+	}
+	bc.OnPage(attach)
+	return w
+}
+
+func (w *watch) addDialogs(d *dialogs) {
+	w.mu.Lock()
+	w.dialogs = append(w.dialogs, d)
+	w.mu.Unlock()
+}
+
+func (w *watch) removeDialogs(d *dialogs) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	w.dialogs = slices.DeleteFunc(w.dialogs, func(x *dialogs) bool { return x == d })
+	w.mu.Unlock()
+}
+
+// dialog передаёт диалог текущему запуску; без запуска диалог закрывается,
+// чтобы страница не осталась заблокированной до следующей задачи.
+func (w *watch) dialog(dg playwright.Dialog) {
+	w.mu.Lock()
+	var d *dialogs
+	if n := len(w.dialogs); n > 0 {
+		d = w.dialogs[n-1]
+	}
+	w.mu.Unlock()
+	if d == nil {
+		go respond(dg, "dismiss", "")
+		return
+	}
+	d.open(dg)
+}
+
+func (w *watch) addDownloads(dl *downloads) {
+	w.mu.Lock()
+	w.downloads = append(w.downloads, dl)
+	w.mu.Unlock()
+}
+
+func (w *watch) removeDownloads(dl *downloads) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	w.downloads = slices.DeleteFunc(w.downloads, func(x *downloads) bool { return x == dl })
+	w.mu.Unlock()
+}
+
+// download передаёт скачивание текущему запуску, который сохраняет скачивания;
+// без такого запуска файл остаётся во временном каталоге Playwright.
+func (w *watch) download(d playwright.Download) {
+	w.mu.Lock()
+	var dl *downloads
+	if n := len(w.downloads); n > 0 {
+		dl = w.downloads[n-1]
+	}
+	w.mu.Unlock()
+	if dl != nil {
+		dl.start(d)
+	}
+}
+
+// trackNet отмечает, что контексту добавлен счётчик запросов; false — он уже был.
+func (w *watch) trackNet() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.netTracked {
+		return false
+	}
+	w.netTracked = true
+	return true
+}
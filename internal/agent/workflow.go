@@ -0,0 +1,465 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"AIAgent/internal/events"
+	"AIAgent/internal/workflow"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Статусы шага сценария.
+const (
+	WorkflowOK        = "ok"
+	WorkflowFailed    = "failed"
+	WorkflowRecovered = "recovered"
+)
+
+// WorkflowStep — итог одного шага сценария.
+type WorkflowStep struct {
+	Path   string `json:"path"`
+	Step   string `json:"step"`
+	Status string `json:"status"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Recovery — запуск планировщика, восстановивший (или не сумевший восстановить) шаг.
+	Recovery *Result `json:"recovery,omitempty"`
+}
+
+// WorkflowResult — итог сценария. Status — done, error или canceled.
+type WorkflowResult struct {
+	Name       string            `json:"name"`
+	Status     string            `json:"status"`
+	Steps      []WorkflowStep    `json:"steps"`
+	Vars       map[string]string `json:"vars,omitempty"`
+	Recovered  int               `json:"recovered,omitempty"`
+	DurationMS int64             `json:"duration_ms"`
+}
+
+// errAssert — проверка сценария не выполнилась.
+var errAssert = errors.New("assertion failed")
+
+// RunWorkflow исполняет YAML-сценарий инструментами агента. Переменные vars
+// дополняют и переопределяют wf.Vars. Если шаг падает и восстановление разрешено
+// (и настроена LLM), планировщику ставится задача довести страницу до состояния
+// после этого шага, после чего сценарий продолжается со следующего шага.
+func RunWorkflow(ctx context.Context, cfg Config, page playwright.Page, wf *workflow.Workflow, vars map[string]string) (WorkflowResult, error) {
+	// Шаги сценария не умеют отвечать на диалоги — их закрывают правила.
+	cfg.Dialogs.Mode = "auto"
+	w := &workflowRunner{
+		cfg:  cfg,
+		wf:   wf,
+		emit: cfg.emitter(),
+		vars: map[string]string{},
+	}
+	for k, v := range wf.Vars {
+		w.vars[k] = v
+	}
+	for k, v := range vars {
+		w.vars[k] = v
+	}
+	w.res = WorkflowResult{Name: wf.Name, Status: StatusError, Vars: w.vars}
+	var err error
+	if w.tools, err = cfg.newTools(page); err != nil {
+		return w.res, err
+	}
+	defer w.tools.Close()
+	started := time.Now()
+	defer func() { w.res.DurationMS = time.Since(started).Milliseconds() }()
+
+	ctx, cancel := withDeadline(ctx, cfg.Timeouts.Run, "run")
+	defer cancel()
+
+	if wf.StartURL != "" {
+		u, err := workflow.Expand(wf.StartURL, w.vars)
+		if err == nil {
+			_, err = w.tools.Call(ctx, "goto_url", map[string]any{"url": u})
+		}
+		if err != nil {
+			return w.res, fmt.Errorf("workflow: start url: %w", err)
+		}
+		w.tools.WaitIdle(ctx)
+	}
+
+	err = w.run(ctx, wf.Steps, "")
+	switch {
+	case err == nil:
+		w.res.Status = StatusDone
+	case ctx.Err() != nil:
+		w.res.Status = StatusCanceled
+	}
+	return w.res, err
+}
+
+type workflowRunner struct {
+	cfg   Config
+	wf    *workflow.Workflow
+	tools *Tools
+	emit  func(events.Event)
+	vars  map[string]string
+	res   WorkflowResult
+}
+
+func (w *workflowRunner) run(ctx context.Context, steps []workflow.Step, prefix string) error {
+	for i, st := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		path := prefix + strconv.Itoa(i+1)
+		if st.Each != nil {
+			if err := w.each(ctx, st, path); err != nil {
+				return err
+			}
+			continue
+		}
+		rs := WorkflowStep{Path: path, Step: st.String()}
+		out, err := w.exec(ctx, st)
+		if err == nil {
+			rs.Status, rs.Output = WorkflowOK, out
+			w.done(rs)
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rs.Error = err.Error()
+		if !w.canRecover(st) {
+			rs.Status = WorkflowFailed
+			w.done(rs)
+			return fmt.Errorf("шаг %s (%s): %w", path, rs.Step, err)
+		}
+		if rerr := w.recover(ctx, st, &rs); rerr != nil {
+			rs.Status = WorkflowFailed
+			w.done(rs)
+			return fmt.Errorf("шаг %s (%s): %v; восстановление: %w", path, rs.Step, err, rerr)
+		}
+		rs.Status = WorkflowRecovered
+		w.res.Recovered++
+		w.done(rs)
+	}
+	return nil
+}
+
+func (w *workflowRunner) done(rs WorkflowStep) {
+	w.res.Steps = append(w.res.Steps, rs)
+	w.emit(events.Event{Kind: events.KindWorkflow, Data: events.WorkflowStep{
+		Path: rs.Path, Step: rs.Step, Status: rs.Status, Output: crop(rs.Output, 200), Error: rs.Error,
+	}})
+}
+
+func (w *workflowRunner) canRecover(st workflow.Step) bool {
+	if st.Fallback != nil {
+		return *st.Fallback && w.cfg.useLLM()
+	}
+	return w.wf.Fallback.Enabled && w.cfg.useLLM()
+}
+
+// recover ставит планировщику задачу выполнить упавший шаг. Проверки и
+// извлечение после восстановления повторяются: их результат нужен сценарию.
+func (w *workflowRunner) recover(ctx context.Context, st workflow.Step, rs *WorkflowStep) error {
+	w.emit(events.Event{Kind: events.KindWorkflow, Data: events.WorkflowStep{
+		Path: rs.Path, Step: rs.Step, Status: "recovering", Error: rs.Error,
+	}})
+	cfg := w.cfg
+	cfg.RunID = ""
+	cfg.MaxSteps = w.wf.Fallback.MaxSteps
+	cfg.Checkpoints.Enabled = false
+	goal := w.wf.Task
+	if goal == "" {
+		goal = w.wf.Name
+	}
+	task := fmt.Sprintf("Выполняется сценарий %q (цель: %s). Шаг «%s» не удался: %s. "+
+		"Выполни этот шаг сам или доведи страницу до состояния, которое должно быть после него, "+
+		"и сразу заверши задачу через answer_or_ask_user. Следующие шаги сценария не выполняй.",
+		w.wf.Name, goal, rs.Step, rs.Error)
+	res, err := Run(ctx, cfg, w.tools.Page, task)
+	rs.Recovery = &res
+	// Планировщик мог открыть, переключить или закрыть вкладки — продолжаем с той, где он закончил.
+	if res.Page != nil && !res.Page.IsClosed() && res.Page != w.tools.Page {
+		w.tools.activate(ctx, res.Page)
+	}
+	w.tools.syncTabs(ctx)
+	if err != nil {
+		return err
+	}
+	if res.Status != StatusDone {
+		return fmt.Errorf("планировщик завершился со статусом %s", res.Status)
+	}
+	if st.Assert != nil || st.Extract != nil {
+		out, err := w.exec(ctx, st)
+		if err != nil {
+			return err
+		}
+		rs.Output = out
+	}
+	return nil
+}
+
+// exec выполняет одно действие сценария (кроме each).
+func (w *workflowRunner) exec(ctx context.Context, st workflow.Step) (string, error) {
+	var (
+		out string
+		err error
+	)
+	switch st.Kind() {
+	case "goto":
+		var u string
+		if u, err = workflow.Expand(st.Goto, w.vars); err != nil {
+			return "", err
+		}
+		out, err = w.tools.Call(ctx, "goto_url", map[string]any{"url": u})
+	case "click":
+		var sel string
+		if sel, err = w.locator(st.Click.Target); err != nil {
+			return "", err
+		}
+		out, err = w.tools.Call(ctx, "click", map[string]any{"selector": sel})
+	case "type":
+		var sel, text string
+		if sel, err = w.locator(st.Type.Target); err != nil {
+			return "", err
+		}
+		if text, err = workflow.Expand(st.Type.Value, w.vars); err != nil {
+			return "", err
+		}
+		out, err = w.tools.Call(ctx, "type", map[string]any{"selector": sel, "text": text, "pressEnter": st.Type.Enter})
+	case "press":
+		out, err = w.tools.Call(ctx, "press", map[string]any{"key": st.Press})
+	case "scroll":
+		if st.Scroll.Target.IsZero() {
+			out, err = w.tools.Call(ctx, "scroll", map[string]any{"y": st.Scroll.Y})
+			break
+		}
+		// Инструмент scroll понимает только CSS, а цель может быть задана ролью или текстом.
+		var sel string
+		if sel, err = w.locator(st.Scroll.Target); err != nil {
+			return "", err
+		}
+		loc := w.tools.Page.Locator(sel).First()
+		_, err = do(ctx, func() (struct{}, error) {
+			return struct{}{}, loc.ScrollIntoViewIfNeeded(playwright.LocatorScrollIntoViewIfNeededOptions{Timeout: w.toolTimeout()})
+		})
+		out = "scrolled-to"
+	case "wait":
+		return "waited", sleepCtx(ctx, st.Wait)
+	case "tool":
+		var args map[string]any
+		if args, err = workflow.ExpandArgs(st.Tool.Args, w.vars); err != nil {
+			return "", err
+		}
+		out, err = w.tools.Call(ctx, st.Tool.Name, args)
+	case "extract":
+		return w.extract(ctx, st.Extract)
+	case "assert":
+		return w.assert(ctx, st.Assert)
+	default:
+		return "", fmt.Errorf("unsupported step %q", st.Kind())
+	}
+	if err != nil {
+		return out, err
+	}
+	w.tools.WaitIdle(ctx)
+	if note := w.tools.syncTabs(ctx); note != "" {
+		w.tools.WaitIdle(ctx)
+	}
+	return out, nil
+}
+
+func (w *workflowRunner) locator(t workflow.Target) (string, error) {
+	t, err := t.Expand(w.vars)
+	if err != nil {
+		return "", err
+	}
+	return t.Locator(), nil
+}
+
+func (w *workflowRunner) extract(ctx context.Context, ex *workflow.Extract) (string, error) {
+	var (
+		val string
+		err error
+	)
+	if ex.Target.IsZero() {
+		val, err = do(ctx, func() (string, error) {
+			return w.tools.Page.InnerText("body", playwright.PageInnerTextOptions{Timeout: w.toolTimeout()})
+		})
+	} else {
+		var sel string
+		if sel, err = w.locator(ex.Target); err != nil {
+			return "", err
+		}
+		tctx, cancel := withTimeout(ctx, w.cfg.Timeouts.Tool)
+		defer cancel()
+		loc := w.tools.Page.Locator(sel).First()
+		val, err = do(tctx, func() (string, error) {
+			if ex.Attr != "" {
+				return loc.GetAttribute(ex.Attr, playwright.LocatorGetAttributeOptions{Timeout: pwTimeout(tctx)})
+			}
+			return loc.InnerText(playwright.LocatorInnerTextOptions{Timeout: pwTimeout(tctx)})
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("extract %s: %w", ex.As, err)
+	}
+	val = safeTrim(val)
+	if w.cfg.ExtractChars > 0 && len(val) > w.cfg.ExtractChars {
+		val = val[:w.cfg.ExtractChars] + "…"
+	}
+	w.vars[ex.As] = val
+	return val, nil
+}
+
+// toolTimeout — таймаут инструмента в мс для прямых вызовов Playwright.
+func (w *workflowRunner) toolTimeout() *float64 {
+	if w.cfg.Timeouts.Tool <= 0 {
+		return nil
+	}
+	ms := float64(w.cfg.Timeouts.Tool.Milliseconds())
+	return &ms
+}
+
+// assertTimeout — сколько ждать выполнения проверки по умолчанию.
+const assertTimeout = 5 * time.Second
+
+// assert опрашивает страницу, пока все условия не выполнятся или не истечёт таймаут.
+func (w *workflowRunner) assert(ctx context.Context, a *workflow.Assert) (string, error) {
+	d := a.Timeout
+	if d <= 0 {
+		d = assertTimeout
+	}
+	deadline := time.Now().Add(d)
+	for {
+		failed, err := w.check(ctx, a)
+		if err != nil {
+			return "", err
+		}
+		if failed == "" {
+			return "passed", nil
+		}
+		if time.Now().After(deadline) {
+			if a.Message != "" {
+				failed = a.Message + ": " + failed
+			}
+			return "", fmt.Errorf("%w: %s", errAssert, failed)
+		}
+		if err := sleepCtx(ctx, 250*time.Millisecond); err != nil {
+			return "", err
+		}
+	}
+}
+
+// check возвращает описание первого невыполненного условия ("" — все выполнены).
+func (w *workflowRunner) check(ctx context.Context, a *workflow.Assert) (string, error) {
+	contains := func(what, have, want string) (string, error) {
+		want, err := workflow.Expand(want, w.vars)
+		if err != nil {
+			return "", err
+		}
+		if !strings.Contains(strings.ToLower(have), strings.ToLower(want)) {
+			return fmt.Sprintf("%s не содержит %q", what, want), nil
+		}
+		return "", nil
+	}
+	if a.URLContains != "" {
+		if msg, err := contains("URL", w.tools.Page.URL(), a.URLContains); msg != "" || err != nil {
+			return msg, err
+		}
+	}
+	if a.TitleContains != "" {
+		title, _ := do(ctx, w.tools.Page.Title)
+		if msg, err := contains("заголовок", title, a.TitleContains); msg != "" || err != nil {
+			return msg, err
+		}
+	}
+	if a.TextContains != "" {
+		body, _ := do(ctx, func() (string, error) {
+			return w.tools.Page.InnerText("body", playwright.PageInnerTextOptions{Timeout: w.toolTimeout()})
+		})
+		if msg, err := contains("текст страницы", body, a.TextContains); msg != "" || err != nil {
+			return msg, err
+		}
+	}
+	for _, c := range []struct {
+		t    *workflow.Element
+		want bool
+	}{{a.Visible, true}, {a.Hidden, false}} {
+		if c.t == nil {
+			continue
+		}
+		sel, err := w.locator(c.t.Target)
+		if err != nil {
+			return "", err
+		}
+		loc := w.tools.Page.Locator(sel).First()
+		vis, _ := do(ctx, func() (bool, error) { return loc.IsVisible() })
+		if vis != c.want {
+			if c.want {
+				return fmt.Sprintf("элемент %s не виден", c.t), nil
+			}
+			return fmt.Sprintf("элемент %s виден", c.t), nil
+		}
+	}
+	if len(a.Equals) == 2 {
+		l, err := workflow.Expand(a.Equals[0], w.vars)
+		if err != nil {
+			return "", err
+		}
+		r, err := workflow.Expand(a.Equals[1], w.vars)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(l) != strings.TrimSpace(r) {
+			return fmt.Sprintf("%q ≠ %q", l, r), nil
+		}
+	}
+	return "", nil
+}
+
+// each выполняет вложенные шаги для строк списка. Строки адресуются по
+// номеру (селектор «rows >> nth=i»), поэтому список пересчитывается перед
+// каждой итерацией: вложенные шаги могут открыть строку и вернуться назад.
+func (w *workflowRunner) each(ctx context.Context, st workflow.Step, path string) error {
+	ea := st.Each
+	as := ea.As
+	if as == "" {
+		as = "row"
+	}
+	rows, err := workflow.Expand(ea.Rows, w.vars)
+	if err != nil {
+		return fmt.Errorf("шаг %s: %w", path, err)
+	}
+	rs := WorkflowStep{Path: path, Step: st.String()}
+	for i := 0; ea.Limit <= 0 || i < ea.Limit; i++ {
+		n, err := do(ctx, w.tools.Page.Locator(rows).Count)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			rs.Status, rs.Error = WorkflowFailed, err.Error()
+			w.done(rs)
+			return fmt.Errorf("шаг %s: %w", path, err)
+		}
+		if i >= n {
+			break
+		}
+		row := w.tools.Page.Locator(rows).Nth(i)
+		text, _ := do(ctx, func() (string, error) {
+			return row.InnerText(playwright.LocatorInnerTextOptions{Timeout: w.toolTimeout()})
+		})
+		w.vars[as+".index"] = strconv.Itoa(i + 1)
+		w.vars[as+".text"] = crop(safeTrim(text), 500)
+		w.vars[as+".selector"] = fmt.Sprintf("%s >> nth=%d", rows, i)
+		if err := w.run(ctx, ea.Steps, fmt.Sprintf("%s.%d.", path, i+1)); err != nil {
+			return err
+		}
+		rs.Output = fmt.Sprintf("%d rows", i+1)
+	}
+	rs.Status = WorkflowOK
+	w.done(rs)
+	return nil
+}
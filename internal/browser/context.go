@@ -0,0 +1,155 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Launch запускает браузер согласно Options: временный контекст (Ephemeral)
+// или постоянный профиль на диске.
+func Launch(ctx context.Context, opts Options) (*playwright.Playwright, playwright.BrowserContext, playwright.Page, error) {
+	if !opts.Ephemeral {
+		return LaunchPersistent(ctx, opts)
+	}
+	pw, b, err := LaunchBrowser(ctx, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bctx, err := NewContext(pw, b, opts)
+	if err != nil {
+		_ = b.Close()
+		_ = pw.Stop()
+		return nil, nil, nil, err
+	}
+	page, err := bctx.NewPage()
+	if err != nil {
+		_ = bctx.Close()
+		_ = b.Close()
+		_ = pw.Stop()
+		return nil, nil, nil, err
+	}
+	return pw, bctx, page, nil
+}
+
+// LaunchBrowser запускает драйвер Playwright и браузер без профиля; контексты
+// создаются NewContext. Закрывается через Browser.Close и Playwright.Stop.
+func LaunchBrowser(ctx context.Context, opts Options) (*playwright.Playwright, playwright.Browser, error) {
+	if err := playwright.Install(&playwright.RunOptions{Browsers: []string{engineName(opts.Engine)}}); err != nil {
+	}
+	pw, err := playwright.Run()
+	if err != nil {
+		return nil, nil, err
+	}
+	bt, err := browserType(pw, opts.Engine)
+	if err != nil {
+		_ = pw.Stop()
+		return nil, nil, err
+	}
+	b, err := bt.Launch(playwright.BrowserTypeLaunchOptions{Headless: playwright.Bool(opts.Headless)})
+	if err != nil {
+		_ = pw.Stop()
+		return nil, nil, err
+	}
+	return pw, b, nil
+}
+
+// NewContext создаёт временный контекст с эмуляцией из opts. Куки и
+// localStorage берутся из файла storage state (StorageFile), если он есть.
+func NewContext(pw *playwright.Playwright, b playwright.Browser, opts Options) (playwright.BrowserContext, error) {
+	emu, err := opts.emulation(pw)
+	if err != nil {
+		return nil, err
+	}
+	var co playwright.BrowserNewContextOptions
+	emu.context(&co)
+	path, err := opts.StorageFile()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		co.StorageStatePath = playwright.String(path)
+	} else if opts.Storage != "" {
+		// Явно указанный файл обязан существовать: молча начать с пустого состояния хуже.
+		return nil, fmt.Errorf("storage state: %w", err)
+	}
+	bctx, err := b.NewContext(co)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout > 0 {
+		bctx.SetDefaultTimeout(float64(opts.Timeout.Milliseconds()))
+		bctx.SetDefaultNavigationTimeout(float64(opts.Timeout.Milliseconds()))
+	}
+	return bctx, nil
+}
+
+// StorageFile — файл storage state: Storage, иначе
+// ~/.<profile>/storage/<account>.json (default.json без Account).
+func (o Options) StorageFile() (string, error) {
+	if o.Storage != "" {
+		return o.Storage, nil
+	}
+	name := o.Account
+	if name == "" {
+		name = "default"
+	} else if err := validAccount(name); err != nil {
+		return "", err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "."+o.Profile, "storage", name+".json"), nil
+}
+
+// validAccount — имя учётной записи годится для имени каталога.
+func validAccount(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid account name %q", name)
+	}
+	return nil
+}
+
+// WriteStorageState записывает состояние в постоянный профиль: куки напрямую,
+// localStorage — открывая каждый origin на заглушке, без обращения к сети.
+// В отличие от ImportStorageState, данные остаются в профиле после закрытия.
+func WriteStorageState(bctx playwright.BrowserContext, st *playwright.StorageState) error {
+	if st == nil {
+		return errors.New("storage state is empty")
+	}
+	if err := addCookies(bctx, st.Cookies); err != nil {
+		return err
+	}
+	page, err := bctx.NewPage()
+	if err != nil {
+		return err
+	}
+	defer page.Close()
+	for _, o := range st.Origins {
+		if len(o.LocalStorage) == 0 {
+			continue
+		}
+		pattern := strings.TrimSuffix(o.Origin, "/") + "/**"
+		stub := func(r playwright.Route) {
+			_ = r.Fulfill(playwright.RouteFulfillOptions{Status: playwright.Int(200), ContentType: playwright.String("text/html"), Body: "<html></html>"})
+		}
+		if err := page.Route(pattern, stub); err != nil {
+			return err
+		}
+		_, err := page.Goto(strings.TrimSuffix(o.Origin, "/")+"/", playwright.PageGotoOptions{WaitUntil: playwright.WaitUntilStateCommit})
+		if err == nil {
+			_, err = page.Evaluate(`(items) => { for (const {name, value} of items) localStorage.setItem(name, value); }`, o.LocalStorage)
+		}
+		_ = page.Unroute(pattern)
+		if err != nil {
+			return fmt.Errorf("localStorage %s: %w", o.Origin, err)
+		}
+	}
+	return nil
+}
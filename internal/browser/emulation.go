@@ -0,0 +1,157 @@
+package browser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// emulation — параметры контекста, которыми браузер представляется сайту.
+type emulation struct {
+	viewport    *playwright.Size
+	screen      *playwright.Size
+	scale       *float64
+	mobile      *bool
+	touch       *bool
+	userAgent   *string
+	locale      *string
+	timezone    *string
+	geo         *playwright.Geolocation
+	scheme      *playwright.ColorScheme
+	headers     map[string]string
+	permissions []string
+}
+
+// emulation собирает параметры из Options: сначала пресет устройства, поверх
+// него — явно заданные размер окна, user agent и остальное.
+func (o Options) emulation(pw *playwright.Playwright) (emulation, error) {
+	var e emulation
+	if o.Device != "" {
+		d, ok := pw.Devices[o.Device]
+		if !ok {
+			return e, fmt.Errorf("unknown device %q, e.g.: %s", o.Device, strings.Join(deviceExamples(pw), ", "))
+		}
+		e.viewport, e.screen = d.Viewport, d.Screen
+		e.scale = playwright.Float(d.DeviceScaleFactor)
+		e.mobile, e.touch = playwright.Bool(d.IsMobile), playwright.Bool(d.HasTouch)
+		if d.UserAgent != "" {
+			e.userAgent = playwright.String(d.UserAgent)
+		}
+	}
+	if o.Viewport != "" {
+		w, h, ok := parseSize(o.Viewport)
+		if !ok {
+			return e, fmt.Errorf("viewport %q: expected WIDTHxHEIGHT, e.g. 1280x800", o.Viewport)
+		}
+		e.viewport = &playwright.Size{Width: w, Height: h}
+	}
+	if o.UserAgent != "" {
+		e.userAgent = playwright.String(o.UserAgent)
+	}
+	if o.Locale != "" {
+		e.locale = playwright.String(o.Locale)
+	}
+	if o.Timezone != "" {
+		e.timezone = playwright.String(o.Timezone)
+	}
+	if o.Geolocation != "" {
+		g, err := parseGeolocation(o.Geolocation)
+		if err != nil {
+			return e, err
+		}
+		// Без разрешения сайт получит отказ вместо подменённых координат.
+		e.geo, e.permissions = g, []string{"geolocation"}
+	}
+	switch strings.ToLower(o.ColorScheme) {
+	case "":
+	case "light":
+		e.scheme = playwright.ColorSchemeLight
+	case "dark":
+		e.scheme = playwright.ColorSchemeDark
+	case "no-preference":
+		e.scheme = playwright.ColorSchemeNoPreference
+	default:
+		return e, fmt.Errorf("color scheme %q: expected light, dark or no-preference", o.ColorScheme)
+	}
+	if len(o.Headers) > 0 {
+		e.headers = o.Headers
+	}
+	return e, nil
+}
+
+// persistent переносит параметры в опции постоянного контекста.
+func (e emulation) persistent(opts *playwright.BrowserTypeLaunchPersistentContextOptions) {
+	opts.Viewport, opts.Screen, opts.DeviceScaleFactor = e.viewport, e.screen, e.scale
+	opts.IsMobile, opts.HasTouch, opts.UserAgent = e.mobile, e.touch, e.userAgent
+	opts.Locale, opts.TimezoneId, opts.Geolocation = e.locale, e.timezone, e.geo
+	opts.ColorScheme, opts.ExtraHttpHeaders, opts.Permissions = e.scheme, e.headers, e.permissions
+}
+
+// context переносит параметры в опции временного контекста.
+func (e emulation) context(opts *playwright.BrowserNewContextOptions) {
+	opts.Viewport, opts.Screen, opts.DeviceScaleFactor = e.viewport, e.screen, e.scale
+	opts.IsMobile, opts.HasTouch, opts.UserAgent = e.mobile, e.touch, e.userAgent
+	opts.Locale, opts.TimezoneId, opts.Geolocation = e.locale, e.timezone, e.geo
+	opts.ColorScheme, opts.ExtraHttpHeaders, opts.Permissions = e.scheme, e.headers, e.permissions
+}
+
+// parseSize разбирает «1280x800».
+func parseSize(s string) (int, int, bool) {
+	ws, hs, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	if !ok {
+		return 0, 0, false
+	}
+	w, err1 := strconv.Atoi(strings.TrimSpace(ws))
+	h, err2 := strconv.Atoi(strings.TrimSpace(hs))
+	if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return 0, 0, false
+	}
+	return w, h, true
+}
+
+// parseGeolocation разбирает «широта,долгота[,точность в метрах]».
+func parseGeolocation(s string) (*playwright.Geolocation, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("geolocation %q: expected latitude,longitude[,accuracy]", s)
+	}
+	nums := make([]float64, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("geolocation %q: %w", s, err)
+		}
+		nums[i] = f
+	}
+	if nums[0] < -90 || nums[0] > 90 || nums[1] < -180 || nums[1] > 180 {
+		return nil, fmt.Errorf("geolocation %q: out of range", s)
+	}
+	g := &playwright.Geolocation{Latitude: nums[0], Longitude: nums[1]}
+	if len(nums) == 3 {
+		g.Accuracy = playwright.Float(nums[2])
+	}
+	return g, nil
+}
+
+// deviceExamples — несколько известных пресетов для сообщения об ошибке.
+func deviceExamples(pw *playwright.Playwright) []string {
+	var out []string
+	for _, name := range []string{"iPhone 13", "Pixel 7", "iPad Pro 11", "Galaxy S9+", "Desktop Chrome", "Desktop Firefox", "Desktop Safari"} {
+		if _, ok := pw.Devices[name]; ok {
+			out = append(out, name)
+		}
+	}
+	if len(out) == 0 {
+		for name := range pw.Devices {
+			out = append(out, name)
+		}
+		sort.Strings(out)
+		if len(out) > 5 {
+			out = out[:5]
+		}
+	}
+	return out
+}
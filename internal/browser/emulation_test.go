@@ -0,0 +1,60 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		w, h int
+		ok   bool
+	}{
+		{"1280x800", 1280, 800, true},
+		{" 390 X 844 ", 390, 844, true},
+		{"1280", 0, 0, false},
+		{"0x800", 0, 0, false},
+		{"1280x-1", 0, 0, false},
+		{"axb", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			w, h, ok := parseSize(tt.in)
+			if w != tt.w || h != tt.h || ok != tt.ok {
+				t.Errorf("parseSize(%q) = %d, %d, %v, want %d, %d, %v", tt.in, w, h, ok, tt.w, tt.h, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseGeolocation(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    *playwright.Geolocation
+		wantErr bool
+	}{
+		{"55.7558,37.6173", &playwright.Geolocation{Latitude: 55.7558, Longitude: 37.6173}, false},
+		{" -33.86 , 151.21 , 50 ", &playwright.Geolocation{Latitude: -33.86, Longitude: 151.21, Accuracy: playwright.Float(50)}, false},
+		{"90,180", &playwright.Geolocation{Latitude: 90, Longitude: 180}, false},
+		{"55.7", nil, true},
+		{"1,2,3,4", nil, true},
+		{"north,37", nil, true},
+		{"91,0", nil, true},
+		{"0,-181", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseGeolocation(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGeolocation(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseGeolocation(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
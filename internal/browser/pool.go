@@ -0,0 +1,101 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Pool — один драйвер Playwright и один процесс браузера, из которых задачи
+// берут собственные временные контексты. Открытых контекстов не больше Size.
+type Pool struct {
+	opts  Options
+	pw    *playwright.Playwright
+	b     playwright.Browser
+	slots chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Lease — контекст, выданный пулом одной задаче.
+type Lease struct {
+	Context playwright.BrowserContext
+	Page    playwright.Page
+	release func()
+	once    sync.Once
+}
+
+// Release закрывает контекст и возвращает место в пул; повторный вызов ничего не делает.
+func (l *Lease) Release() {
+	l.once.Do(l.release)
+}
+
+// NewPool запускает браузер для size одновременных контекстов. Постоянный
+// профиль в пуле не используется: его может открыть только один контекст.
+func NewPool(ctx context.Context, opts Options, size int) (*Pool, error) {
+	if size < 1 {
+		size = 1
+	}
+	pw, b, err := LaunchBrowser(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Pool{opts: opts, pw: pw, b: b, slots: make(chan struct{}, size)}, nil
+}
+
+// Size — сколько контекстов пул держит одновременно.
+func (p *Pool) Size() int { return cap(p.slots) }
+
+// Acquire ждёт свободное место и открывает чистый контекст с эмуляцией пула;
+// куки и localStorage — из файла storage state учётной записи account
+// (пусто — из настроек пула).
+func (p *Pool) Acquire(ctx context.Context, account string) (*Lease, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	free := func() { <-p.slots }
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		free()
+		return nil, errors.New("browser pool is closed")
+	}
+	opts := p.opts
+	if account != "" {
+		opts.Account, opts.Storage = account, ""
+	}
+	bctx, err := NewContext(p.pw, p.b, opts)
+	if err != nil {
+		free()
+		return nil, err
+	}
+	page, err := bctx.NewPage()
+	if err != nil {
+		_ = bctx.Close()
+		free()
+		return nil, err
+	}
+	return &Lease{Context: bctx, Page: page, release: func() {
+		_ = bctx.Close()
+		free()
+	}}, nil
+}
+
+// Close закрывает браузер и драйвер; контексты, ещё выданные задачам, закрываются вместе с браузером.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+	_ = p.b.Close()
+	return p.pw.Stop()
+}
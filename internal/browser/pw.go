@@ -2,33 +2,144 @@ package browser
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/playwright-community/playwright-go"
 )
 
-func LaunchPersistent(ctx context.Context, userDataDir string, headed bool) (*playwright.Playwright, playwright.BrowserContext, playwright.Page, error) {
+// Options — параметры запуска браузера.
+type Options struct {
+	// Engine: chromium, firefox или webkit.
+	Engine   string `yaml:"engine"`
+	Headless bool   `yaml:"headless"`
+	// Profile — имя профиля в домашнем каталоге (~/.<profile>/profile).
+	Profile string `yaml:"profile"`
+	// ProfileDir — явный путь к профилю; имеет приоритет над Profile и Account.
+	ProfileDir string `yaml:"profile_dir"`
+	// Account — именованный профиль учётной записи: ~/.<profile>/accounts/<account>,
+	// у каждой свои куки; для временного контекста — свой файл storage state.
+	Account string `yaml:"account"`
+	// Ephemeral — временный контекст без профиля на диске: запуски не делят
+	// состояние и могут идти параллельно. Начальные куки и localStorage — из StorageFile.
+	Ephemeral bool `yaml:"ephemeral"`
+	// Storage — JSON storage state Playwright для временного контекста и команд storage.
+	Storage string `yaml:"storage"`
+	// Timeout — таймаут по умолчанию для действий и навигации Playwright.
+	Timeout time.Duration `yaml:"timeout"`
 
+	// Device — пресет устройства Playwright («iPhone 13», «Pixel 7»): размер
+	// экрана, масштаб, user agent, сенсорный ввод. Остальные поля уточняют его.
+	Device string `yaml:"device"`
+	// Viewport — размер окна, «1280x800».
+	Viewport  string `yaml:"viewport"`
+	UserAgent string `yaml:"user_agent"`
+	// Locale — язык браузера и Accept-Language, например ru-RU.
+	Locale string `yaml:"locale"`
+	// Timezone — часовой пояс IANA, например Europe/Moscow.
+	Timezone string `yaml:"timezone"`
+	// Geolocation — «широта,долгота[,точность]»; разрешение на геолокацию выдаётся сразу.
+	Geolocation string `yaml:"geolocation"`
+	// ColorScheme — prefers-color-scheme: light, dark или no-preference.
+	ColorScheme string `yaml:"color_scheme"`
+	// Headers — дополнительные HTTP-заголовки каждого запроса.
+	Headers map[string]string `yaml:"headers"`
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Engine:  "chromium",
+		Profile: "aiagent",
+		Timeout: 30 * time.Second,
+	}
+}
+
+// UserDataDir возвращает каталог профиля с учётом ProfileDir/Profile.
+func (o Options) UserDataDir() (string, error) {
+	if o.ProfileDir != "" {
+		return o.ProfileDir, nil
+	}
+	if o.Account != "" {
+		if err := validAccount(o.Account); err != nil {
+			return "", err
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "."+o.Profile, "accounts", o.Account), nil
+	}
+	return DefaultProfileDir(o.Profile)
+}
+
+func browserType(pw *playwright.Playwright, engine string) (playwright.BrowserType, error) {
+	switch strings.ToLower(engine) {
+	case "", "chromium", "chrome":
+		return pw.Chromium, nil
+	case "firefox":
+		return pw.Firefox, nil
+	case "webkit", "safari":
+		return pw.WebKit, nil
+	default:
+		return nil, fmt.Errorf("unknown browser engine: %s", engine)
+	}
+}
+
+// engineName — имя движка для установки браузера Playwright.
+func engineName(engine string) string {
+	switch strings.ToLower(engine) {
+	case "firefox":
+		return "firefox"
+	case "webkit", "safari":
+		return "webkit"
+	}
+	return "chromium"
+}
+
+func LaunchPersistent(ctx context.Context, opts Options) (*playwright.Playwright, playwright.BrowserContext, playwright.Page, error) {
+	userDataDir, err := opts.UserDataDir()
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	if err := os.MkdirAll(userDataDir, 0o755); err != nil {
 		return nil, nil, nil, err
 	}
 
-	if err := playwright.Install(); err != nil {
+	// Скачиваем только нужный движок, а не все три.
+	if err := playwright.Install(&playwright.RunOptions{Browsers: []string{engineName(opts.Engine)}}); err != nil {
 	}
 	pw, err := playwright.Run()
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
+	bt, err := browserType(pw, opts.Engine)
+	if err != nil {
+		_ = pw.Stop()
+		return nil, nil, nil, err
+	}
+
+	emu, err := opts.emulation(pw)
+	if err != nil {
+		_ = pw.Stop()
+		return nil, nil, nil, err
+	}
 	ctxOpts := playwright.BrowserTypeLaunchPersistentContextOptions{
-		Headless: playwright.Bool(!headed),
+		Headless: playwright.Bool(opts.Headless),
 	}
-	bctx, err := pw.Chromium.LaunchPersistentContext(userDataDir, ctxOpts)
+	emu.persistent(&ctxOpts)
+	bctx, err := bt.LaunchPersistentContext(userDataDir, ctxOpts)
 	if err != nil {
 		_ = pw.Stop()
 		return nil, nil, nil, err
 	}
+	if opts.Timeout > 0 {
+		bctx.SetDefaultTimeout(float64(opts.Timeout.Milliseconds()))
+		bctx.SetDefaultNavigationTimeout(float64(opts.Timeout.Milliseconds()))
+	}
 
 	page, err := bctx.NewPage()
 	if err != nil {
@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// ExportStorageState снимает куки и localStorage контекста.
+func ExportStorageState(bctx playwright.BrowserContext) (*playwright.StorageState, error) {
+	return bctx.StorageState()
+}
+
+// ImportStorageState переносит сохранённое состояние в уже открытый контекст:
+// куки добавляются напрямую, localStorage — скриптом инициализации, который
+// заполняет отсутствующие ключи при открытии страницы соответствующего origin.
+func ImportStorageState(bctx playwright.BrowserContext, st *playwright.StorageState) error {
+	if st == nil {
+		return nil
+	}
+	if err := addCookies(bctx, st.Cookies); err != nil {
+		return err
+	}
+	if len(st.Origins) > 0 {
+		data := map[string]map[string]string{}
+		for _, o := range st.Origins {
+			kv := map[string]string{}
+			for _, nv := range o.LocalStorage {
+				kv[nv.Name] = nv.Value
+			}
+			data[o.Origin] = kv
+		}
+		js, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		script := fmt.Sprintf(`(() => {
+  const data = %s;
+  const items = data[location.origin];
+  if (!items) return;
+  try {
+    for (const [k, v] of Object.entries(items)) {
+      if (localStorage.getItem(k) === null) localStorage.setItem(k, v);
+    }
+  } catch (e) {}
+})();`, js)
+		if err := bctx.AddInitScript(playwright.Script{Content: playwright.String(script)}); err != nil {
+			return fmt.Errorf("import localStorage: %w", err)
+		}
+	}
+	return nil
+}
+
+func addCookies(bctx playwright.BrowserContext, cs []playwright.Cookie) error {
+	if len(cs) == 0 {
+		return nil
+	}
+	cookies := make([]playwright.OptionalCookie, 0, len(cs))
+	for _, c := range cs {
+		cookies = append(cookies, playwright.OptionalCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   playwright.String(c.Domain),
+			Path:     playwright.String(c.Path),
+			Expires:  playwright.Float(c.Expires),
+			HttpOnly: playwright.Bool(c.HttpOnly),
+			Secure:   playwright.Bool(c.Secure),
+			SameSite: c.SameSite,
+		})
+	}
+	if err := bctx.AddCookies(cookies); err != nil {
+		return fmt.Errorf("import cookies: %w", err)
+	}
+	return nil
+}
+
+// SaveStorageState записывает состояние в JSON-файл (формат Playwright storageState).
+func SaveStorageState(path string, st *playwright.StorageState) error {
+	js, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, js, 0o600)
+}
+
+// LoadStorageState читает JSON-файл в формате Playwright storageState.
+func LoadStorageState(path string) (*playwright.StorageState, error) {
+	js, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var st playwright.StorageState
+	if err := json.Unmarshal(js, &st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &st, nil
+}
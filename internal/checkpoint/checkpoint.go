@@ -0,0 +1,121 @@
+// Package checkpoint сохраняет состояние запуска агента после каждого шага,
+// чтобы прерванную задачу можно было продолжить командой agent resume.
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"AIAgent/internal/memory"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Checkpoint — всё, что нужно для продолжения задачи.
+type Checkpoint struct {
+	RunID   string       `json:"run_id"`
+	Task    string       `json:"task"`
+	Step    int          `json:"step"`
+	Status  string       `json:"status"` // running или итоговый agent.Status*
+	URL     string       `json:"url"`
+	Title   string       `json:"title"`
+	Memory  memory.State `json:"memory"`
+	Started time.Time    `json:"started"`
+	Updated time.Time    `json:"updated"`
+	// Storage — куки и localStorage на момент сохранения.
+	Storage *playwright.StorageState `json:"storage,omitempty"`
+}
+
+// StatusRunning — задача не завершена и может быть продолжена.
+const StatusRunning = "running"
+
+// Store хранит контрольные точки в каталоге, по файлу <run-id>.json.
+type Store struct {
+	Dir string
+}
+
+// DefaultDir — ~/.aiagent/runs.
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".aiagent", "runs"), nil
+}
+
+// NewRunID генерирует идентификатор запуска вида 20261018-150405-1a2b.
+func NewRunID() string {
+	now := time.Now()
+	return fmt.Sprintf("%s-%04x", now.Format("20060102-150405"), now.Nanosecond()&0xffff)
+}
+
+func (s Store) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", fmt.Errorf("checkpoint: bad run id %q", id)
+	}
+	return filepath.Join(s.Dir, id+".json"), nil
+}
+
+// Save атомарно перезаписывает контрольную точку запуска.
+func (s Store) Save(cp Checkpoint) error {
+	p, err := s.path(cp.RunID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	cp.Updated = time.Now()
+	js, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, js, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (s Store) Load(id string) (Checkpoint, error) {
+	var cp Checkpoint
+	p, err := s.path(id)
+	if err != nil {
+		return cp, err
+	}
+	js, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, fmt.Errorf("checkpoint %s not found in %s", id, s.Dir)
+	}
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(js, &cp); err != nil {
+		return cp, fmt.Errorf("checkpoint %s: %w", id, err)
+	}
+	return cp, nil
+}
+
+// List возвращает контрольные точки, новые первыми.
+func (s Store) List() ([]Checkpoint, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []Checkpoint
+	for _, f := range files {
+		cp, err := s.Load(strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			continue
+		}
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Updated.After(out[j].Updated) })
+	return out, nil
+}
@@ -0,0 +1,106 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"AIAgent/internal/memory"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestSaveLoad(t *testing.T) {
+	s := Store{Dir: filepath.Join(t.TempDir(), "runs")}
+	cp := Checkpoint{
+		RunID:   "20261018-150405-1a2b",
+		Task:    "разобрать почту",
+		Step:    3,
+		Status:  StatusRunning,
+		URL:     "https://mail.example/inbox",
+		Title:   "Входящие",
+		Memory:  memory.State{LastAction: "click", Hints: []string{"письма слева"}},
+		Started: time.Date(2026, 10, 18, 15, 4, 5, 0, time.UTC),
+		Storage: &playwright.StorageState{Cookies: []playwright.Cookie{{Name: "sid", Value: "x", Domain: "mail.example", Path: "/"}}},
+	}
+	if err := s.Save(cp); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Load(cp.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Updated.IsZero() {
+		t.Error("Updated is not set")
+	}
+	cp.Updated = got.Updated
+	if !reflect.DeepEqual(got, cp) {
+		t.Errorf("Load = %+v\nwant %+v", got, cp)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, cp.RunID+".json.tmp")); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestBadRunID(t *testing.T) {
+	s := Store{Dir: t.TempDir()}
+	for _, id := range []string{"", ".", "..", "../x", `a\b`, "a/b"} {
+		t.Run(id, func(t *testing.T) {
+			if err := s.Save(Checkpoint{RunID: id}); err == nil {
+				t.Error("Save accepted bad id")
+			}
+			if _, err := s.Load(id); err == nil {
+				t.Error("Load accepted bad id")
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	s := Store{Dir: t.TempDir()}
+	if err := os.WriteFile(filepath.Join(s.Dir, "broken.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"missing", "checkpoint missing not found in " + s.Dir},
+		{"broken", "checkpoint broken: unexpected end of JSON input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			_, err := s.Load(tt.id)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Load(%q) error = %v, want %q", tt.id, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	s := Store{Dir: t.TempDir()}
+	for _, id := range []string{"old", "new"} {
+		if err := s.Save(Checkpoint{RunID: id, Status: StatusRunning}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Битые файлы пропускаются.
+	if err := os.WriteFile(filepath.Join(s.Dir, "broken.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, cp := range list {
+		ids = append(ids, cp.RunID)
+	}
+	if want := []string{"new", "old"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("List ids = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,226 @@
+// Package config собирает настройки агента из значений по умолчанию,
+// YAML-файла, переменных окружения AIAGENT_* и флагов командной строки
+// (в порядке возрастания приоритета).
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"AIAgent/internal/agent"
+	"AIAgent/internal/browser"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix — префикс переменных окружения, переопределяющих флаги:
+// флаг -max-steps ↔ AIAGENT_MAX_STEPS.
+const EnvPrefix = "AIAGENT_"
+
+type Config struct {
+	Browser browser.Options `yaml:"browser"`
+	Agent   agent.Config    `yaml:"agent"`
+}
+
+func Default() Config {
+	return Config{
+		Browser: browser.DefaultOptions(),
+		Agent:   agent.DefaultConfig(),
+	}
+}
+
+// DefaultPath — ~/.aiagent/config.yaml; используется, если файл существует.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aiagent", "config.yaml")
+}
+
+// LoadFile накладывает YAML-файл поверх c. Неизвестные ключи считаются ошибкой.
+func (c *Config) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+// RegisterFlags привязывает флаги к полям c.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	b, a := &c.Browser, &c.Agent
+	fs.BoolVar(&b.Headless, "headless", b.Headless, "запускать браузер без окна")
+	fs.StringVar(&b.Engine, "engine", b.Engine, "движок браузера: chromium, firefox, webkit")
+	fs.StringVar(&b.Profile, "profile", b.Profile, "имя профиля (~/.<profile>/profile)")
+	fs.StringVar(&b.ProfileDir, "profile-dir", b.ProfileDir, "каталог профиля браузера")
+	fs.StringVar(&b.Account, "account", b.Account, "именованный профиль учётной записи (~/.<profile>/accounts/<account>)")
+	fs.BoolVar(&b.Ephemeral, "ephemeral", b.Ephemeral, "временный контекст без профиля на диске (состояние — из -storage)")
+	fs.StringVar(&b.Storage, "storage", b.Storage, "файл storage state (куки и localStorage) для -ephemeral и команды storage")
+	fs.DurationVar(&b.Timeout, "browser-timeout", b.Timeout, "таймаут действий и навигации браузера")
+	fs.StringVar(&b.Device, "device", b.Device, "эмулировать устройство Playwright, например \"iPhone 13\" или \"Pixel 7\"")
+	fs.StringVar(&b.Viewport, "viewport", b.Viewport, "размер окна ШИРИНАxВЫСОТА, например 1280x800")
+	fs.StringVar(&b.UserAgent, "user-agent", b.UserAgent, "строка User-Agent")
+	fs.StringVar(&b.Locale, "locale", b.Locale, "язык браузера, например ru-RU")
+	fs.StringVar(&b.Timezone, "timezone", b.Timezone, "часовой пояс, например Europe/Moscow")
+	fs.StringVar(&b.Geolocation, "geolocation", b.Geolocation, "координаты широта,долгота[,точность]")
+	fs.StringVar(&b.ColorScheme, "color-scheme", b.ColorScheme, "предпочтительная тема: light, dark или no-preference")
+	fs.Var(headersFlag{&b.Headers}, "header", "дополнительный HTTP-заголовок \"Имя: значение\" (можно повторять)")
+
+	fs.StringVar(&a.LLM.Provider, "provider", a.LLM.Provider, "планировщик: openai или heuristic")
+	fs.StringVar(&a.LLM.Model, "model", a.LLM.Model, "модель LLM")
+	fs.StringVar(&a.LLM.Endpoint, "endpoint", a.LLM.Endpoint, "URL chat/completions OpenAI-совместимого API")
+	fs.Float64Var(&a.LLM.Temperature, "temperature", a.LLM.Temperature, "температура LLM")
+	fs.DurationVar(&a.LLM.Timeout, "llm-timeout", a.LLM.Timeout, "таймаут запроса к LLM")
+
+	fs.IntVar(&a.MaxSteps, "max-steps", a.MaxSteps, "лимит шагов на задачу")
+	fs.IntVar(&a.InitialCandidates, "initial-candidates", a.InitialCandidates, "кандидатов на первом наблюдении")
+	fs.IntVar(&a.Candidates, "candidates", a.Candidates, "кандидатов на каждом следующем наблюдении")
+	fs.IntVar(&a.PromptCandidates, "prompt-candidates", a.PromptCandidates, "сколько кандидатов передавать модели")
+	fs.IntVar(&a.SnapshotChars, "snapshot-chars", a.SnapshotChars, "размер текстового снимка страницы")
+	fs.IntVar(&a.PromptSnapshotChars, "prompt-snapshot-chars", a.PromptSnapshotChars, "размер снимка в промпте")
+	fs.IntVar(&a.ExtractChars, "extract-chars", a.ExtractChars, "лимит текста для инструмента extract")
+	fs.DurationVar(&a.StepDelay, "step-delay", a.StepDelay, "дополнительная пауза между шагами")
+	fs.DurationVar(&a.Settle.Quiet, "settle-quiet", a.Settle.Quiet, "сколько DOM должен не меняться, чтобы страница считалась успокоившейся")
+	fs.DurationVar(&a.Settle.Max, "settle-max", a.Settle.Max, "предел ожидания, пока страница успокоится после действия")
+	fs.DurationVar(&a.Timeouts.Run, "run-timeout", a.Timeouts.Run, "дедлайн на всю задачу (0 — без ограничения)")
+	fs.DurationVar(&a.Timeouts.Step, "step-timeout", a.Timeouts.Step, "дедлайн на один шаг")
+	fs.DurationVar(&a.Timeouts.Tool, "tool-timeout", a.Timeouts.Tool, "дедлайн на вызов инструмента")
+	fs.StringVar(&a.LogFormat, "log-format", a.LogFormat, "формат журнала: text, json или none")
+
+	fs.IntVar(&a.Policy.NoProgressLimit, "no-progress-limit", a.Policy.NoProgressLimit, "шагов без прогресса до принудительного fallback")
+	fs.BoolVar(&a.Policy.StallFallback, "stall-fallback", a.Policy.StallFallback, "разрешить принудительный fallback при зацикливании")
+	fs.BoolVar(&a.Policy.Backtrack, "backtrack", a.Policy.Backtrack, "при затяжном зацикливании возвращаться назад по истории")
+	fs.BoolVar(&a.Checkpoints.Enabled, "checkpoints", a.Checkpoints.Enabled, "сохранять контрольные точки после каждого шага для agent resume (в файл попадают куки и localStorage сессии)")
+	fs.StringVar(&a.Checkpoints.Dir, "checkpoint-dir", a.Checkpoints.Dir, "каталог контрольных точек")
+	fs.BoolVar(&a.Trajectory.Enabled, "record", a.Trajectory.Enabled, "записывать траекторию запуска")
+	fs.StringVar(&a.Trajectory.Dir, "trajectory-dir", a.Trajectory.Dir, "каталог траекторий")
+	fs.BoolVar(&a.Trajectory.Screenshots, "screenshots", a.Trajectory.Screenshots, "сохранять скриншоты в траекторию (без маскировки персональных данных)")
+	fs.BoolVar(&a.Skills.Enabled, "skills", a.Skills.Enabled, "предлагать планировщику навыки из библиотеки (run_skill)")
+	fs.StringVar(&a.Skills.Dir, "skills-dir", a.Skills.Dir, "каталог библиотеки навыков")
+	fs.BoolVar(&a.Spam.Enabled, "spam", a.Spam.Enabled, "локальный классификатор спама для mail_classify")
+	fs.StringVar(&a.Spam.Dir, "spam-dir", a.Spam.Dir, "каталог размеченных примеров классификатора")
+	fs.Float64Var(&a.Spam.Threshold, "spam-threshold", a.Spam.Threshold, "уверенность, с которой спам предлагается удалить")
+	fs.StringVar(&a.Dialogs.Mode, "dialogs", a.Dialogs.Mode, "диалоги без правила: planner (решает модель) или auto")
+	fs.StringVar(&a.Dialogs.Default, "dialog-default", a.Dialogs.Default, "ответ на диалог без правила в режиме auto: accept или dismiss")
+	fs.BoolVar(&a.Vision.Enabled, "vision", a.Vision.Enabled, "прикладывать к запросу модели снимок страницы; -redact снимок не маскирует: всё видимое на экране, включая адреса и тексты писем, уходит в LLM")
+	fs.StringVar(&a.Files.UploadDir, "upload-dir", a.Files.UploadDir, "каталог, из которого upload_file может брать файлы (пусто — загрузка запрещена)")
+	fs.BoolVar(&a.Files.Downloads, "downloads", a.Files.Downloads, "сохранять скачанные файлы")
+	fs.StringVar(&a.Files.DownloadDir, "download-dir", a.Files.DownloadDir, "каталог скачиваний (внутри — подкаталог на каждый запуск)")
+	fs.StringVar(&a.Mailbox.Backend, "mailbox", a.Mailbox.Backend, "доступ к ящику без браузера: imap или maildir (пусто — только веб-клиент)")
+	fs.StringVar(&a.Mailbox.Addr, "mailbox-addr", a.Mailbox.Addr, "host:port IMAP-сервера")
+	fs.BoolVar(&a.Mailbox.TLS, "mailbox-tls", a.Mailbox.TLS, "подключаться к IMAP по TLS")
+	fs.StringVar(&a.Mailbox.Username, "mailbox-user", a.Mailbox.Username, "логин IMAP")
+	fs.StringVar(&a.Mailbox.Password, "mailbox-password", a.Mailbox.Password, "пароль IMAP (лучше через AIAGENT_MAILBOX_PASSWORD)")
+	fs.StringVar(&a.Mailbox.Maildir, "maildir", a.Mailbox.Maildir, "каталог Maildir")
+	fs.BoolVar(&a.Redact.Enabled, "redact", a.Redact.Enabled, "скрывать персональные данные перед отправкой в LLM")
+}
+
+// Parse разбирает args: сначала флаги (чтобы узнать -config), затем файл,
+// окружение и повторно явно заданные флаги, чтобы они имели наивысший приоритет.
+func Parse(fs *flag.FlagSet, args []string) (Config, error) {
+	c := Default()
+	c.RegisterFlags(fs)
+	path := fs.String("config", "", "путь к YAML-файлу конфигурации (по умолчанию ~/.aiagent/config.yaml)")
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+
+	explicit := map[string]string{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = f.Value.String() })
+
+	cfgPath := *path
+	if cfgPath == "" {
+		if p := os.Getenv(EnvPrefix + "CONFIG"); p != "" {
+			cfgPath = p
+		} else if p := DefaultPath(); p != "" {
+			if _, err := os.Stat(p); err == nil {
+				cfgPath = p
+			}
+		}
+	}
+	if cfgPath != "" {
+		if err := c.LoadFile(cfgPath); err != nil {
+			return c, err
+		}
+	}
+
+	var envErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+		if v, ok := os.LookupEnv(EnvName(f.Name)); ok && envErr == nil {
+			if err := fs.Set(f.Name, v); err != nil {
+				envErr = fmt.Errorf("%s: %w", EnvName(f.Name), err)
+			}
+		}
+	})
+	if envErr != nil {
+		return c, envErr
+	}
+
+	// Явные флаги важнее файла и окружения, поэтому применяются заново — но
+	// только те, чьё значение файл или окружение изменили. Повторяемый -header
+	// при этом дополняет заголовки из файла и окружения, а при совпадении имени
+	// заменяет их.
+	for name, v := range explicit {
+		if fs.Lookup(name).Value.String() == v {
+			continue
+		}
+		if err := fs.Set(name, v); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// headersFlag — повторяемый флаг -header "Имя: значение". Несколько заголовков
+// в одном значении (AIAGENT_HEADER, повторное применение флагов) разделяются переводом строки.
+type headersFlag struct{ m *map[string]string }
+
+func (h headersFlag) String() string {
+	if h.m == nil || len(*h.m) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(*h.m))
+	for k, v := range *h.m {
+		parts = append(parts, k+": "+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\n")
+}
+
+func (h headersFlag) Set(s string) error {
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("ожидается \"Имя: значение\", получено %q", line)
+		}
+		if *h.m == nil {
+			*h.m = map[string]string{}
+		}
+		(*h.m)[strings.TrimSpace(name)] = strings.TrimSpace(val)
+	}
+	return nil
+}
+
+// EnvName возвращает имя переменной окружения для флага.
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testYAML = `
+agent:
+  max_steps: 5
+  llm:
+    model: yaml-model
+    temperature: 0.5
+browser:
+  headers:
+    X-A: yaml
+    X-B: yaml
+`
+
+func TestParsePrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		steps    int
+		model    string
+		temp     float64
+		headers  map[string]string
+		headless bool
+	}{
+		{
+			name:    "file over defaults",
+			steps:   5,
+			model:   "yaml-model",
+			temp:    0.5,
+			headers: map[string]string{"X-A": "yaml", "X-B": "yaml"},
+		},
+		{
+			name:    "env over file",
+			env:     map[string]string{"AIAGENT_MAX_STEPS": "7", "AIAGENT_MODEL": "env-model"},
+			steps:   7,
+			model:   "env-model",
+			temp:    0.5,
+			headers: map[string]string{"X-A": "yaml", "X-B": "yaml"},
+		},
+		{
+			name:    "flag over env and file",
+			env:     map[string]string{"AIAGENT_MAX_STEPS": "7", "AIAGENT_MODEL": "env-model"},
+			args:    []string{"-max-steps", "9", "-headless"},
+			steps:   9,
+			model:   "env-model",
+			temp:    0.5,
+			headers: map[string]string{"X-A": "yaml", "X-B": "yaml"},
+			// Значение по умолчанию зависит от окружения, поэтому проверяется только явный флаг.
+			headless: true,
+		},
+		{
+			name:     "repeated header flag merges over file and env",
+			env:      map[string]string{"AIAGENT_HEADER": "X-C: env\nX-B: env"},
+			args:     []string{"-header", "X-B: flag", "-header", "X-D: flag", "-headless"},
+			steps:    5,
+			model:    "yaml-model",
+			temp:     0.5,
+			headers:  map[string]string{"X-A": "yaml", "X-B": "flag", "X-C": "env", "X-D": "flag"},
+			headless: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(testYAML), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv(EnvPrefix+"CONFIG", path)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			c, err := Parse(flag.NewFlagSet("test", flag.ContinueOnError), tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Agent.MaxSteps != tt.steps {
+				t.Errorf("MaxSteps = %d, want %d", c.Agent.MaxSteps, tt.steps)
+			}
+			if c.Agent.LLM.Model != tt.model {
+				t.Errorf("Model = %q, want %q", c.Agent.LLM.Model, tt.model)
+			}
+			if c.Agent.LLM.Temperature != tt.temp {
+				t.Errorf("Temperature = %v, want %v", c.Agent.LLM.Temperature, tt.temp)
+			}
+			if !reflect.DeepEqual(c.Browser.Headers, tt.headers) {
+				t.Errorf("Headers = %v, want %v", c.Browser.Headers, tt.headers)
+			}
+			if tt.headless && !c.Browser.Headless {
+				t.Error("Headless = false, want true")
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  map[string]string
+	}{
+		{"unknown yaml key", "agent:\n  no_such_key: 1\n", nil},
+		{"bad env value", "", map[string]string{"AIAGENT_MAX_STEPS": "many"}},
+		{"bad env header", "", map[string]string{"AIAGENT_HEADER": "no colon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv(EnvPrefix+"CONFIG", path)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if _, err := Parse(flag.NewFlagSet("test", flag.ContinueOnError), nil); err == nil {
+				t.Error("Parse succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"max-steps":   "AIAGENT_MAX_STEPS",
+		"headless":    "AIAGENT_HEADLESS",
+		"llm-timeout": "AIAGENT_LLM_TIMEOUT",
+	}
+	for flagName, want := range tests {
+		if got := EnvName(flagName); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", flagName, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package dom
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -9,15 +10,54 @@ import (
 )
 
 type Candidate struct {
-	Selector string
-	Tag      string
-	Role     string
-	Text     string
-	Desc     string
-	BBox     string
-	Href     string
-	Selected bool
-	Region   string
+	Selector string `json:"selector"`
+	Tag      string `json:"tag,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Desc     string `json:"desc,omitempty"`
+	BBox     string `json:"bbox,omitempty"`
+	Href     string `json:"href,omitempty"`
+	Selected bool   `json:"selected,omitempty"`
+	Region   string `json:"region,omitempty"`
+	// Checked — состояние флажка, переключателя или switch; nil — элемент не отмечаемый.
+	Checked *bool `json:"checked,omitempty"`
+	// Options — пункты <select> (подписи), Value — выбранный пункт.
+	Options []string `json:"options,omitempty"`
+	Value   string   `json:"value,omitempty"`
+}
+
+// controlJS читает состояние элементов форм: отмечен ли флажок, пункты и выбранное значение списка.
+const controlJS = `(el) => {
+	const r = {};
+	if (el.tagName === 'SELECT') {
+		r.options = Array.from(el.options).slice(0, 30).map(o => (o.label || o.text || o.value).trim());
+		r.value = Array.from(el.selectedOptions).map(o => (o.label || o.text || o.value).trim()).join(', ');
+	}
+	if (el.type === 'checkbox' || el.type === 'radio') r.checked = el.checked;
+	const ac = el.getAttribute('aria-checked');
+	if (ac === 'true' || ac === 'false' || ac === 'mixed') r.checked = ac === 'true';
+	return r;
+}`
+
+type control struct {
+	Checked *bool    `json:"checked"`
+	Options []string `json:"options"`
+	Value   string   `json:"value"`
+}
+
+// isControl — у элемента есть состояние, которое стоит показать модели.
+func isControl(tag, typ, role string) bool {
+	switch {
+	case tag == "select":
+		return true
+	case tag == "input" && (typ == "checkbox" || typ == "radio"):
+		return true
+	}
+	switch role {
+	case "checkbox", "radio", "switch", "menuitemcheckbox", "menuitemradio":
+		return true
+	}
+	return false
 }
 
 // Собираем кликабельные/вводимые элементы.
@@ -37,6 +77,9 @@ func CollectCandidates(ctx context.Context, page playwright.Page, limit int) ([]
 	seen := make(map[string]struct{})
 
 	for _, e := range elems {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
 		vis, _ := e.IsVisible()
 		if !vis {
 			continue
@@ -80,9 +123,10 @@ func CollectCandidates(ctx context.Context, page playwright.Page, limit int) ([]
 		}
 
 		box, _ := e.BoundingBox()
-		bbox := ""
+		bbox, at := "", ""
 		if box != nil {
 			bbox = fmt.Sprintf("%.0f,%.0f,%.0f,%.0f", box.X, box.Y, box.Width, box.Height)
+			at = fmt.Sprintf("%.0f,%.0f", box.X+box.Width/2, box.Y+box.Height/2)
 		}
 
 		cls, _ = e.GetAttribute("class")
@@ -136,6 +180,10 @@ func CollectCandidates(ctx context.Context, page playwright.Page, limit int) ([]
 				s = awaitNth(base)
 			}
 		}
+		// Голый тег неоднозначен — даём центр элемента для click_at.
+		if s != base {
+			at = ""
+		}
 
 		s = strings.TrimSpace(s)
 		if s == "" {
@@ -151,6 +199,19 @@ func CollectCandidates(ctx context.Context, page playwright.Page, limit int) ([]
 			state = "state=selected"
 		}
 
+		var ctl control
+		if isControl(tagStr, typ, role) {
+			if v, err := e.Evaluate(controlJS); err == nil {
+				if b, err := json.Marshal(v); err == nil {
+					_ = json.Unmarshal(b, &ctl)
+				}
+			}
+		}
+		checked := ""
+		if ctl.Checked != nil {
+			checked = fmt.Sprint(*ctl.Checked)
+		}
+
 		desc := strings.TrimSpace(strings.Join([]string{
 			"tag=" + tagStr,
 			"role=" + role,
@@ -162,6 +223,10 @@ func CollectCandidates(ctx context.Context, page playwright.Page, limit int) ([]
 			ifNonEmpty("data-testid", dtid),
 			ifNonEmpty("data-test", dtest),
 			ifNonEmpty("state", state),
+			ifNonEmpty("checked", checked),
+			ifNonEmpty("options", crop(strings.Join(ctl.Options, "|"), 120)),
+			ifNonEmpty("value", crop(ctl.Value, 60)),
+			ifNonEmpty("at", at),
 		}, "; "))
 
 		out = append(out, Candidate{
@@ -173,6 +238,9 @@ func CollectCandidates(ctx context.Context, page playwright.Page, limit int) ([]
 			BBox:     bbox,
 			Href:     href,
 			Selected: selected,
+			Checked:  ctl.Checked,
+			Options:  ctl.Options,
+			Value:    ctl.Value,
 		})
 
 		if limit > 0 && len(out) >= limit {
@@ -209,9 +277,15 @@ func guessRole(tag, typ, href string) string {
 	if tag == "a" || href != "" {
 		return "link"
 	}
+	if tag == "select" {
+		return "combobox"
+	}
 	if tag == "input" || tag == "textarea" {
-		if typ == "submit" {
+		switch typ {
+		case "submit", "button", "reset":
 			return "button"
+		case "checkbox", "radio":
+			return typ
 		}
 		return "textbox"
 	}
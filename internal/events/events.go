@@ -0,0 +1,169 @@
+// Package events — шина типизированных событий цикла агента. На неё
+// подписываются консольный вывод, JSON-логи, SSE-поток HTTP API и
+// пользовательские Go-хуки.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindRunStarted  Kind = "run_started"
+	KindObservation Kind = "observation"
+	KindDecision    Kind = "decision"
+	KindRedacted    Kind = "redacted"
+	KindToolInvoked Kind = "tool_invoked"
+	KindToolFailed  Kind = "tool_failed"
+	KindUserAsked   Kind = "user_asked"
+	KindProgress    Kind = "progress"
+	KindFallback    Kind = "fallback"
+	KindControl     Kind = "control"
+	KindWorkflow    Kind = "workflow_step"
+	KindRunFinished Kind = "run_finished"
+)
+
+// Event — конверт события; Data содержит одну из структур ниже по Kind.
+type Event struct {
+	Kind Kind      `json:"kind"`
+	Run  string    `json:"run,omitempty"`
+	Step int       `json:"step,omitempty"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data,omitempty"`
+}
+
+type RunStarted struct {
+	Task    string `json:"task"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Resumed bool   `json:"resumed,omitempty"`
+}
+
+type Observation struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Candidates  int    `json:"candidates"`
+	SnapshotLen int    `json:"snapshot_len"`
+	// Tabs — число открытых вкладок.
+	Tabs int `json:"tabs,omitempty"`
+}
+
+type Decision struct {
+	Tool    string         `json:"tool"`
+	Args    map[string]any `json:"args,omitempty"`
+	Comment string         `json:"comment,omitempty"`
+}
+
+type Redacted struct {
+	Summary string `json:"summary"`
+}
+
+type ToolInvoked struct {
+	Tool   string         `json:"tool"`
+	Args   map[string]any `json:"args,omitempty"`
+	Result string         `json:"result,omitempty"`
+}
+
+type ToolFailed struct {
+	Tool  string         `json:"tool"`
+	Args  map[string]any `json:"args,omitempty"`
+	Error string         `json:"error"`
+}
+
+type UserAsked struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer,omitempty"`
+}
+
+type Progress struct {
+	PrevURL        string `json:"prev_url"`
+	URL            string `json:"url"`
+	Title          string `json:"title"`
+	URLChanged     bool   `json:"url_changed"`
+	ContentChanged bool   `json:"content_changed"`
+	NoProgress     int    `json:"no_progress"`
+}
+
+type Fallback struct {
+	Reason string `json:"reason"`
+	Tool   string `json:"tool"`
+}
+
+// Control — реакция цикла на ручное управление: paused, takeover, resumed, hint, override.
+type Control struct {
+	Action string `json:"action"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// WorkflowStep — шаг YAML-сценария: Path — номер шага («3», «4.2.1» внутри each),
+// Status — ok, failed, recovering или recovered.
+type WorkflowStep struct {
+	Path   string `json:"path"`
+	Step   string `json:"step"`
+	Status string `json:"status"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type RunFinished struct {
+	Status     string   `json:"status"`
+	Answer     string   `json:"answer,omitempty"`
+	Steps      int      `json:"steps"`
+	DurationMS int64    `json:"duration_ms"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// Handler получает события синхронно, в горутине цикла агента, поэтому
+// должен возвращаться быстро.
+type Handler func(Event)
+
+// Bus рассылает события всем подписчикам в порядке подписки. Нулевое
+// значение готово к работе, nil-шина молча игнорирует события.
+type Bus struct {
+	mu   sync.RWMutex
+	next int
+	subs []subscriber
+}
+
+type subscriber struct {
+	id int
+	h  Handler
+}
+
+func NewBus() *Bus { return &Bus{} }
+
+// Subscribe регистрирует обработчик и возвращает функцию отписки.
+func (b *Bus) Subscribe(h Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.subs = append(b.subs, subscriber{id, h})
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.subs {
+			if s.id == id {
+				b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	for _, s := range subs {
+		s.h(e)
+	}
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Console печатает события в человекочитаемом виде (формат прежнего stdout-лога).
+func Console(w io.Writer) Handler {
+	var mu sync.Mutex
+	return func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch d := e.Data.(type) {
+		case RunStarted:
+			fmt.Fprintln(w, "\n[agent] Задача:", d.Task)
+			if e.Run != "" {
+				verb := "запуск"
+				if d.Resumed {
+					verb = "продолжение запуска"
+				}
+				fmt.Fprintf(w, "[agent] %s %s (шаг %d)\n", verb, e.Run, e.Step+1)
+			}
+			fmt.Fprintf(w, "[agent] Текущая страница: %s | %s\n", d.URL, d.Title)
+		case Redacted:
+			fmt.Fprintf(w, "[agent] скрыто перед отправкой в LLM: %s\n", d.Summary)
+		case Decision:
+			fmt.Fprintf(w, "\n[agent] Шаг %d\n", e.Step)
+			fmt.Fprintf(w, "[agent] → инструмент: %s\n", d.Tool)
+			if len(d.Args) != 0 {
+				js, _ := json.Marshal(d.Args)
+				fmt.Fprintf(w, "[agent] → аргументы: %s\n", js)
+			}
+			if s := strings.TrimSpace(d.Comment); s != "" {
+				fmt.Fprintf(w, "[agent] → комментарий: %s\n", s)
+			}
+		case ToolFailed:
+			fmt.Fprintf(w, "[agent] ⚠ ошибка инструмента: %s\n", d.Error)
+		case UserAsked:
+			if d.Answer == "" {
+				fmt.Fprintf(w, "[agent] ? вопрос пользователю: %s\n", d.Question)
+			}
+		case Progress:
+			if d.URLChanged {
+				fmt.Fprintf(w, "[agent] URL изменился: %s → %s\n", d.PrevURL, d.URL)
+			} else {
+				fmt.Fprintln(w, "[agent] URL не изменился")
+			}
+			if !d.ContentChanged {
+				fmt.Fprintln(w, "[agent] Контент почти не изменился (hash)")
+			}
+		case Fallback:
+			fmt.Fprintf(w, "[agent] %s → принудительно %s\n", d.Reason, d.Tool)
+		case Control:
+			switch d.Action {
+			case "paused":
+				fmt.Fprintln(w, "[agent] ⏸ пауза (:resume — продолжить, :step — один шаг)")
+			case "takeover":
+				fmt.Fprintln(w, "[agent] ⏸ браузер передан вам (:handback — вернуть управление)")
+			case "resumed":
+				fmt.Fprintln(w, "[agent] ▶ продолжаю")
+			case "hint":
+				fmt.Fprintf(w, "[agent] подсказка оператора: %s\n", d.Detail)
+			case "override":
+				fmt.Fprintf(w, "[agent] ручное действие вместо планировщика: %s\n", d.Detail)
+			}
+		case WorkflowStep:
+			switch d.Status {
+			case "ok":
+				fmt.Fprintf(w, "[workflow] %s %s: ok\n", d.Path, d.Step)
+			case "recovering":
+				fmt.Fprintf(w, "[workflow] %s %s: ⚠ %s → восстановление через планировщик\n", d.Path, d.Step, d.Error)
+			case "recovered":
+				fmt.Fprintf(w, "[workflow] %s %s: восстановлено, продолжаю сценарий\n", d.Path, d.Step)
+			default:
+				fmt.Fprintf(w, "[workflow] %s %s: ⚠ %s\n", d.Path, d.Step, d.Error)
+			}
+		case RunFinished:
+			switch {
+			case d.Status == "needs_input" && d.Answer != "":
+				fmt.Fprintln(w, "\n[agent] Вопрос пользователю:")
+				fmt.Fprintln(w, d.Answer)
+			case d.Answer != "":
+				fmt.Fprintln(w, "\n[agent] Ответ/уточнение:")
+				fmt.Fprintln(w, d.Answer)
+			}
+		}
+	}
+}
+
+// JSONLines пишет каждое событие отдельной JSON-строкой.
+func JSONLines(w io.Writer) Handler {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(e)
+	}
+}
@@ -0,0 +1,394 @@
+// Package export превращает успешную траекторию агента в самостоятельную
+// программу (или тест) на playwright-go, чтобы найденный сценарий можно было
+// запускать детерминированно, без LLM.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"AIAgent/internal/dom"
+	"AIAgent/internal/redact"
+	"AIAgent/internal/trajectory"
+)
+
+// Options — параметры генерации.
+type Options struct {
+	// Test — сгенерировать _test.go с функцией TestXxx вместо программы с main.
+	Test bool
+	// Package — имя пакета (по умолчанию main или flow_test для теста).
+	Package string
+	// Name — суффикс имени теста, TestName.
+	Name string
+	// Headless — запускать браузер без окна.
+	Headless bool
+	// Force — экспортировать и незавершённую траекторию.
+	Force bool
+	// Warn получает предупреждения о шагах, которые перенесены не полностью
+	// (nil — не сообщать).
+	Warn func(string)
+}
+
+// ErrNotSuccessful возвращается для траекторий, которые не завершились успехом.
+var ErrNotSuccessful = fmt.Errorf("export: trajectory did not finish successfully")
+
+// GoScript генерирует исходный код. Экспортируются только успешно выполненные
+// click, type, goto_url, press и scroll; остальные шаги попадают в комментарии.
+func GoScript(t *trajectory.Trajectory, opts Options) ([]byte, error) {
+	if t.Meta.Status != "done" && !opts.Force {
+		return nil, ErrNotSuccessful
+	}
+	if opts.Package == "" {
+		opts.Package = "main"
+		if opts.Test {
+			opts.Package = "flow_test"
+		}
+	}
+	if opts.Name == "" {
+		opts.Name = "Flow"
+	}
+
+	var body strings.Builder
+	if u := t.Meta.StartURL; u != "" && u != "about:blank" {
+		fmt.Fprintf(&body, "// Стартовая страница записи.\n")
+		writeGoto(&body, 0, u)
+	}
+	for _, st := range t.Steps {
+		if st.Error != "" {
+			fmt.Fprintf(&body, "\n// Шаг %d: %s пропущен — в записи завершился ошибкой: %s\n", st.Step, st.Action.Tool, oneLine(st.Error))
+			continue
+		}
+		code := stepCode(st)
+		if code == "" {
+			fmt.Fprintf(&body, "\n// Шаг %d: %s не переносится в скрипт.\n", st.Step, st.Action.Tool)
+			continue
+		}
+		if toks := redact.Tokens(code); len(toks) > 0 {
+			// Оригиналы токенов в траекторию не пишутся: шаг остаётся в скрипте
+			// закомментированным, чтобы его дописали руками.
+			fmt.Fprintf(&body, "\n// Шаг %d: %s\n// TODO: redacted value — в записи %s вместо настоящих значений; подставьте их и раскомментируйте шаг.\n",
+				st.Step, st.Action.Tool, strings.Join(toks, ", "))
+			for _, line := range strings.Split(strings.TrimSuffix(code, "\n"), "\n") {
+				body.WriteString("// " + line + "\n")
+			}
+			if opts.Warn != nil {
+				opts.Warn(fmt.Sprintf("step %d: %s uses redacted %s, left commented out", st.Step, st.Action.Tool, strings.Join(toks, ", ")))
+			}
+			continue
+		}
+		fmt.Fprintf(&body, "\n// Шаг %d: %s", st.Step, st.Action.Tool)
+		if c := strings.TrimSpace(st.Action.Comment); c != "" {
+			fmt.Fprintf(&body, " — %s", oneLine(c))
+		}
+		body.WriteString("\n")
+		body.WriteString(code)
+		// Если действие привело к переходу, дожидаемся новой страницы, а не гоняемся с ней.
+		if st.After.URL != "" && !sameDoc(st.Before.URL, st.After.URL) && st.Action.Tool != "goto_url" {
+			fmt.Fprintf(&body, "if err := page.WaitForURL(%s); err != nil {\n\treturn fmt.Errorf(\"step %d: wait for navigation: %%w\", err)\n}\n",
+				strconv.Quote(urlPattern(st.After.URL)), st.Step)
+		}
+		fmt.Fprintf(&body, "waitSettled(page)\n")
+	}
+
+	var out bytes.Buffer
+	err := scriptTmpl.Execute(&out, map[string]any{
+		"Opts": opts,
+		"Task": oneLine(t.Meta.Task),
+		"Run":  t.Meta.RunID,
+		"Body": body.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("export: generated code does not format: %w", err)
+	}
+	return src, nil
+}
+
+func stepCode(st trajectory.Step) string {
+	var b strings.Builder
+	a := st.Action
+	switch a.Tool {
+	case "goto_url":
+		u, _ := a.Args["url"].(string)
+		if u == "" {
+			return ""
+		}
+		writeGoto(&b, st.Step, u)
+	case "click":
+		loc := locatorExpr(st, str(a.Args, "selector"))
+		if loc == "" {
+			return ""
+		}
+		fmt.Fprintf(&b, "if err := %s.Click(); err != nil {\n\treturn fmt.Errorf(\"step %d: click: %%w\", err)\n}\n", loc, st.Step)
+	case "type":
+		loc := locatorExpr(st, str(a.Args, "selector"))
+		if loc == "" {
+			return ""
+		}
+		fmt.Fprintf(&b, "if err := %s.Fill(%s); err != nil {\n\treturn fmt.Errorf(\"step %d: fill: %%w\", err)\n}\n", loc, strconv.Quote(str(a.Args, "text")), st.Step)
+		if enter, _ := a.Args["pressEnter"].(bool); enter {
+			fmt.Fprintf(&b, "if err := %s.Press(\"Enter\"); err != nil {\n\treturn fmt.Errorf(\"step %d: press Enter: %%w\", err)\n}\n", loc, st.Step)
+		}
+	case "press":
+		key := str(a.Args, "key")
+		if key == "" {
+			key = "Escape"
+		}
+		fmt.Fprintf(&b, "if err := page.Keyboard().Press(%s); err != nil {\n\treturn fmt.Errorf(\"step %d: press: %%w\", err)\n}\n", strconv.Quote(key), st.Step)
+	case "scroll":
+		if sel := str(a.Args, "selector"); sel != "" {
+			fmt.Fprintf(&b, "if err := page.Locator(%s).First().ScrollIntoViewIfNeeded(); err != nil {\n\treturn fmt.Errorf(\"step %d: scroll: %%w\", err)\n}\n", strconv.Quote(sel), st.Step)
+		} else {
+			dy := 600.0
+			if v, ok := a.Args["y"].(float64); ok && v != 0 {
+				dy = v
+			}
+			fmt.Fprintf(&b, "if _, err := page.Evaluate(`(dy)=>{window.scrollBy(0,dy)}`, %v); err != nil {\n\treturn fmt.Errorf(\"step %d: scroll: %%w\", err)\n}\n", dy, st.Step)
+		}
+	case "check", "uncheck", "hover", "dblclick":
+		loc := locatorExpr(st, str(a.Args, "selector"))
+		if loc == "" {
+			return ""
+		}
+		method := map[string]string{"check": "Check", "uncheck": "Uncheck", "hover": "Hover", "dblclick": "Dblclick"}[a.Tool]
+		fmt.Fprintf(&b, "if err := %s.%s(); err != nil {\n\treturn fmt.Errorf(\"step %d: %s: %%w\", err)\n}\n", loc, method, st.Step, a.Tool)
+	case "right_click":
+		loc := locatorExpr(st, str(a.Args, "selector"))
+		if loc == "" {
+			return ""
+		}
+		fmt.Fprintf(&b, "if err := %s.Click(playwright.LocatorClickOptions{Button: playwright.MouseButtonRight}); err != nil {\n\treturn fmt.Errorf(\"step %d: right click: %%w\", err)\n}\n", loc, st.Step)
+	case "drag":
+		src, dst := locatorExpr(st, str(a.Args, "selector")), locatorExpr(st, str(a.Args, "target"))
+		if src == "" || dst == "" {
+			return ""
+		}
+		fmt.Fprintf(&b, "if err := %s.DragTo(%s); err != nil {\n\treturn fmt.Errorf(\"step %d: drag: %%w\", err)\n}\n", src, dst, st.Step)
+	case "select_option":
+		sel := str(a.Args, "selector")
+		opt := str(a.Args, "option")
+		if opt == "" {
+			opt = str(a.Args, "value") + str(a.Args, "label")
+		}
+		if c, ok := findCandidate(st.Candidates, sel); !ok || c.Tag != "select" || opt == "" {
+			// Комбобоксы без <select> и списки из нескольких пунктов не экспортируются.
+			return ""
+		}
+		fmt.Fprintf(&b, "if _, err := %s.SelectOption(playwright.SelectOptionValues{ValuesOrLabels: &[]string{%s}}); err != nil {\n\treturn fmt.Errorf(\"step %d: select: %%w\", err)\n}\n",
+			locatorExpr(st, sel), strconv.Quote(opt), st.Step)
+	case "click_at", "move_mouse":
+		x, okx := a.Args["x"].(float64)
+		y, oky := a.Args["y"].(float64)
+		if !okx || !oky {
+			return ""
+		}
+		method := map[string]string{"click_at": "Click", "move_mouse": "Move"}[a.Tool]
+		fmt.Fprintf(&b, "if err := page.Mouse().%s(%v, %v); err != nil {\n\treturn fmt.Errorf(\"step %d: %s: %%w\", err)\n}\n", method, x, y, st.Step, a.Tool)
+	case "type_keys":
+		if text := str(a.Args, "text"); text != "" {
+			fmt.Fprintf(&b, "if err := page.Keyboard().Type(%s); err != nil {\n\treturn fmt.Errorf(\"step %d: type keys: %%w\", err)\n}\n", strconv.Quote(text), st.Step)
+		}
+		var keys []string
+		switch v := a.Args["keys"].(type) {
+		case string:
+			keys = []string{v}
+		case []any:
+			for _, k := range v {
+				keys = append(keys, fmt.Sprint(k))
+			}
+		}
+		for _, k := range keys {
+			fmt.Fprintf(&b, "if err := page.Keyboard().Press(%s); err != nil {\n\treturn fmt.Errorf(\"step %d: press: %%w\", err)\n}\n", strconv.Quote(k), st.Step)
+		}
+	case "go_back", "go_forward", "reload":
+		method := map[string]string{"go_back": "GoBack", "go_forward": "GoForward", "reload": "Reload"}[a.Tool]
+		n := 1
+		if v, ok := a.Args["steps"].(float64); ok && v > 1 && a.Tool != "reload" {
+			n = int(v)
+		}
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "if _, err := page.%s(); err != nil {\n\treturn fmt.Errorf(\"step %d: %s: %%w\", err)\n}\n", method, st.Step, a.Tool)
+		}
+	case "wait_for":
+		method, ok := map[string]string{"": "Visible", "visible": "Visible", "hidden": "Hidden", "attached": "Attached", "detached": "Detached"}[strings.ToLower(str(a.Args, "state"))]
+		if !ok {
+			return ""
+		}
+		var loc string
+		switch {
+		case str(a.Args, "selector") != "":
+			loc = locatorExpr(st, str(a.Args, "selector"))
+		case str(a.Args, "text") != "":
+			loc = fmt.Sprintf("page.GetByText(%s).First()", strconv.Quote(str(a.Args, "text")))
+		case str(a.Args, "url_pattern") != "":
+			p := str(a.Args, "url_pattern")
+			if strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+				// Регулярные выражения JS и Go различаются — не переносим.
+				return ""
+			}
+			if !strings.Contains(p, "*") {
+				p = "**" + p + "**"
+			}
+			fmt.Fprintf(&b, "if err := page.WaitForURL(%s); err != nil {\n\treturn fmt.Errorf(\"step %d: wait for url: %%w\", err)\n}\n", strconv.Quote(p), st.Step)
+			return b.String()
+		default:
+			// Ожидание успокоения страницы и так есть после каждого шага.
+			return ""
+		}
+		fmt.Fprintf(&b, "if err := %s.WaitFor(playwright.LocatorWaitForOptions{State: playwright.WaitForSelectorState%s}); err != nil {\n\treturn fmt.Errorf(\"step %d: wait for: %%w\", err)\n}\n",
+			loc, method, st.Step)
+	default:
+		return ""
+	}
+	return b.String()
+}
+
+func writeGoto(b *strings.Builder, step int, u string) {
+	fmt.Fprintf(b, "if _, err := page.Goto(%s, playwright.PageGotoOptions{WaitUntil: playwright.WaitUntilStateDomcontentloaded}); err != nil {\n\treturn fmt.Errorf(\"step %d: goto: %%w\", err)\n}\n", strconv.Quote(u), step)
+}
+
+// locatorExpr строит локатор: по роли и доступному имени, если кандидат
+// в записи это позволяет (устойчиво к перестройке DOM), иначе по CSS-селектору.
+func locatorExpr(st trajectory.Step, selector string) string {
+	if selector == "" {
+		return ""
+	}
+	if c, ok := findCandidate(st.Candidates, selector); ok {
+		// Селекторы кандидатов записываются как есть, а аргументы действия — после редактора.
+		selector = c.Selector
+		name := strings.TrimSuffix(strings.TrimSpace(c.Text), "…")
+		// Текст <select> — подписи всех пунктов, а не его доступное имя.
+		if c.Role != "" && c.Tag != "select" && name != "" && !strings.Contains(name, "\n") && len([]rune(name)) <= 60 &&
+			len(redact.Tokens(name)) == 0 && !strings.HasPrefix(selector, "[id=") && !strings.HasPrefix(selector, "[data-") {
+			return fmt.Sprintf("page.GetByRole(playwright.AriaRole(%s), playwright.PageGetByRoleOptions{Name: %s}).First()",
+				strconv.Quote(c.Role), strconv.Quote(name))
+		}
+	}
+	return fmt.Sprintf("page.Locator(%s).First()", strconv.Quote(selector))
+}
+
+// findCandidate ищет кандидата по селектору; токены редактора в selector
+// совпадают с любым значением.
+func findCandidate(cands []dom.Candidate, selector string) (dom.Candidate, bool) {
+	for _, c := range cands {
+		if c.Selector == selector {
+			return c, true
+		}
+	}
+	if len(redact.Tokens(selector)) > 0 {
+		for _, c := range cands {
+			if redact.Match(selector, c.Selector) {
+				return c, true
+			}
+		}
+	}
+	return dom.Candidate{}, false
+}
+
+// urlPattern — glob для WaitForURL: путь без запроса и фрагмента, которые часто
+// содержат идентификаторы сессии. Токены редактора в пути заменяются на *.
+func urlPattern(u string) string {
+	for _, tok := range redact.Tokens(u) {
+		u = strings.ReplaceAll(u, tok, "*")
+	}
+	pu, err := url.Parse(u)
+	if err != nil || pu.Host == "" {
+		return u
+	}
+	return pu.Scheme + "://" + pu.Host + pu.Path + "**"
+}
+
+// sameDoc — адреса совпадают с точностью до фрагмента.
+func sameDoc(a, b string) bool {
+	cut := func(s string) string {
+		if i := strings.IndexByte(s, '#'); i >= 0 {
+			s = s[:i]
+		}
+		return strings.TrimSuffix(s, "/")
+	}
+	return cut(a) == cut(b)
+}
+
+func str(args map[string]any, k string) string {
+	s, _ := args[k].(string)
+	return s
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var scriptTmpl = template.Must(template.New("script").Parse(`// Code generated by agent export from run {{.Run}}. Review before use.
+//
+// Задача: {{.Task}}
+
+package {{.Opts.Package}}
+
+import (
+	"fmt"
+{{- if not .Opts.Test}}
+	"log"
+{{- end}}
+{{- if .Opts.Test}}
+	"testing"
+{{- end}}
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+{{if .Opts.Test -}}
+func Test{{.Opts.Name}}(t *testing.T) {
+	if err := runFlow(); err != nil {
+		t.Fatal(err)
+	}
+}
+{{- else -}}
+func main() {
+	if err := runFlow(); err != nil {
+		log.Fatal(err)
+	}
+}
+{{- end}}
+
+func runFlow() error {
+	pw, err := playwright.Run()
+	if err != nil {
+		return err
+	}
+	defer pw.Stop()
+
+	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{Headless: playwright.Bool({{.Opts.Headless}})})
+	if err != nil {
+		return err
+	}
+	defer browser.Close()
+
+	page, err := browser.NewPage()
+	if err != nil {
+		return err
+	}
+	page.SetDefaultTimeout(15000)
+
+	return flow(page)
+}
+
+// waitSettled даёт странице догрузиться после действия.
+func waitSettled(page playwright.Page) {
+	_ = page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{State: playwright.LoadStateDomcontentloaded})
+	time.Sleep(300 * time.Millisecond)
+}
+
+func flow(page playwright.Page) error {
+{{.Body}}
+	return nil
+}
+`))
@@ -0,0 +1,168 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"AIAgent/internal/dom"
+	"AIAgent/internal/trajectory"
+)
+
+var update = flag.Bool("update", false, "перезаписать эталоны в testdata")
+
+// sample — запись входа в почту: переход по роли, ввод, навигация,
+// пропущенный шаг с ошибкой и шаг, который не переносится в скрипт.
+func sample() *trajectory.Trajectory {
+	inbox := "https://mail.example/inbox?sid=42"
+	return &trajectory.Trajectory{
+		Meta: trajectory.Meta{RunID: "20261018-150405-1a2b", Task: "открыть\nпервое письмо", StartURL: "https://mail.example/login", Status: "done"},
+		Steps: []trajectory.Step{
+			{
+				Step:   1,
+				Before: trajectory.PageState{URL: "https://mail.example/login"},
+				After:  trajectory.PageState{URL: "https://mail.example/login"},
+				Action: trajectory.Action{Tool: "type", Args: map[string]any{"selector": "#login", "text": "ivan", "pressEnter": true}, Comment: "вводим логин"},
+			},
+			{
+				Step:       2,
+				Before:     trajectory.PageState{URL: "https://mail.example/login"},
+				After:      trajectory.PageState{URL: inbox},
+				Candidates: []dom.Candidate{{Selector: "button.submit", Tag: "button", Role: "button", Text: "Войти"}},
+				Action:     trajectory.Action{Tool: "click", Args: map[string]any{"selector": "button.submit"}},
+			},
+			{
+				Step:   3,
+				Action: trajectory.Action{Tool: "click", Args: map[string]any{"selector": "#missing"}},
+				Error:  "timeout\n30s",
+			},
+			{
+				Step:   3,
+				Before: trajectory.PageState{URL: inbox},
+				After:  trajectory.PageState{URL: inbox + "#top"},
+				Action: trajectory.Action{Tool: "scroll", Args: map[string]any{"y": 300.0}},
+			},
+			{
+				Step:   4,
+				Action: trajectory.Action{Tool: "extract"},
+			},
+			{
+				Step:   5,
+				Before: trajectory.PageState{URL: inbox},
+				After:  trajectory.PageState{URL: inbox},
+				Action: trajectory.Action{Tool: "select_option", Args: map[string]any{"selector": "#folder", "option": "Спам"}},
+				Candidates: []dom.Candidate{
+					{Selector: "#folder", Tag: "select", Role: "combobox", Text: "Входящие\nСпам"},
+				},
+			},
+		},
+	}
+}
+
+func TestGoScriptGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		golden string
+	}{
+		{"program", Options{}, "flow.go.golden"},
+		{"test", Options{Test: true, Name: "Login", Headless: true}, "flow_test.go.golden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GoScript(sample(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("GoScript output differs from %s (go test -run TestGoScriptGolden -update):\n%s", path, got)
+			}
+		})
+	}
+}
+
+func TestGoScriptNotSuccessful(t *testing.T) {
+	tr := sample()
+	tr.Meta.Status = "failed"
+	if _, err := GoScript(tr, Options{}); !errors.Is(err, ErrNotSuccessful) {
+		t.Errorf("err = %v, want ErrNotSuccessful", err)
+	}
+	if _, err := GoScript(tr, Options{Force: true}); err != nil {
+		t.Errorf("Force: %v", err)
+	}
+}
+
+func TestGoScriptRedacted(t *testing.T) {
+	inbox := "https://mail.example/inbox"
+	msg := "https://mail.example/message/79123456789"
+	tr := &trajectory.Trajectory{
+		Meta: trajectory.Meta{RunID: "r1", Status: "done"},
+		Steps: []trajectory.Step{
+			{
+				Step:   1,
+				Action: trajectory.Action{Tool: "type", Args: map[string]any{"selector": "#to", "text": "[EMAIL_1]"}},
+			},
+			{
+				// Селектор в аргументах скрыт, у кандидата — настоящий.
+				Step:       2,
+				Before:     trajectory.PageState{URL: inbox},
+				After:      trajectory.PageState{URL: "https://mail.example/message/[PHONE_1]"},
+				Candidates: []dom.Candidate{{Selector: `[data-id="79123456789"]`, Tag: "a", Role: "link", Text: "[EMAIL_2]"}},
+				Action:     trajectory.Action{Tool: "click", Args: map[string]any{"selector": `[data-id="[PHONE_1]"]`}},
+			},
+			{
+				Step:   3,
+				Before: trajectory.PageState{URL: msg},
+				After:  trajectory.PageState{URL: "https://mail.example/u/[PHONE_2]"},
+				Action: trajectory.Action{Tool: "goto_url", Args: map[string]any{"url": "https://mail.example/u/[PHONE_2]"}},
+			},
+		},
+	}
+	var warnings []string
+	src, err := GoScript(tr, Options{Warn: func(s string) { warnings = append(warnings, s) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "_1]") || strings.Contains(line, "_2]") {
+			if l := strings.TrimSpace(line); !strings.HasPrefix(l, "//") {
+				t.Errorf("token outside a comment: %q", line)
+			}
+		}
+	}
+	for _, want := range []string{
+		"// TODO: redacted value — в записи [EMAIL_1]",
+		`// if err := page.Locator("#to").First().Fill("[EMAIL_1]"); err != nil {`,
+		// Кандидат найден по шаблону: локатор по настоящему селектору, а не по скрытому имени.
+		`if err := page.Locator("[data-id=\"79123456789\"]").First().Click(); err != nil {`,
+		`if err := page.WaitForURL("https://mail.example/message/***"); err != nil {`,
+		"// TODO: redacted value — в записи [PHONE_2]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	wantWarn := []string{
+		"step 1: type uses redacted [EMAIL_1], left commented out",
+		"step 3: goto_url uses redacted [PHONE_2], left commented out",
+	}
+	if !reflect.DeepEqual(warnings, wantWarn) {
+		t.Errorf("warnings = %q, want %q", warnings, wantWarn)
+	}
+}
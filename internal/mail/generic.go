@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Generic — любой другой веб-клиент. Тип страницы и список писем
+// определяются эвристически по ролям ARIA; действия не поддерживаются и
+// остаются планировщику.
+var Generic Adapter = generic{}
+
+type generic struct{}
+
+const genericRows = `main [role=row], main [role=listitem], [role=main] [role=row], [role=grid] [role=row]`
+
+func (generic) Name() string                          { return "generic" }
+func (generic) Match(*url.URL) bool                   { return true }
+func (generic) InboxURL() string                      { return "" }
+func (generic) Read(playwright.Page) (Content, error) { return Content{}, ErrUnsupported }
+
+func (generic) Detect(page playwright.Page) (Status, error) {
+	st := Status{Client: "generic", Page: PageUnknown}
+	low := strings.ToLower(page.URL())
+	switch {
+	case strings.Contains(low, "login") || strings.Contains(low, "signin") || strings.Contains(low, "auth"):
+		st.Page = PageLogin
+	case strings.Contains(low, "compose"):
+		st.Page = PageCompose
+	default:
+		if n, _ := page.Locator(genericRows).Count(); n > 1 {
+			st.Page = PageMessageList
+		}
+	}
+	return st, nil
+}
+
+func (generic) List(page playwright.Page, limit int) ([]Message, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	texts, err := page.Locator(genericRows).AllInnerTexts()
+	if err != nil {
+		return nil, err
+	}
+	var out []Message
+	for _, t := range texts {
+		lines := strings.FieldsFunc(t, func(r rune) bool { return r == '\n' || r == '\t' })
+		if len(lines) == 0 {
+			continue
+		}
+		m := Message{Index: len(out) + 1, From: strings.TrimSpace(lines[0])}
+		if len(lines) > 1 {
+			m.Subject = strings.TrimSpace(lines[1])
+		}
+		if len(lines) > 2 {
+			m.Snippet = strings.TrimSpace(strings.Join(lines[2:], " "))
+		}
+		out = append(out, m)
+		if len(out) == limit {
+			break
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("mail: generic: no list rows found: %w", ErrUnsupported)
+	}
+	return out, nil
+}
+
+func (generic) Open(page playwright.Page, ref string) error {
+	var n int
+	if _, err := fmt.Sscan(strings.TrimPrefix(ref, "#"), &n); err != nil || n < 1 {
+		return ErrUnsupported
+	}
+	return page.Locator(genericRows).Nth(n - 1).Click()
+}
+
+func (generic) OpenFolder(page playwright.Page, folder string) error {
+	return page.Locator(fmt.Sprintf("role=link[name=%q]", folder)).First().Click()
+}
+
+func (generic) Delete(playwright.Page) error       { return ErrUnsupported }
+func (generic) Move(playwright.Page, string) error { return ErrUnsupported }
+func (generic) MarkSpam(playwright.Page) error     { return ErrUnsupported }
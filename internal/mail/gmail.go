@@ -0,0 +1,69 @@
+package mail
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Gmail — веб-интерфейс Gmail (mail.google.com).
+var Gmail Adapter = &site{
+	name:  "gmail",
+	hosts: []string{"mail.google."},
+	login: []string{"accounts.google."},
+	base:  "https://mail.google.com/mail/u/0/",
+	folders: map[string]string{
+		"inbox":  "#inbox",
+		"spam":   "#spam",
+		"trash":  "#trash",
+		"sent":   "#sent",
+		"drafts": "#drafts",
+	},
+	kind: func(u *url.URL) (PageKind, string) {
+		f := u.Fragment
+		if strings.Contains(f, "compose=") {
+			return PageCompose, ""
+		}
+		if strings.HasPrefix(f, "settings") {
+			if strings.HasPrefix(f, "settings/labels") {
+				return PageFolderList, ""
+			}
+			return PageUnknown, ""
+		}
+		if f == "" {
+			return PageMessageList, "inbox"
+		}
+		// #inbox/<id>, но #label/<имя> и #search/<запрос> — списки, а письмо в них — на уровень глубже.
+		folder, rest, _ := strings.Cut(f, "/")
+		if folder == "label" || folder == "search" {
+			if strings.Contains(rest, "/") {
+				return PageMessage, ""
+			}
+			return PageMessageList, f
+		}
+		if rest != "" {
+			return PageMessage, ""
+		}
+		return PageMessageList, folder
+	},
+	messageURL: func(id string) string { return "#all/" + id },
+	list: listSelectors{
+		Rows:     "tr.zA",
+		From:     ".yX [email]",
+		FromAttr: "email",
+		Subject:  ".bog",
+		Snippet:  ".y2",
+		Date:     ".xW span",
+		Unread:   ".zE",
+		IDAttr:   "data-legacy-thread-id",
+	},
+	msg: messageSelectors{
+		Subject:  "h2.hP",
+		From:     ".gD",
+		FromAttr: "email",
+		Date:     ".g3",
+		Body:     ".a3s",
+	},
+	deleteBtn: []string{`[act="10"]`, `role=button[name="Delete"]`, `role=button[name="Удалить"]`},
+	spamBtn:   []string{`[act="9"]`, `role=button[name="Report spam"]`, `role=button[name="В спам!"]`},
+	moveBtn:   []string{`role=button[name="Move to"]`, `role=button[name="Переместить в"]`},
+}
@@ -0,0 +1,167 @@
+// Package mail — адаптеры веб-почты: определяют тип страницы, читают список
+// писем и открытое письмо, открывают папки, удаляют, перемещают и помечают
+// письма как спам. Агент вызывает их через инструменты mail_*, вместо того
+// чтобы искать нужные элементы на каждом шаге.
+//
+// Для Яндекс Почты и Gmail есть адаптеры с известной разметкой. На остальных
+// сайтах работает Generic: он умеет только определять тип страницы и грубо
+// читать список, а действия возвращают ErrUnsupported — тогда планировщик
+// выполняет их обычными инструментами click/type.
+package mail
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// PageKind — тип страницы почтового клиента.
+type PageKind string
+
+const (
+	PageUnknown     PageKind = "unknown"
+	PageLogin       PageKind = "login"
+	PageFolderList  PageKind = "folder_list"
+	PageMessageList PageKind = "message_list"
+	PageMessage     PageKind = "message"
+	PageCompose     PageKind = "compose"
+)
+
+// Message — строка списка писем.
+type Message struct {
+	// Index — номер строки в текущем списке, с 1.
+	Index int `json:"index"`
+	// ID — идентификатор письма у почтового сервиса, если его удалось извлечь.
+	ID       string `json:"id,omitempty"`
+	From     string `json:"from"`
+	FromAddr string `json:"from_addr,omitempty"`
+	Subject  string `json:"subject"`
+	Snippet  string `json:"snippet,omitempty"`
+	Date     string `json:"date,omitempty"`
+	Unread   bool   `json:"unread,omitempty"`
+}
+
+// Content — открытое письмо.
+type Content struct {
+	ID       string `json:"id,omitempty"`
+	From     string `json:"from"`
+	FromAddr string `json:"from_addr,omitempty"`
+	Subject  string `json:"subject"`
+	Date     string `json:"date,omitempty"`
+	Body     string `json:"body"`
+}
+
+// Status — где находится пользователь.
+type Status struct {
+	Client string   `json:"client"`
+	Page   PageKind `json:"page"`
+	Folder string   `json:"folder,omitempty"`
+}
+
+// Adapter — операции с веб-клиентом почты на открытой странице.
+type Adapter interface {
+	Name() string
+	// Match — страница с этим адресом принадлежит клиенту.
+	Match(u *url.URL) bool
+	InboxURL() string
+	Detect(page playwright.Page) (Status, error)
+	List(page playwright.Page, limit int) ([]Message, error)
+	// Open открывает письмо по ID или номеру строки из List.
+	Open(page playwright.Page, ref string) error
+	Read(page playwright.Page) (Content, error)
+	OpenFolder(page playwright.Page, folder string) error
+	// Delete, Move и MarkSpam действуют на открытое или выделенное письмо.
+	Delete(page playwright.Page) error
+	Move(page playwright.Page, folder string) error
+	MarkSpam(page playwright.Page) error
+}
+
+// ErrUnsupported — адаптер не умеет это действие; его нужно выполнить через UI.
+var ErrUnsupported = errors.New("mail: operation is not supported by this client adapter, use UI tools")
+
+// Adapters — известные клиенты в порядке проверки.
+var Adapters = []Adapter{Yandex, Gmail}
+
+// For возвращает адаптер для адреса страницы; для незнакомых сайтов — Generic.
+func For(rawURL string) Adapter {
+	u, err := url.Parse(rawURL)
+	if err == nil {
+		for _, a := range Adapters {
+			if a.Match(u) {
+				return a
+			}
+		}
+	}
+	return Generic
+}
+
+// ForTask подбирает клиент по тексту задачи (упоминание сервиса); по умолчанию Яндекс.
+func ForTask(task string) Adapter {
+	t := strings.ToLower(task)
+	if strings.Contains(t, "gmail") || strings.Contains(t, "google") {
+		return Gmail
+	}
+	return Yandex
+}
+
+// KindOf — тип страницы только по адресу, без обращения к DOM.
+func KindOf(rawURL string) (string, PageKind) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Generic.Name(), PageUnknown
+	}
+	for _, a := range Adapters {
+		if s, ok := a.(*site); ok && a.Match(u) {
+			if s.isLogin(u) {
+				return s.name, PageLogin
+			}
+			kind, _ := s.kind(u)
+			return s.name, kind
+		}
+	}
+	return Generic.Name(), PageUnknown
+}
+
+// Folder приводит название папки к каноническому имени: inbox, spam, trash,
+// sent, drafts. Незнакомое название возвращается как есть.
+func Folder(name string) string {
+	n := strings.ToLower(strings.TrimSpace(name))
+	switch n {
+	case "inbox", "входящие":
+		return "inbox"
+	case "spam", "junk", "спам":
+		return "spam"
+	case "trash", "deleted", "bin", "удалённые", "удаленные", "корзина":
+		return "trash"
+	case "sent", "отправленные":
+		return "sent"
+	case "drafts", "draft", "черновики":
+		return "drafts"
+	}
+	return name
+}
+
+// decode перекладывает результат page.Evaluate в структуру через JSON.
+func decode(v any, out any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// clickFirst нажимает первый видимый элемент из альтернативных селекторов.
+func clickFirst(page playwright.Page, what string, selectors ...string) error {
+	for _, sel := range selectors {
+		loc := page.Locator(sel).First()
+		if vis, _ := loc.IsVisible(); !vis {
+			continue
+		}
+		return loc.Click()
+	}
+	return fmt.Errorf("mail: %s: control not found", what)
+}
@@ -0,0 +1,40 @@
+package mail
+
+import "testing"
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		url    string
+		client string
+		kind   PageKind
+	}{
+		{"https://mail.yandex.ru/", "yandex", PageMessageList},
+		{"https://mail.yandex.ru/#inbox", "yandex", PageMessageList},
+		{"https://mail.yandex.com/#spam", "yandex", PageMessageList},
+		{"https://mail.yandex.ru/#message/189231004283912", "yandex", PageMessage},
+		{"https://mail.yandex.ru/#compose", "yandex", PageCompose},
+		{"https://mail.yandex.ru/#setup/folders", "yandex", PageFolderList},
+		{"https://mail.yandex.ru/#setup/other", "yandex", PageUnknown},
+		{"https://passport.yandex.ru/auth?retpath=https%3A%2F%2Fmail.yandex.ru", "yandex", PageLogin},
+		{"https://mail.google.com/mail/u/0/", "gmail", PageMessageList},
+		{"https://mail.google.com/mail/u/0/#inbox/FMfcgzQXJ", "gmail", PageMessage},
+		{"https://mail.google.com/mail/u/0/#label/work", "gmail", PageMessageList},
+		{"https://mail.google.com/mail/u/0/#label/work/FMfcgzQXJ", "gmail", PageMessage},
+		{"https://mail.google.com/mail/u/0/#inbox?compose=new", "gmail", PageCompose},
+		{"https://mail.google.com/mail/u/0/#settings/labels", "gmail", PageFolderList},
+		{"https://accounts.google.com/signin", "gmail", PageLogin},
+		// Для Mail.ru отдельного адаптера нет — работает Generic.
+		{"https://e.mail.ru/inbox/", "generic", PageUnknown},
+		{"https://example.com/mail/#inbox", "generic", PageUnknown},
+		{"://bad", "generic", PageUnknown},
+	}
+	for _, tt := range tests {
+		client, kind := KindOf(tt.url)
+		if client != tt.client || kind != tt.kind {
+			t.Errorf("KindOf(%q) = %s, %s, want %s, %s", tt.url, client, kind, tt.client, tt.kind)
+		}
+		if got := For(tt.url).Name(); got != tt.client {
+			t.Errorf("For(%q) = %s, want %s", tt.url, got, tt.client)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package mail
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// site — адаптер клиента с известной разметкой; Яндекс и Gmail отличаются
+// только селекторами и схемой адресов.
+type site struct {
+	name  string
+	hosts []string
+	login []string
+	base  string
+	// folders — канонические папки → фрагмент адреса (#inbox).
+	folders map[string]string
+	// kind определяет тип страницы и папку по адресу.
+	kind func(u *url.URL) (PageKind, string)
+	// messageURL — адрес письма по ID ("" — открывать кликом по строке).
+	messageURL func(id string) string
+
+	list listSelectors
+	msg  messageSelectors
+
+	deleteBtn []string
+	spamBtn   []string
+	moveBtn   []string
+}
+
+// listSelectors описывает строку списка писем; поля ищутся внутри строки.
+type listSelectors struct {
+	Rows     string `json:"rows"`
+	From     string `json:"from"`
+	FromAttr string `json:"fromAttr"`
+	Subject  string `json:"subject"`
+	Snippet  string `json:"snippet"`
+	Date     string `json:"date"`
+	Unread   string `json:"unread"`
+	// IDAttr — атрибут строки (или потомка) с ID; IDHref — регулярное выражение
+	// для ссылки строки, первая группа которого — ID.
+	IDAttr string `json:"idAttr"`
+	IDHref string `json:"idHref"`
+	Limit  int    `json:"limit"`
+}
+
+type messageSelectors struct {
+	Subject  string `json:"subject"`
+	From     string `json:"from"`
+	FromAttr string `json:"fromAttr"`
+	Date     string `json:"date"`
+	Body     string `json:"body"`
+}
+
+const listJS = `(s) => Array.from(document.querySelectorAll(s.rows)).filter(r => r.offsetParent !== null).slice(0, s.limit).map((r, i) => {
+	const q = (sel) => sel ? r.querySelector(sel) : null;
+	const txt = (sel) => { const e = q(sel); return e ? (e.innerText || '').trim() : ''; };
+	const from = q(s.from);
+	let id = '';
+	if (s.idAttr) {
+		const e = r.matches('[' + s.idAttr + ']') ? r : r.querySelector('[' + s.idAttr + ']');
+		if (e) id = e.getAttribute(s.idAttr) || '';
+	}
+	if (!id && s.idHref) {
+		const a = r.matches('a[href]') ? r : r.querySelector('a[href]');
+		const m = a && (a.getAttribute('href') || '').match(new RegExp(s.idHref));
+		if (m) id = m[1];
+	}
+	return {
+		index: i + 1, id,
+		from: from ? (from.innerText || '').trim() : '',
+		from_addr: from && s.fromAttr ? (from.getAttribute(s.fromAttr) || '') : '',
+		subject: txt(s.subject), snippet: txt(s.snippet), date: txt(s.date),
+		unread: s.unread ? (r.matches(s.unread) || !!r.querySelector(s.unread)) : false,
+	};
+})`
+
+const readJS = `(s) => {
+	const txt = (sel) => { const e = sel && document.querySelector(sel); return e ? (e.innerText || '').trim() : ''; };
+	const from = s.from && document.querySelector(s.from);
+	return {
+		subject: txt(s.subject), date: txt(s.date), body: txt(s.body),
+		from: from ? (from.innerText || '').trim() : '',
+		from_addr: from && s.fromAttr ? (from.getAttribute(s.fromAttr) || '') : '',
+	};
+}`
+
+func (s *site) Name() string     { return s.name }
+func (s *site) InboxURL() string { return s.base + s.folders["inbox"] }
+
+func (s *site) Match(u *url.URL) bool {
+	for _, h := range append(append([]string{}, s.hosts...), s.login...) {
+		if strings.HasPrefix(u.Host, h) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *site) isLogin(u *url.URL) bool {
+	for _, h := range s.login {
+		if strings.HasPrefix(u.Host, h) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *site) Detect(page playwright.Page) (Status, error) {
+	u, err := url.Parse(page.URL())
+	if err != nil {
+		return Status{Client: s.name, Page: PageUnknown}, err
+	}
+	st := Status{Client: s.name}
+	if s.isLogin(u) {
+		st.Page = PageLogin
+		return st, nil
+	}
+	st.Page, st.Folder = s.kind(u)
+	if st.Page != PageUnknown {
+		return st, nil
+	}
+	// Адрес ничего не сказал — смотрим на разметку.
+	if n, _ := page.Locator(s.msg.Body).Count(); n > 0 {
+		st.Page = PageMessage
+	} else if n, _ := page.Locator(s.list.Rows).Count(); n > 0 {
+		st.Page = PageMessageList
+	}
+	return st, nil
+}
+
+func (s *site) List(page playwright.Page, limit int) ([]Message, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	sel := s.list
+	sel.Limit = limit
+	v, err := page.Evaluate(listJS, sel)
+	if err != nil {
+		return nil, err
+	}
+	var out []Message
+	if err := decode(v, &out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("mail: %s: no messages found on the page (empty folder or changed markup)", s.name)
+	}
+	return out, nil
+}
+
+func (s *site) Open(page playwright.Page, ref string) error {
+	ref = strings.TrimPrefix(strings.TrimSpace(ref), "#")
+	if n, err := strconv.Atoi(ref); err == nil && n > 0 && len(ref) < 4 {
+		rows, err := s.List(page, n)
+		if err != nil {
+			return err
+		}
+		if n > len(rows) {
+			return fmt.Errorf("mail: %s: only %d messages in the list", s.name, len(rows))
+		}
+		if rows[n-1].ID != "" && s.messageURL != nil {
+			return s.gotoHash(page, s.messageURL(rows[n-1].ID))
+		}
+		return page.Locator(s.list.Rows).Filter(playwright.LocatorFilterOptions{Visible: playwright.Bool(true)}).Nth(n - 1).Click()
+	}
+	if s.messageURL == nil {
+		return ErrUnsupported
+	}
+	return s.gotoHash(page, s.messageURL(ref))
+}
+
+// gotoHash переходит по адресу внутри одностраничного клиента. Если меняется
+// только фрагмент, достаточно location.hash — без полной перезагрузки.
+func (s *site) gotoHash(page playwright.Page, target string) error {
+	if strings.HasPrefix(target, "#") {
+		cur, err := url.Parse(page.URL())
+		if err == nil && s.Match(cur) && !s.isLogin(cur) {
+			_, err = page.Evaluate(`(h) => { location.hash = h }`, target)
+			return err
+		}
+		target = s.base + target
+	}
+	_, err := page.Goto(target)
+	return err
+}
+
+func (s *site) Read(page playwright.Page) (Content, error) {
+	v, err := page.Evaluate(readJS, s.msg)
+	if err != nil {
+		return Content{}, err
+	}
+	var c Content
+	if err := decode(v, &c); err != nil {
+		return c, err
+	}
+	if c.Body == "" && c.Subject == "" {
+		return c, fmt.Errorf("mail: %s: no open message on the page", s.name)
+	}
+	if u, err := url.Parse(page.URL()); err == nil {
+		c.ID = messageID(u.Fragment)
+	}
+	return c, nil
+}
+
+// messageID — последний сегмент фрагмента вида folder/ID или message/ID.
+func messageID(fragment string) string {
+	if i := strings.LastIndexByte(fragment, '/'); i >= 0 {
+		return fragment[i+1:]
+	}
+	return ""
+}
+
+func (s *site) OpenFolder(page playwright.Page, folder string) error {
+	if h, ok := s.folders[Folder(folder)]; ok {
+		return s.gotoHash(page, h)
+	}
+	return page.Locator(fmt.Sprintf("role=link[name=%q]", folder)).First().Click()
+}
+
+func (s *site) Delete(page playwright.Page) error {
+	return clickFirst(page, "delete", s.deleteBtn...)
+}
+
+func (s *site) MarkSpam(page playwright.Page) error {
+	return clickFirst(page, "spam", s.spamBtn...)
+}
+
+func (s *site) Move(page playwright.Page, folder string) error {
+	switch Folder(folder) {
+	case "trash":
+		return s.Delete(page)
+	case "spam":
+		return s.MarkSpam(page)
+	}
+	if err := clickFirst(page, "move", s.moveBtn...); err != nil {
+		return err
+	}
+	return clickFirst(page, "move target "+folder,
+		fmt.Sprintf("role=menuitem[name=%q]", folder),
+		fmt.Sprintf("role=menuitemcheckbox[name=%q]", folder),
+		fmt.Sprintf("role=option[name=%q]", folder),
+		fmt.Sprintf("text=%q", folder))
+}
@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Yandex — Яндекс Почта (mail.yandex.ru и региональные домены).
+var Yandex Adapter = &site{
+	name:  "yandex",
+	hosts: []string{"mail.yandex."},
+	login: []string{"passport.yandex."},
+	base:  "https://mail.yandex.ru/",
+	folders: map[string]string{
+		"inbox":  "#inbox",
+		"spam":   "#spam",
+		"trash":  "#trash",
+		"sent":   "#sent",
+		"drafts": "#draft",
+	},
+	kind: func(u *url.URL) (PageKind, string) {
+		f := u.Fragment
+		switch {
+		case strings.HasPrefix(f, "message/"), strings.Contains(f, "/thread/"):
+			return PageMessage, ""
+		case strings.HasPrefix(f, "compose"):
+			return PageCompose, ""
+		case strings.HasPrefix(f, "setup/folders"):
+			return PageFolderList, ""
+		case f == "", f == "inbox":
+			return PageMessageList, "inbox"
+		case f == "spam", f == "trash", f == "sent":
+			return PageMessageList, f
+		case f == "draft":
+			return PageMessageList, "drafts"
+		case strings.HasPrefix(f, "folder/"), strings.HasPrefix(f, "label/"), strings.HasPrefix(f, "search"):
+			return PageMessageList, f
+		}
+		return PageUnknown, ""
+	},
+	messageURL: func(id string) string { return "#message/" + id },
+	list: listSelectors{
+		Rows:     ".ns-view-messages-item-wrap, a.mail-MessageSnippet",
+		From:     ".mail-MessageSnippet-FromText",
+		FromAttr: "title",
+		Subject:  ".mail-MessageSnippet-Item_subject",
+		Snippet:  ".mail-MessageSnippet-Item_firstline",
+		Date:     ".mail-MessageSnippet-Item_dateText",
+		Unread:   ".mail-MessageSnippet-Item_unread, .is-unread",
+		IDHref:   `#message/(\d+)`,
+	},
+	msg: messageSelectors{
+		Subject: ".mail-Message-Toolbar-Subject, .mail-Message-Head-Subject",
+		From:    ".mail-Message-Sender-Email, .mail-Message-Sender-Name",
+		Date:    ".mail-Message-Date",
+		Body:    ".mail-Message-Body-Content",
+	},
+	deleteBtn: []string{`role=button[name="Удалить"]`, `[title^="Удалить"]`, ".ns-view-toolbar-button-delete"},
+	spamBtn:   []string{`role=button[name="Это спам!"]`, `[title^="Это спам"]`, ".ns-view-toolbar-button-spam"},
+	moveBtn:   []string{`role=button[name="В папку"]`, `[title^="В папку"]`, ".ns-view-toolbar-button-folders-actions"},
+}
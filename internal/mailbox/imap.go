@@ -0,0 +1,222 @@
+package mailbox
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"AIAgent/internal/mail"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// imapBackend — ящик на IMAP-сервере. Клиент go-imap не потокобезопасен,
+// поэтому вызовы идут по одному (см. Tools.Call).
+type imapBackend struct {
+	cfg Config
+	c   *client.Client
+	// selected — открытая папка, чтобы не делать SELECT на каждый вызов.
+	selected string
+}
+
+func dialIMAP(cfg Config) (*imapBackend, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var (
+		c   *client.Client
+		err error
+	)
+	if cfg.TLS {
+		c, err = client.DialWithDialerTLS(dialer, cfg.Addr, nil)
+	} else {
+		c, err = client.DialWithDialer(dialer, cfg.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mailbox: connect %s: %w", cfg.Addr, err)
+	}
+	c.Timeout = timeout
+	if err := c.Login(cfg.Username, cfg.Password); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("mailbox: login %s: %w", cfg.Username, err)
+	}
+	return &imapBackend{cfg: cfg, c: c}, nil
+}
+
+func (b *imapBackend) Close() error {
+	return b.c.Logout()
+}
+
+func (b *imapBackend) Folders() ([]string, error) {
+	ch := make(chan *imap.MailboxInfo, 16)
+	done := make(chan error, 1)
+	go func() { done <- b.c.List("", "*", ch) }()
+	var out []string
+	for m := range ch {
+		if !hasAttr(m.Attributes, imap.NoSelectAttr) {
+			out = append(out, m.Name)
+		}
+	}
+	if err := <-done; err != nil {
+		return nil, err
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func hasAttr(attrs []string, a string) bool {
+	for _, x := range attrs {
+		if x == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *imapBackend) sel(folder string) (*imap.MailboxStatus, error) {
+	name := b.cfg.resolve(folder)
+	st, err := b.c.Select(name, false)
+	if err != nil {
+		b.selected = ""
+		return nil, fmt.Errorf("mailbox: select %s: %w", name, err)
+	}
+	b.selected = name
+	return st, nil
+}
+
+func (b *imapBackend) List(folder string, limit int) ([]mail.Message, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	st, err := b.sel(folder)
+	if err != nil {
+		return nil, err
+	}
+	if st.Messages == 0 {
+		return nil, nil
+	}
+	from := uint32(1)
+	if st.Messages > uint32(limit) {
+		from = st.Messages - uint32(limit) + 1
+	}
+	seq := new(imap.SeqSet)
+	seq.AddRange(from, st.Messages)
+	ch := make(chan *imap.Message, limit)
+	done := make(chan error, 1)
+	go func() {
+		done <- b.c.Fetch(seq, []imap.FetchItem{imap.FetchEnvelope, imap.FetchFlags, imap.FetchUid}, ch)
+	}()
+	var msgs []*imap.Message
+	for m := range ch {
+		msgs = append(msgs, m)
+	}
+	if err := <-done; err != nil {
+		return nil, err
+	}
+	// Новые письма — с большими номерами; показываем их первыми.
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].SeqNum > msgs[j].SeqNum })
+	out := make([]mail.Message, 0, len(msgs))
+	for i, m := range msgs {
+		out = append(out, envelope(i+1, m))
+	}
+	return out, nil
+}
+
+func envelope(index int, m *imap.Message) mail.Message {
+	msg := mail.Message{Index: index, ID: strconv.FormatUint(uint64(m.Uid), 10), Unread: !hasAttr(m.Flags, imap.SeenFlag)}
+	if e := m.Envelope; e != nil {
+		msg.Subject = e.Subject
+		if !e.Date.IsZero() {
+			msg.Date = e.Date.Format(time.RFC3339)
+		}
+		if len(e.From) > 0 {
+			msg.From, msg.FromAddr = e.From[0].PersonalName, e.From[0].Address()
+			if msg.From == "" {
+				msg.From = msg.FromAddr
+			}
+		}
+	}
+	return msg
+}
+
+// uidSet выбирает папку и проверяет ID.
+func (b *imapBackend) uidSet(folder, id string) (*imap.SeqSet, error) {
+	uid, err := strconv.ParseUint(id, 10, 32)
+	if err != nil || uid == 0 {
+		return nil, fmt.Errorf("mailbox: bad message id %q", id)
+	}
+	if _, err := b.sel(folder); err != nil {
+		return nil, err
+	}
+	seq := new(imap.SeqSet)
+	seq.AddNum(uint32(uid))
+	return seq, nil
+}
+
+func (b *imapBackend) Read(folder, id string) (mail.Content, error) {
+	seq, err := b.uidSet(folder, id)
+	if err != nil {
+		return mail.Content{}, err
+	}
+	// BODY.PEEK не меняет флаг \Seen: чтение агентом не отмечает письмо прочитанным.
+	section := &imap.BodySectionName{Peek: true}
+	ch := make(chan *imap.Message, 1)
+	done := make(chan error, 1)
+	go func() { done <- b.c.UidFetch(seq, []imap.FetchItem{section.FetchItem(), imap.FetchUid}, ch) }()
+	var msg *imap.Message
+	for m := range ch {
+		msg = m
+	}
+	if err := <-done; err != nil {
+		return mail.Content{}, err
+	}
+	if msg == nil {
+		return mail.Content{}, fmt.Errorf("%w: %s in %s", ErrNotFound, id, b.selected)
+	}
+	body := msg.GetBody(section)
+	if body == nil {
+		return mail.Content{}, fmt.Errorf("mailbox: server returned no body for %s", id)
+	}
+	c, err := parse(body)
+	c.ID = id
+	return c, err
+}
+
+func (b *imapBackend) Move(folder, id, dest string) error {
+	seq, err := b.uidSet(folder, id)
+	if err != nil {
+		return err
+	}
+	return b.c.UidMove(seq, b.cfg.resolve(dest))
+}
+
+func (b *imapBackend) Delete(folder, id string) error {
+	trash := b.cfg.resolve("trash")
+	if b.cfg.resolve(folder) != trash && b.exists(trash) {
+		return b.Move(folder, id, "trash")
+	}
+	seq, err := b.uidSet(folder, id)
+	if err != nil {
+		return err
+	}
+	if err := b.c.UidStore(seq, imap.FormatFlagsOp(imap.AddFlags, true), []any{imap.DeletedFlag}, nil); err != nil {
+		return err
+	}
+	return b.c.Expunge(nil)
+}
+
+func (b *imapBackend) exists(name string) bool {
+	ch := make(chan *imap.MailboxInfo, 1)
+	done := make(chan error, 1)
+	go func() { done <- b.c.List("", name, ch) }()
+	found := false
+	for range ch {
+		found = true
+	}
+	return <-done == nil && found
+}
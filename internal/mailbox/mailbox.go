@@ -0,0 +1,273 @@
+// Package mailbox — доступ к почтовому ящику без браузера: по IMAP или к
+// локальному каталогу Maildir. Агент вызывает его через инструменты
+// mailbox_*, когда в конфигурации заданы учётные данные; без них почтовые
+// задачи решаются через веб-клиент (см. internal/mail).
+//
+// Письма описываются теми же типами mail.Message и mail.Content, что и в
+// веб-адаптерах; ID — UID письма на IMAP-сервере или уникальное имя файла в
+// Maildir, он остаётся стабильным, пока письмо в папке.
+package mailbox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"AIAgent/internal/mail"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-message/charset"
+	gomail "github.com/emersion/go-message/mail"
+)
+
+func init() {
+	// Кодированные заголовки и тела в koi8-r, windows-1251 и т.п.
+	imap.CharsetReader = charset.Reader
+}
+
+// Backend — операции с почтовым ящиком. folder — каноническое имя (inbox,
+// spam, trash, sent, drafts) или имя папки на сервере.
+type Backend interface {
+	Folders() ([]string, error)
+	// List возвращает до limit писем папки, новые первыми.
+	List(folder string, limit int) ([]mail.Message, error)
+	Read(folder, id string) (mail.Content, error)
+	Move(folder, id, dest string) error
+	// Delete перекладывает письмо в корзину, а в самой корзине удаляет насовсем.
+	Delete(folder, id string) error
+	Close() error
+}
+
+// Config — подключение к ящику; пустой Backend — ящик не настроен.
+type Config struct {
+	// Backend: "imap" или "maildir".
+	Backend string `yaml:"backend"`
+	// Addr — host:port IMAP-сервера, например imap.yandex.ru:993.
+	Addr     string `yaml:"addr"`
+	TLS      bool   `yaml:"tls"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Maildir — корневой каталог Maildir (в нём cur, new, tmp и .Папки).
+	Maildir string `yaml:"maildir"`
+	// Folders сопоставляет канонические имена папкам ящика, если они
+	// называются не Spam/Trash/Sent/Drafts (например, "[Gmail]/Spam").
+	Folders map[string]string `yaml:"folders"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// Enabled — ящик настроен.
+func (c Config) Enabled() bool { return c.Backend != "" }
+
+// Validate проверяет, что для выбранного бэкенда заданы нужные поля.
+func (c Config) Validate() error {
+	switch c.Backend {
+	case "":
+		return nil
+	case "imap":
+		if c.Addr == "" || c.Username == "" {
+			return errors.New("mailbox: imap needs addr and username")
+		}
+	case "maildir":
+		if c.Maildir == "" {
+			return errors.New("mailbox: maildir path is not set")
+		}
+	default:
+		return fmt.Errorf("mailbox: unknown backend %q (imap, maildir)", c.Backend)
+	}
+	return nil
+}
+
+var defaultFolders = map[string]string{
+	"inbox":  "INBOX",
+	"spam":   "Spam",
+	"trash":  "Trash",
+	"sent":   "Sent",
+	"drafts": "Drafts",
+}
+
+// resolve переводит каноническое или русское название папки в имя в ящике.
+func (c Config) resolve(folder string) string {
+	if strings.TrimSpace(folder) == "" {
+		folder = "inbox"
+	}
+	canon := mail.Folder(folder)
+	if f, ok := c.Folders[canon]; ok {
+		return f
+	}
+	if f, ok := defaultFolders[canon]; ok {
+		return f
+	}
+	return folder
+}
+
+// Open подключается к ящику.
+func Open(c Config) (Backend, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	switch c.Backend {
+	case "imap":
+		return dialIMAP(c)
+	case "maildir":
+		return openMaildir(c)
+	}
+	return nil, errors.New("mailbox: backend is not configured")
+}
+
+// ErrNotFound — письма с таким ID нет в папке.
+var ErrNotFound = errors.New("mailbox: message not found")
+
+// Client подключается к ящику при первом обращении и держит соединение до Close.
+type Client struct {
+	Config Config
+
+	mu sync.Mutex
+	b  Backend
+}
+
+// NewClient — ленивое подключение; nil, если ящик не настроен.
+func NewClient(c Config) (*Client, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &Client{Config: c}, nil
+}
+
+// Backend возвращает открытое подключение, при необходимости открывая его.
+func (c *Client) Backend() (Backend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.b != nil {
+		return c.b, nil
+	}
+	b, err := Open(c.Config)
+	if err != nil {
+		return nil, err
+	}
+	c.b = b
+	return b, nil
+}
+
+// Reset закрывает подключение после сетевой ошибки; следующий вызов переподключится.
+func (c *Client) Reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.b != nil {
+		c.b.Close()
+		c.b = nil
+	}
+}
+
+// Close закрывает подключение, если оно было открыто.
+func (c *Client) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.b == nil {
+		return nil
+	}
+	err := c.b.Close()
+	c.b = nil
+	return err
+}
+
+// header — поля строки списка из заголовков письма.
+func header(h gomail.Header) mail.Message {
+	m := mail.Message{}
+	m.Subject, _ = h.Subject()
+	if from, err := h.AddressList("From"); err == nil && len(from) > 0 {
+		m.From, m.FromAddr = from[0].Name, from[0].Address
+		if m.From == "" {
+			m.From = m.FromAddr
+		}
+	}
+	if d, err := h.Date(); err == nil && !d.IsZero() {
+		m.Date = d.Format(time.RFC3339)
+	}
+	return m
+}
+
+// parse читает письмо целиком: заголовки и текст (text/plain, иначе text/html без разметки).
+func parse(r io.Reader) (mail.Content, error) {
+	mr, err := gomail.CreateReader(r)
+	if err != nil && mr == nil {
+		return mail.Content{}, err
+	}
+	defer mr.Close()
+	h := header(mr.Header)
+	c := mail.Content{From: h.From, FromAddr: h.FromAddr, Subject: h.Subject, Date: h.Date}
+	var plain, html string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if plain != "" || html != "" {
+				break
+			}
+			return c, err
+		}
+		ih, ok := p.Header.(*gomail.InlineHeader)
+		if !ok {
+			continue
+		}
+		ct, _, _ := ih.ContentType()
+		b, err := io.ReadAll(p.Body)
+		if err != nil {
+			continue
+		}
+		switch {
+		case ct == "text/plain" && plain == "":
+			plain = string(b)
+		case ct == "text/html" && html == "":
+			html = string(b)
+		}
+	}
+	c.Body = strings.TrimSpace(plain)
+	if c.Body == "" {
+		c.Body = stripHTML(html)
+	}
+	return c, nil
+}
+
+var (
+	reHTMLSkip  = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	reHTMLBreak = regexp.MustCompile(`(?i)<(br|/p|/div|/tr|/li|/h\d)[^>]*>`)
+	reHTMLTag   = regexp.MustCompile(`<[^>]+>`)
+	reBlank     = regexp.MustCompile(`[ \t]+`)
+	reBlankLine = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// stripHTML — грубый текст HTML-письма: без тегов, скриптов и стилей.
+func stripHTML(s string) string {
+	s = reHTMLSkip.ReplaceAllString(s, "")
+	s = reHTMLBreak.ReplaceAllString(s, "\n")
+	s = reHTMLTag.ReplaceAllString(s, "")
+	r := strings.NewReplacer("&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#39;", "'")
+	s = r.Replace(s)
+	s = reBlank.ReplaceAllString(s, " ")
+	s = reBlankLine.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// snippet — начало текста для строки списка.
+func snippet(body string) string {
+	s := strings.Join(strings.Fields(body), " ")
+	if r := []rune(s); len(r) > 120 {
+		return string(r[:120]) + "…"
+	}
+	return s
+}
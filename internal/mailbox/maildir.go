@@ -0,0 +1,206 @@
+package mailbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"AIAgent/internal/mail"
+)
+
+// maildirBackend — локальный Maildir с папками в формате Maildir++:
+// входящие в корне, остальные папки — подкаталоги ".Имя".
+type maildirBackend struct {
+	cfg  Config
+	root string
+}
+
+func openMaildir(cfg Config) (*maildirBackend, error) {
+	if !isMaildir(cfg.Maildir) {
+		return nil, fmt.Errorf("mailbox: %s is not a maildir (no cur/new)", cfg.Maildir)
+	}
+	return &maildirBackend{cfg: cfg, root: cfg.Maildir}, nil
+}
+
+func isMaildir(dir string) bool {
+	for _, sub := range []string{"cur", "new"} {
+		if st, err := os.Stat(filepath.Join(dir, sub)); err != nil || !st.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *maildirBackend) Close() error { return nil }
+
+// dir — каталог папки; ошибка, если такой папки нет.
+func (b *maildirBackend) dir(folder string) (string, error) {
+	name := b.cfg.resolve(folder)
+	if strings.EqualFold(name, "INBOX") {
+		return b.root, nil
+	}
+	for _, d := range []string{"." + strings.ReplaceAll(name, "/", "."), name} {
+		p := filepath.Join(b.root, d)
+		if isMaildir(p) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("mailbox: folder %s not found in %s", name, b.root)
+}
+
+func (b *maildirBackend) Folders() ([]string, error) {
+	entries, err := os.ReadDir(b.root)
+	if err != nil {
+		return nil, err
+	}
+	out := []string{"INBOX"}
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), ".") && isMaildir(filepath.Join(b.root, e.Name())) {
+			out = append(out, strings.TrimPrefix(e.Name(), "."))
+		}
+	}
+	sort.Strings(out[1:])
+	return out, nil
+}
+
+// entry — файл письма.
+type entry struct {
+	path string
+	id   string
+	new  bool
+	seen bool
+	mod  int64
+}
+
+// idOf — уникальная часть имени файла, без флагов ":2,FS".
+func idOf(name string) string {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+func (b *maildirBackend) entries(dir string) ([]entry, error) {
+	var out []entry
+	for _, sub := range []string{"new", "cur"} {
+		files, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+				continue
+			}
+			e := entry{path: filepath.Join(dir, sub, f.Name()), id: idOf(f.Name()), new: sub == "new"}
+			if i := strings.Index(f.Name(), ":2,"); i >= 0 {
+				e.seen = strings.ContainsRune(f.Name()[i+3:], 'S')
+			}
+			if info, err := f.Info(); err == nil {
+				e.mod = info.ModTime().UnixNano()
+			}
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (b *maildirBackend) find(folder, id string) (string, entry, error) {
+	dir, err := b.dir(folder)
+	if err != nil {
+		return "", entry{}, err
+	}
+	es, err := b.entries(dir)
+	if err != nil {
+		return "", entry{}, err
+	}
+	for _, e := range es {
+		if e.id == id {
+			return dir, e, nil
+		}
+	}
+	return "", entry{}, fmt.Errorf("%w: %s in %s", ErrNotFound, id, b.cfg.resolve(folder))
+}
+
+func (b *maildirBackend) List(folder string, limit int) ([]mail.Message, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	dir, err := b.dir(folder)
+	if err != nil {
+		return nil, err
+	}
+	es, err := b.entries(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(es, func(i, j int) bool {
+		if es[i].mod != es[j].mod {
+			return es[i].mod > es[j].mod
+		}
+		return es[i].id > es[j].id
+	})
+	if len(es) > limit {
+		es = es[:limit]
+	}
+	out := make([]mail.Message, 0, len(es))
+	for i, e := range es {
+		c, err := readFile(e.path)
+		if err != nil {
+			return nil, fmt.Errorf("mailbox: %s: %w", e.path, err)
+		}
+		out = append(out, mail.Message{
+			Index: i + 1, ID: e.id, From: c.From, FromAddr: c.FromAddr,
+			Subject: c.Subject, Snippet: snippet(c.Body), Date: c.Date, Unread: e.new || !e.seen,
+		})
+	}
+	return out, nil
+}
+
+func readFile(path string) (mail.Content, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return mail.Content{}, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+func (b *maildirBackend) Read(folder, id string) (mail.Content, error) {
+	_, e, err := b.find(folder, id)
+	if err != nil {
+		return mail.Content{}, err
+	}
+	c, err := readFile(e.path)
+	c.ID = id
+	return c, err
+}
+
+func (b *maildirBackend) Move(folder, id, dest string) error {
+	_, e, err := b.find(folder, id)
+	if err != nil {
+		return err
+	}
+	to, err := b.dir(dest)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(e.path)
+	if e.new {
+		// Письмо из new/ уже видел клиент — по правилам Maildir оно переходит в cur/ с флагами.
+		name += ":2,"
+	}
+	return os.Rename(e.path, filepath.Join(to, "cur", name))
+}
+
+func (b *maildirBackend) Delete(folder, id string) error {
+	dir, e, err := b.find(folder, id)
+	if err != nil {
+		return err
+	}
+	if trash, err := b.dir("trash"); err == nil && trash != dir {
+		return b.Move(folder, id, "trash")
+	}
+	return os.Remove(e.path)
+}
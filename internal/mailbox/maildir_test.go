@@ -0,0 +1,216 @@
+package mailbox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	plainMsg = "From: Ольга <olga@company.example>\r\n" +
+		"Subject: Отчёт\r\n" +
+		"Date: Sat, 17 Oct 2026 10:00:00 +0300\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n" +
+		"Отчёт во вложении.\r\n"
+	htmlMsg = "From: shop@sale.example\r\n" +
+		"Subject: =?utf-8?B?0KHQutC40LTQutC4?=\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n\r\n" +
+		"<html><head><style>p{}</style></head><body><p>Только&nbsp;сегодня</p><script>x()</script></body></html>\r\n"
+	spamMsg = "From: win@lottery.example\r\nSubject: Приз\r\n\r\nЗаберите приз\r\n"
+)
+
+// newMaildir собирает ящик: во входящих новое письмо и прочитанное HTML-письмо
+// (оно старше), в .Spam одно письмо, .Trash пустая.
+func newMaildir(t *testing.T) (Backend, string) {
+	t.Helper()
+	root := t.TempDir()
+	for _, d := range []string{"", ".Spam", ".Trash"} {
+		for _, sub := range []string{"cur", "new", "tmp"} {
+			if err := os.MkdirAll(filepath.Join(root, d, sub), 0o700); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// Каталог без cur/new — не папка.
+	if err := os.MkdirAll(filepath.Join(root, ".broken"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	files := []struct {
+		path string
+		body string
+		mod  time.Time
+	}{
+		{"new/1001.M1.host", plainMsg, time.Now()},
+		{"cur/1000.M1.host:2,S", htmlMsg, old},
+		{".Spam/cur/999.M1.host:2,", spamMsg, old},
+	}
+	for _, f := range files {
+		p := filepath.Join(root, f.path)
+		if err := os.WriteFile(p, []byte(f.body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, f.mod, f.mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := Open(Config{Backend: "maildir", Maildir: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, root
+}
+
+func TestMaildirFolders(t *testing.T) {
+	b, _ := newMaildir(t)
+	got, err := b.Folders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"INBOX", "Spam", "Trash"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Folders = %v, want %v", got, want)
+	}
+}
+
+func TestMaildirList(t *testing.T) {
+	b, _ := newMaildir(t)
+	tests := []struct {
+		folder string
+		limit  int
+		ids    []string
+		unread []bool
+	}{
+		{"", 0, []string{"1001.M1.host", "1000.M1.host"}, []bool{true, false}},
+		{"входящие", 1, []string{"1001.M1.host"}, []bool{true}},
+		{"спам", 10, []string{"999.M1.host"}, []bool{true}},
+		{"trash", 10, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			msgs, err := b.List(tt.folder, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			var unread []bool
+			for i, m := range msgs {
+				if m.Index != i+1 {
+					t.Errorf("Index = %d, want %d", m.Index, i+1)
+				}
+				ids = append(ids, m.ID)
+				unread = append(unread, m.Unread)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) || !reflect.DeepEqual(unread, tt.unread) {
+				t.Errorf("ids = %v unread = %v, want %v %v", ids, unread, tt.ids, tt.unread)
+			}
+		})
+	}
+	msgs, _ := b.List("inbox", 0)
+	if m := msgs[0]; m.From != "Ольга" || m.FromAddr != "olga@company.example" || m.Subject != "Отчёт" || m.Snippet != "Отчёт во вложении." || m.Date != "2026-10-17T10:00:00+03:00" {
+		t.Errorf("first message = %+v", m)
+	}
+	if _, err := b.List("archive", 0); err == nil {
+		t.Error("List of a missing folder succeeded")
+	}
+}
+
+func TestMaildirRead(t *testing.T) {
+	b, _ := newMaildir(t)
+	tests := []struct {
+		id      string
+		subject string
+		body    string
+	}{
+		{"1001.M1.host", "Отчёт", "Отчёт во вложении."},
+		{"1000.M1.host", "Скидки", "Только сегодня"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			c, err := b.Read("inbox", tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.ID != tt.id || c.Subject != tt.subject || c.Body != tt.body {
+				t.Errorf("Read = %+v", c)
+			}
+		})
+	}
+	if _, err := b.Read("inbox", "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Read missing: %v, want ErrNotFound", err)
+	}
+}
+
+func TestMaildirMoveDelete(t *testing.T) {
+	b, root := newMaildir(t)
+
+	// Новое письмо при переносе попадает в cur/ с пустыми флагами.
+	if err := b.Move("inbox", "1001.M1.host", "spam"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".Spam", "cur", "1001.M1.host:2,")); err != nil {
+		t.Errorf("moved file: %v", err)
+	}
+	if _, err := b.Read("inbox", "1001.M1.host"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("message is still in inbox: %v", err)
+	}
+	if err := b.Move("inbox", "1000.M1.host", "archive"); err == nil {
+		t.Error("Move to a missing folder succeeded")
+	}
+
+	// Удаление перекладывает в корзину, в корзине — удаляет насовсем.
+	if err := b.Delete("spam", "999.M1.host"); err != nil {
+		t.Fatal(err)
+	}
+	trash, _ := b.List("корзина", 10)
+	if len(trash) != 1 || trash[0].ID != "999.M1.host" {
+		t.Fatalf("trash = %+v", trash)
+	}
+	if err := b.Delete("trash", "999.M1.host"); err != nil {
+		t.Fatal(err)
+	}
+	left, _ := os.ReadDir(filepath.Join(root, ".Trash", "cur"))
+	if len(left) != 0 {
+		t.Errorf("trash/cur after delete = %v", left)
+	}
+}
+
+func TestOpenMaildirErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"no path", Config{Backend: "maildir"}, "maildir path is not set"},
+		{"not a maildir", Config{Backend: "maildir", Maildir: t.TempDir()}, "is not a maildir"},
+		{"unknown backend", Config{Backend: "pop3"}, `unknown backend "pop3"`},
+		{"imap without user", Config{Backend: "imap", Addr: "imap.example:993"}, "imap needs addr and username"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Open(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Open error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	c := Config{Folders: map[string]string{"spam": "[Gmail]/Spam"}}
+	tests := map[string]string{
+		"":          "INBOX",
+		"Входящие":  "INBOX",
+		"спам":      "[Gmail]/Spam",
+		"Корзина":   "Trash",
+		"Проекты/A": "Проекты/A",
+	}
+	for in, want := range tests {
+		if got := c.resolve(in); got != want {
+			t.Errorf("resolve(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
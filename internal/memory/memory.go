@@ -7,6 +7,15 @@ type Memory struct {
 	lastHash    string
 	lastToolSel string
 	repeatCount int
+	answers     []UserAnswer
+	hints       []string
+	history     []Step
+}
+
+// UserAnswer — вопрос агента и ответ пользователя на него.
+type UserAnswer struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
 }
 
 func (m *Memory) UpdatePage(url, title, hash string) {
@@ -28,3 +37,74 @@ func New() *Memory { return &Memory{} }
 
 func (m *Memory) LastAction() string     { return m.lastAction }
 func (m *Memory) SetLastAction(s string) { m.lastAction = s }
+
+func (m *Memory) AddUserAnswer(q, a string) {
+	m.answers = append(m.answers, UserAnswer{Question: q, Answer: a})
+}
+func (m *Memory) UserAnswers() []UserAnswer { return m.answers }
+
+func (m *Memory) AddHint(h string) { m.hints = append(m.hints, h) }
+func (m *Memory) Hints() []string  { return m.hints }
+
+// Step — запись истории выполненных действий.
+type Step struct {
+	N      int            `json:"n"`
+	Tool   string         `json:"tool"`
+	Args   map[string]any `json:"args,omitempty"`
+	Result string         `json:"result,omitempty"`
+	Error  string         `json:"error,omitempty"`
+	URL    string         `json:"url,omitempty"`
+}
+
+func (m *Memory) AddStep(s Step)  { m.history = append(m.history, s) }
+func (m *Memory) History() []Step { return m.history }
+
+// Recent возвращает последние n шагов истории.
+func (m *Memory) Recent(n int) []Step {
+	if len(m.history) <= n {
+		return m.history
+	}
+	return m.history[len(m.history)-n:]
+}
+
+// State — сериализуемый снимок памяти для контрольных точек.
+type State struct {
+	LastAction  string       `json:"last_action,omitempty"`
+	LastURL     string       `json:"last_url,omitempty"`
+	LastTitle   string       `json:"last_title,omitempty"`
+	LastHash    string       `json:"last_hash,omitempty"`
+	LastToolSel string       `json:"last_tool_sel,omitempty"`
+	RepeatCount int          `json:"repeat_count,omitempty"`
+	Answers     []UserAnswer `json:"answers,omitempty"`
+	Hints       []string     `json:"hints,omitempty"`
+	History     []Step       `json:"history,omitempty"`
+}
+
+func (m *Memory) State() State {
+	return State{
+		LastAction:  m.lastAction,
+		LastURL:     m.lastURL,
+		LastTitle:   m.lastTitle,
+		LastHash:    m.lastHash,
+		LastToolSel: m.lastToolSel,
+		RepeatCount: m.repeatCount,
+		Answers:     append([]UserAnswer(nil), m.answers...),
+		Hints:       append([]string(nil), m.hints...),
+		History:     append([]Step(nil), m.history...),
+	}
+}
+
+// FromState восстанавливает память из снимка.
+func FromState(s State) *Memory {
+	return &Memory{
+		lastAction:  s.LastAction,
+		lastURL:     s.LastURL,
+		lastTitle:   s.LastTitle,
+		lastHash:    s.LastHash,
+		lastToolSel: s.LastToolSel,
+		repeatCount: s.RepeatCount,
+		answers:     s.Answers,
+		hints:       s.Hints,
+		history:     s.History,
+	}
+}
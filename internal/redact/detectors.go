@@ -0,0 +1,100 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	reEmail = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	reCard  = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+	rePhone = regexp.MustCompile(`(?:\+\d{1,3}[\s\-]?|[78][\s\-]?)?(?:\(\d{3}\)|\d{3})[\s\-]?\d{3}[\s\-]?\d{2}[\s\-]?\d{2}\b`)
+	reOTP   = regexp.MustCompile(`(?i)(?:код|code|otp|пароль|password|pin)[^\d\n]{0,20}\b(\d{4,8})\b`)
+)
+
+// Встроенные детекторы в порядке приоритета.
+func EmailDetector() Detector { return RegexDetector{Name: "email", Re: reEmail} }
+func CardDetector() Detector  { return RegexDetector{Name: "card", Re: reCard, Validate: luhnValid} }
+func PhoneDetector() Detector { return RegexDetector{Name: "phone", Re: rePhone, Bounded: true} }
+func OTPDetector() Detector   { return RegexDetector{Name: "otp", Re: reOTP} }
+
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// Config описывает конвейер редактирования.
+type Config struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Kinds — включённые встроенные детекторы: email, card, phone, otp.
+	// Пустой список означает «все».
+	Kinds []string `yaml:"kinds" json:"kinds"`
+	// Dictionary — слова/имена, которые нужно скрывать (kind=name).
+	Dictionary []string `yaml:"dictionary" json:"dictionary"`
+	// Patterns — пользовательские регулярные выражения: kind → regexp.
+	Patterns map[string]string `yaml:"patterns" json:"patterns"`
+}
+
+func DefaultConfig() Config {
+	return Config{Enabled: true}
+}
+
+// FromConfig собирает Redactor по конфигурации. При Enabled=false возвращает nil,
+// а методы nil-редактора ничего не меняют.
+func FromConfig(cfg Config) (*Redactor, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	builtin := map[string]func() Detector{
+		"email": EmailDetector,
+		"card":  CardDetector,
+		"phone": PhoneDetector,
+		"otp":   OTPDetector,
+	}
+	kinds := cfg.Kinds
+	if len(kinds) == 0 {
+		kinds = []string{"email", "card", "phone", "otp"}
+	}
+
+	var ds []Detector
+	for _, k := range kinds {
+		mk, ok := builtin[strings.ToLower(strings.TrimSpace(k))]
+		if !ok {
+			return nil, fmt.Errorf("redact: unknown detector %q", k)
+		}
+		ds = append(ds, mk())
+	}
+	custom := make([]string, 0, len(cfg.Patterns))
+	for kind := range cfg.Patterns {
+		custom = append(custom, kind)
+	}
+	sort.Strings(custom)
+	for _, kind := range custom {
+		re, err := regexp.Compile(cfg.Patterns[kind])
+		if err != nil {
+			return nil, fmt.Errorf("redact: pattern %s: %w", kind, err)
+		}
+		ds = append(ds, RegexDetector{Name: kind, Re: re})
+	}
+	if len(cfg.Dictionary) > 0 {
+		ds = append(ds, NewDictDetector("name", cfg.Dictionary))
+	}
+	return New(ds...), nil
+}
@@ -0,0 +1,284 @@
+// Package redact заменяет чувствительные фрагменты текста (email, телефоны,
+// номера карт, одноразовые коды, пользовательские шаблоны) на токены вида
+// [EMAIL_1] перед отправкой в LLM и возвращает оригиналы в ответе модели.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Span — найденный детектором фрагмент [Start, End) в исходной строке.
+type Span struct {
+	Start, End int
+}
+
+// Detector находит чувствительные фрагменты одного вида.
+type Detector interface {
+	Kind() string
+	Find(s string) []Span
+}
+
+// Entry — запись журнала редактирования. Оригинальное значение в журнал не попадает.
+type Entry struct {
+	Kind  string `json:"kind"`
+	Token string `json:"token"`
+	Len   int    `json:"len"`
+}
+
+// Redactor хранит соответствие токен ↔ оригинал в рамках одного запуска,
+// поэтому одно и то же значение всегда получает один и тот же токен.
+type Redactor struct {
+	mu        sync.Mutex
+	detectors []Detector
+	byValue   map[string]string
+	byToken   map[string]string
+	counters  map[string]int
+	log       []Entry
+}
+
+func New(detectors ...Detector) *Redactor {
+	return &Redactor{
+		detectors: detectors,
+		byValue:   map[string]string{},
+		byToken:   map[string]string{},
+		counters:  map[string]int{},
+	}
+}
+
+// Redact заменяет все найденные фрагменты токенами.
+// На nil-редакторе возвращает строку без изменений.
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" || len(r.detectors) == 0 {
+		return s
+	}
+	type hit struct {
+		Span
+		kind string
+	}
+	// Детекторы идут в порядке приоритета: при пересечении побеждает более
+	// ранний, а из находок одного детектора — начавшаяся раньше.
+	var hits []hit
+	for _, d := range r.detectors {
+		for _, sp := range d.Find(s) {
+			if sp.Start < 0 || sp.End > len(s) || sp.Start >= sp.End {
+				continue
+			}
+			if !slices.ContainsFunc(hits, func(h hit) bool { return sp.Start < h.End && h.Start < sp.End }) {
+				hits = append(hits, hit{sp, d.Kind()})
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return s
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Start < hits[j].Start })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	pos := 0
+	for _, h := range hits {
+		b.WriteString(s[pos:h.Start])
+		b.WriteString(r.tokenFor(h.kind, s[h.Start:h.End]))
+		pos = h.End
+	}
+	b.WriteString(s[pos:])
+	return b.String()
+}
+
+func (r *Redactor) tokenFor(kind, value string) string {
+	if tok, ok := r.byValue[value]; ok {
+		return tok
+	}
+	r.counters[kind]++
+	tok := fmt.Sprintf("[%s_%d]", strings.ToUpper(kind), r.counters[kind])
+	r.byValue[value] = tok
+	r.byToken[tok] = value
+	r.log = append(r.log, Entry{Kind: kind, Token: tok, Len: len([]rune(value))})
+	return tok
+}
+
+// Restore возвращает оригинальные значения вместо известных токенов.
+func (r *Redactor) Restore(s string) string {
+	if r == nil || s == "" || !strings.Contains(s, "[") {
+		return s
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for tok, v := range r.byToken {
+		s = strings.ReplaceAll(s, tok, v)
+	}
+	return s
+}
+
+// RedactArgs рекурсивно заменяет токенами строки в аргументах инструмента —
+// для всего, что пишется на диск.
+func (r *Redactor) RedactArgs(args map[string]any) map[string]any {
+	if r == nil || args == nil {
+		return args
+	}
+	return r.mapValues(args, r.Redact)
+}
+
+// RestoreArgs рекурсивно восстанавливает строки в аргументах инструмента.
+func (r *Redactor) RestoreArgs(args map[string]any) map[string]any {
+	if r == nil || args == nil {
+		return args
+	}
+	return r.mapValues(args, r.Restore)
+}
+
+func (r *Redactor) mapValues(args map[string]any, f func(string) string) map[string]any {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		out[k] = r.mapValue(v, f)
+	}
+	return out
+}
+
+func (r *Redactor) mapValue(v any, f func(string) string) any {
+	switch x := v.(type) {
+	case string:
+		return f(x)
+	case map[string]any:
+		return r.mapValues(x, f)
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = r.mapValue(e, f)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Log возвращает копию журнала: какие виды данных и какими токенами были скрыты.
+func (r *Redactor) Log() []Entry {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.log...)
+}
+
+// Summary — краткая сводка журнала вида "email=2 phone=1".
+func (r *Redactor) Summary() string {
+	counts := map[string]int{}
+	for _, e := range r.Log() {
+		counts[e.Kind]++
+	}
+	kinds := make([]string, 0, len(counts))
+	for k := range counts {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, 0, len(kinds))
+	for _, k := range kinds {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, counts[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// RegexDetector находит совпадения регулярного выражения. Если в выражении
+// есть группа, скрывается только первая группа (например, сам код после слова «код»).
+type RegexDetector struct {
+	Name     string
+	Re       *regexp.Regexp
+	Validate func(string) bool
+	// Bounded — совпадение не должно продолжать слово или число: иначе телефоном
+	// оказывается хвост длинного идентификатора в URL или атрибуте.
+	Bounded bool
+}
+
+func (d RegexDetector) Kind() string { return d.Name }
+
+func (d RegexDetector) Find(s string) []Span {
+	var out []Span
+	for _, m := range d.Re.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if d.Validate != nil && !d.Validate(s[start:end]) {
+			continue
+		}
+		if d.Bounded && !wholeWord(s, start, end) {
+			continue
+		}
+		out = append(out, Span{start, end})
+	}
+	return out
+}
+
+// DictDetector находит слова из словаря (имена, логины и т.п.) без учёта регистра.
+type DictDetector struct {
+	Name  string
+	Words []string
+	re    *regexp.Regexp
+}
+
+func NewDictDetector(kind string, words []string) *DictDetector {
+	d := &DictDetector{Name: kind, Words: words}
+	var alts []string
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			alts = append(alts, regexp.QuoteMeta(w))
+		}
+	}
+	// Длинные слова первыми, чтобы «Анна Петрова» не разбивалось на «Анна».
+	sort.Slice(alts, func(i, j int) bool { return len(alts[i]) > len(alts[j]) })
+	if len(alts) > 0 {
+		d.re = regexp.MustCompile(`(?i)(?:` + strings.Join(alts, "|") + `)`)
+	}
+	return d
+}
+
+func (d *DictDetector) Kind() string { return d.Name }
+
+func (d *DictDetector) Find(s string) []Span {
+	if d.re == nil {
+		return nil
+	}
+	var out []Span
+	for pos := 0; pos < len(s); {
+		m := d.re.FindStringIndex(s[pos:])
+		if m == nil {
+			break
+		}
+		start, end := pos+m[0], pos+m[1]
+		if wholeWord(s, start, end) {
+			out = append(out, Span{start, end})
+			pos = end
+			continue
+		}
+		// Часть другого слова: ищем дальше со следующей буквы.
+		_, size := utf8.DecodeRuneInString(s[start:])
+		pos = start + size
+	}
+	return out
+}
+
+// wholeWord — s[start:end] не примыкает к буквам и цифрам. Граница проверяется
+// по соседним символам, а не входит в совпадение: иначе из двух слов через
+// один разделитель находилось бы только первое.
+func wholeWord(s string, start, end int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(s[:start]); start > 0 && isWordRune(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(s[end:]); end < len(s) && isWordRune(r) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
@@ -0,0 +1,161 @@
+package redact
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestRedactDetectors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		in   string
+		want string
+	}{
+		{"email", Config{Enabled: true}, "пишите на ivan.petrov@mail.ru", "пишите на [EMAIL_1]"},
+		{"phone", Config{Enabled: true}, "звоните +7 912 345-67-89", "звоните [PHONE_1]"},
+		{"phone trunk prefix", Config{Enabled: true}, "тел. 8(912)345-67-89 и 89123456789", "тел. [PHONE_1] и [PHONE_2]"},
+		{"phone after punctuation", Config{Enabled: true, Kinds: []string{"phone"}}, "tel:+79123456789,", "tel:[PHONE_1],"},
+		{"long id in url fragment", Config{Enabled: true}, "https://mail.yandex.ru/#message/189231004283912", "https://mail.yandex.ru/#message/189231004283912"},
+		{"long id in query", Config{Enabled: true}, "/touch/inbox?uid=1130000012345678&x=1", "/touch/inbox?uid=1130000012345678&x=1"},
+		{"long id in attribute", Config{Enabled: true}, `[data-id="17123456789"]`, `[data-id="17123456789"]`},
+		{"digits glued to letters", Config{Enabled: true, Kinds: []string{"phone"}}, "id9123456789 и 9123456789тел", "id9123456789 и 9123456789тел"},
+		{"card luhn", Config{Enabled: true}, "карта 4111 1111 1111 1111", "карта [CARD_1]"},
+		{"card bad checksum", Config{Enabled: true, Kinds: []string{"card"}}, "счёт 4111 1111 1111 1112", "счёт 4111 1111 1111 1112"},
+		{"otp keeps keyword", Config{Enabled: true}, "Ваш код: 482913", "Ваш код: [OTP_1]"},
+		{"same value same token", Config{Enabled: true}, "a@b.io и снова a@b.io", "[EMAIL_1] и снова [EMAIL_1]"},
+		{"distinct values", Config{Enabled: true}, "a@b.io, c@d.io", "[EMAIL_1], [EMAIL_2]"},
+		{"kinds filter", Config{Enabled: true, Kinds: []string{"phone"}}, "a@b.io", "a@b.io"},
+		{"custom pattern", Config{Enabled: true, Kinds: []string{"email"}, Patterns: map[string]string{"contract": `Д-\d{6}`}}, "договор Д-123456", "договор [CONTRACT_1]"},
+		{"dictionary", Config{Enabled: true, Dictionary: []string{"Анна"}}, "Привет, анна!", "Привет, [NAME_1]!"},
+		{"dictionary whole words", Config{Enabled: true, Dictionary: []string{"Анна"}}, "Аннабель", "Аннабель"},
+		{"dictionary adjacent words", Config{Enabled: true, Dictionary: []string{"Анна", "Олег"}}, "Анна,Олег", "[NAME_1],[NAME_2]"},
+		{"dictionary longest first", Config{Enabled: true, Dictionary: []string{"Анна", "Анна Петрова"}}, "Анна Петрова", "[NAME_1]"},
+		{"disabled", Config{}, "a@b.io", "a@b.io"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := FromConfig(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Redact(tt.in); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactOverlapPriority(t *testing.T) {
+	// Оба детектора находят пересекающиеся фрагменты; побеждает первый в списке,
+	// даже если второй начинается раньше.
+	digits := RegexDetector{Name: "digits", Re: regexp.MustCompile(`\d+`)}
+	word := RegexDetector{Name: "word", Re: regexp.MustCompile(`id\d+`)}
+	tests := []struct {
+		name      string
+		detectors []Detector
+		want      string
+	}{
+		{"later start wins by priority", []Detector{digits, word}, "id[DIGITS_1] x"},
+		{"earlier detector first", []Detector{word, digits}, "[WORD_1] x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.detectors...).Redact("id42 x"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	r, err := FromConfig(Config{Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	red := r.Redact("напиши a@b.io про код 1234")
+	if red != "напиши [EMAIL_1] про код [OTP_1]" {
+		t.Fatalf("Redact = %q", red)
+	}
+	if got := r.Restore("отправлено на [EMAIL_1], код [OTP_1], [PHONE_9]"); got != "отправлено на a@b.io, код 1234, [PHONE_9]" {
+		t.Errorf("Restore = %q", got)
+	}
+	args := map[string]any{
+		"text":   "[EMAIL_1]",
+		"list":   []any{"[OTP_1]", 3.0},
+		"nested": map[string]any{"to": "[EMAIL_1]"},
+	}
+	want := map[string]any{
+		"text":   "a@b.io",
+		"list":   []any{"1234", 3.0},
+		"nested": map[string]any{"to": "a@b.io"},
+	}
+	if got := r.RestoreArgs(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("RestoreArgs = %v, want %v", got, want)
+	}
+	// Голое число — не код: OTP ищется только рядом со словом «код».
+	plain := map[string]any{"text": "a@b.io", "list": []any{"код 1234", "1234"}}
+	redacted := map[string]any{"text": "[EMAIL_1]", "list": []any{"код [OTP_1]", "1234"}}
+	if got := r.RedactArgs(plain); !reflect.DeepEqual(got, redacted) {
+		t.Errorf("RedactArgs = %v, want %v", got, redacted)
+	}
+	if got := r.Summary(); got != "email=1 otp=1" {
+		t.Errorf("Summary = %q", got)
+	}
+}
+
+func TestNilRedactor(t *testing.T) {
+	var r *Redactor
+	if got := r.Redact("a@b.io"); got != "a@b.io" {
+		t.Errorf("Redact = %q", got)
+	}
+	if got := r.Restore("[EMAIL_1]"); got != "[EMAIL_1]" {
+		t.Errorf("Restore = %q", got)
+	}
+	if r.Log() != nil {
+		t.Error("Log of nil redactor is not nil")
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"напиши [EMAIL_1] и [PHONE_2], копия [EMAIL_1]", []string{"[EMAIL_1]", "[PHONE_2]"}},
+		{"[CONTRACT-ID_3] [NAME_10]", []string{"[CONTRACT-ID_3]", "[NAME_10]"}},
+		{"f[name]=1 [EMAIL] [email_1] [_1]", nil},
+	}
+	for _, tt := range tests {
+		if got := Tokens(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokens(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	args := map[string]any{
+		"text":   "[OTP_1]",
+		"list":   []any{"[EMAIL_1]", 1.0},
+		"nested": map[string]any{"b": "[PHONE_1]", "a": "[EMAIL_1]"},
+	}
+	if got, want := ArgTokens(args), []string{"[EMAIL_1]", "[PHONE_1]", "[OTP_1]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ArgTokens = %v, want %v", got, want)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"/message/[PHONE_1]", "/message/79123456789", true},
+		{"/message/[PHONE_1]", "/message/", false},
+		{"[EMAIL_1] → [EMAIL_2]", "a@b.io → c@d.io", true},
+		{"a.b", "axb", false},
+		{"a.b", "a.b", true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
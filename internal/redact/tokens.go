@@ -0,0 +1,76 @@
+package redact
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// reToken — токен редактора: [EMAIL_1], [PHONE_12], [CONTRACT_3].
+var reToken = regexp.MustCompile(`\[\p{Lu}[\p{Lu}\d_\-]*_\d+\]`)
+
+// Tokens возвращает токены редактора в s в порядке появления, без повторов.
+// Оригиналы токенов знает только Redactor запуска, поэтому строку с токенами
+// нельзя повторить буквально: replay, export и навыки проверяют её этой функцией.
+func Tokens(s string) []string {
+	var out []string
+	for _, tok := range reToken.FindAllString(s, -1) {
+		if !slices.Contains(out, tok) {
+			out = append(out, tok)
+		}
+	}
+	return out
+}
+
+// ArgTokens — токены во всех строках аргументов инструмента, без повторов.
+func ArgTokens(args map[string]any) []string {
+	var out []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch x := v.(type) {
+		case string:
+			for _, tok := range Tokens(x) {
+				if !slices.Contains(out, tok) {
+					out = append(out, tok)
+				}
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(x))
+			for k := range x {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(x[k])
+			}
+		case []any:
+			for _, e := range x {
+				walk(e)
+			}
+		}
+	}
+	walk(args)
+	return out
+}
+
+// Match сообщает, совпадает ли s с записанной строкой pattern, в которой
+// токены стоят на месте скрытых значений: каждый токен совпадает с любым
+// непустым фрагментом.
+func Match(pattern, s string) bool {
+	locs := reToken.FindAllStringIndex(pattern, -1)
+	if len(locs) == 0 {
+		return pattern == s
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	pos := 0
+	for _, l := range locs {
+		b.WriteString(regexp.QuoteMeta(pattern[pos:l[0]]))
+		b.WriteString(".+?")
+		pos = l[1]
+	}
+	b.WriteString(regexp.QuoteMeta(pattern[pos:]))
+	b.WriteString("$")
+	return regexp.MustCompile(b.String()).MatchString(s)
+}
@@ -0,0 +1,477 @@
+// Package server — HTTP/JSON API для постановки задач агенту и наблюдения за ними.
+//
+//	POST /tasks                   {"task": "...", "start_url": "..."} → 202 {task}
+//	GET  /tasks                   список задач
+//	GET  /tasks/{id}              состояние и результат
+//	GET  /tasks/{id}/events?after=N события (long-poll: wait=30s; stream=1 — Server-Sent Events)
+//	GET  /events                  SSE-поток событий всех задач
+//	GET  /tasks/{id}/screenshot   PNG текущей страницы
+//	POST /tasks/{id}/cancel       отмена
+//	POST /tasks/{id}/answer       {"answer": "..."} — ответ на answer_or_ask_user
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"AIAgent/internal/agent"
+	"AIAgent/internal/events"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Server выполняет задачи из очереди последовательно на одной вкладке.
+type Server struct {
+	// Keep — сколько завершённых задач помнить; более старые забываются
+	// (GET по их id отвечает 404), чтобы долго работающий сервер не рос без предела.
+	Keep int
+
+	cfg agent.Config
+	bus *events.Bus
+
+	mu sync.Mutex
+	// page — вкладка, активная у агента; задача может переключить её.
+	page  playwright.Page
+	tasks map[string]*Task
+	order []string
+	seq   int
+	queue chan *Task
+}
+
+// defaultKeep — Keep по умолчанию.
+const defaultKeep = 256
+
+// screenshotTimeout ограничивает снимок экрана: зависшая страница не должна держать обработчик.
+var screenshotTimeout = 10 * time.Second
+
+func New(cfg agent.Config, page playwright.Page, queueSize int) *Server {
+	if queueSize <= 0 {
+		queueSize = 64
+	}
+	return &Server{
+		Keep:  defaultKeep,
+		cfg:   cfg,
+		page:  page,
+		bus:   events.NewBus(),
+		tasks: map[string]*Task{},
+		queue: make(chan *Task, queueSize),
+	}
+}
+
+// Work выполняет задачи из очереди до отмены ctx.
+func (s *Server) Work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-s.queue:
+			s.runTask(ctx, t)
+		}
+	}
+}
+
+func (s *Server) runTask(parent context.Context, t *Task) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	skip := false
+	t.update(func(t *Task) {
+		if t.State != StateQueued { // отменена, пока ждала в очереди
+			skip = true
+			return
+		}
+		t.State = StateRunning
+		t.Started = time.Now()
+		t.cancel = cancel
+	})
+	if skip {
+		return
+	}
+
+	bus := events.NewBus()
+	bus.Subscribe(t.record)
+	bus.Subscribe(s.bus.Publish)
+	bus.Subscribe(s.cfg.Events.Publish)
+
+	cfg := s.cfg
+	cfg.RunID = t.ID
+	cfg.Events = bus
+	cfg.AskUser = t.ask
+	cfg.OnPage = s.setPage
+	page := s.activePage()
+
+	var (
+		res agent.Result
+		err error
+	)
+	if t.StartURL != "" {
+		_, err = page.Goto(t.StartURL)
+	}
+	if err == nil {
+		res, err = agent.Run(ctx, cfg, page, t.Text)
+		if res.Page != nil {
+			s.setPage(res.Page)
+		}
+	} else {
+		res = agent.Result{Task: t.Text, Status: agent.StatusError, Errors: []string{err.Error()}}
+	}
+	t.update(func(t *Task) {
+		t.Result = &res
+		t.State = res.Status
+		t.Question = ""
+		t.Finished = time.Now()
+		t.cancel = nil
+	})
+	s.evict()
+}
+
+func (s *Server) setPage(p playwright.Page) {
+	s.mu.Lock()
+	s.page = p
+	s.mu.Unlock()
+}
+
+func (s *Server) activePage() playwright.Page {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.page
+}
+
+// Submit ставит задачу в очередь.
+func (s *Server) Submit(text, startURL string) (*Task, error) {
+	s.mu.Lock()
+	s.seq++
+	t := newTask(strconv.Itoa(s.seq), text, startURL)
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- t:
+	default:
+		return nil, errors.New("queue is full")
+	}
+
+	s.mu.Lock()
+	s.tasks[t.ID] = t
+	s.order = append(s.order, t.ID)
+	s.mu.Unlock()
+	s.evict()
+	return t, nil
+}
+
+// evict забывает самые старые завершённые задачи сверх Keep. Задачи в очереди
+// и выполняющиеся не трогаются.
+func (s *Server) evict() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	done := 0
+	for _, id := range s.order {
+		if s.tasks[id].done() {
+			done++
+		}
+	}
+	keep := s.order[:0]
+	for _, id := range s.order {
+		if done > s.Keep && s.tasks[id].done() {
+			delete(s.tasks, id)
+			done--
+			continue
+		}
+		keep = append(keep, id)
+	}
+	s.order = keep
+}
+
+func (s *Server) task(id string) *Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tasks[id]
+}
+
+// Handler возвращает маршруты API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tasks", s.handleSubmit)
+	mux.HandleFunc("GET /tasks", s.handleList)
+	mux.HandleFunc("GET /tasks/{id}", s.withTask(s.handleGet))
+	mux.HandleFunc("GET /tasks/{id}/events", s.withTask(s.handleEvents))
+	mux.HandleFunc("GET /events", s.handleAllEvents)
+	mux.HandleFunc("GET /tasks/{id}/screenshot", s.withTask(s.handleScreenshot))
+	mux.HandleFunc("POST /tasks/{id}/cancel", s.withTask(s.handleCancel))
+	mux.HandleFunc("POST /tasks/{id}/answer", s.withTask(s.handleAnswer))
+	return mux
+}
+
+func (s *Server) withTask(h func(http.ResponseWriter, *http.Request, *Task)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t := s.task(r.PathValue("id"))
+		if t == nil {
+			writeError(w, http.StatusNotFound, "task not found")
+			return
+		}
+		h(w, r, t)
+	}
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Task     string `json:"task"`
+		StartURL string `json:"start_url"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad json: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Task) == "" {
+		writeError(w, http.StatusBadRequest, "task is required")
+		return
+	}
+	t, err := s.Submit(strings.TrimSpace(req.Task), req.StartURL)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	w.Header().Set("Location", "/tasks/"+t.ID)
+	writeJSON(w, http.StatusAccepted, t.view())
+}
+
+func (s *Server) handleList(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	ids := append([]string(nil), s.order...)
+	s.mu.Unlock()
+	out := make([]taskView, 0, len(ids))
+	for _, id := range ids {
+		if t := s.task(id); t != nil {
+			out = append(out, t.view())
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, _ *http.Request, t *Task) {
+	writeJSON(w, http.StatusOK, t.view())
+}
+
+// Events возвращает шину событий всех задач сервера для пользовательских хуков.
+func (s *Server) Events() *events.Bus { return s.bus }
+
+// handleEvents отдаёт события задачи начиная с позиции after. С wait=<duration>
+// ждёт новых событий (long-poll), со stream=1 держит соединение как SSE до завершения задачи.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request, t *Task) {
+	q := r.URL.Query()
+	after, _ := strconv.Atoi(q.Get("after"))
+	if id, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		after = id
+	}
+	if after < 0 {
+		after = 0
+	}
+
+	if q.Get("stream") == "1" || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamEvents(w, r, t, after)
+		return
+	}
+
+	evs, changed, done := t.eventsAfter(after)
+	if wait, _ := time.ParseDuration(q.Get("wait")); len(evs) == 0 && !done && wait > 0 {
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+		evs, _, done = t.eventsAfter(after)
+	}
+	if evs == nil {
+		evs = []events.Event{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"events": evs,
+		"next":   after + len(evs),
+		"done":   done,
+	})
+}
+
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, t *Task, after int) {
+	sse := newSSE(w)
+	for {
+		evs, changed, done := t.eventsAfter(after)
+		for _, e := range evs {
+			after++
+			if err := sse.send(strconv.Itoa(after), e); err != nil {
+				return
+			}
+		}
+		if done {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleAllEvents транслирует события всех задач как SSE. Медленному
+// клиенту события не ставятся в очередь бесконечно: при переполнении буфера они отбрасываются.
+func (s *Server) handleAllEvents(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan events.Event, 256)
+	unsubscribe := s.bus.Subscribe(func(e events.Event) {
+		select {
+		case ch <- e:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	sse := newSSE(w)
+	for {
+		select {
+		case e := <-ch:
+			if err := sse.send("", e); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) handleScreenshot(w http.ResponseWriter, r *http.Request, t *Task) {
+	if v := t.view(); v.State != StateRunning && v.State != StateWaiting {
+		writeError(w, http.StatusConflict, "task is not running")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), screenshotTimeout)
+	defer cancel()
+	png, err := screenshot(ctx, s.activePage())
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeError(w, http.StatusGatewayTimeout, "screenshot timed out")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(png)
+}
+
+// screenshot снимает страницу с таймаутом Playwright из дедлайна ctx и
+// возвращается при отмене ctx, даже если вызов завис.
+func screenshot(ctx context.Context, page playwright.Page) ([]byte, error) {
+	var timeout *float64
+	if dl, ok := ctx.Deadline(); ok {
+		timeout = playwright.Float(float64(max(time.Until(dl).Milliseconds(), 1)))
+	}
+	type result struct {
+		png []byte
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		png, err := page.Screenshot(playwright.PageScreenshotOptions{Timeout: timeout})
+		ch <- result{png, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.png, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, _ *http.Request, t *Task) {
+	t.update(func(t *Task) {
+		switch {
+		case t.State == StateQueued:
+			t.State = agent.StatusCanceled
+			t.Finished = time.Now()
+		case t.cancel != nil:
+			t.cancel()
+		}
+	})
+	writeJSON(w, http.StatusAccepted, t.view())
+}
+
+func (s *Server) handleAnswer(w http.ResponseWriter, r *http.Request, t *Task) {
+	var req struct {
+		Answer string `json:"answer"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad json: "+err.Error())
+		return
+	}
+	if err := t.reply(req.Answer); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, t.view())
+}
+
+type sseWriter struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+func newSSE(w http.ResponseWriter) *sseWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	f, _ := w.(http.Flusher)
+	if f != nil {
+		f.Flush()
+	}
+	return &sseWriter{w: w, f: f}
+}
+
+func (s *sseWriter) send(id string, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		fmt.Fprintf(s.w, "id: %s\n", id)
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", e.Kind, data); err != nil {
+		return err
+	}
+	if s.f != nil {
+		s.f.Flush()
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+// ListenAndServe запускает обработчик очереди и HTTP-сервер до отмены ctx.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	go s.Work(ctx)
+	hs := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() { errc <- hs.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		return fmt.Errorf("serve %s: %w", addr, err)
+	case <-ctx.Done():
+		shCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return hs.Shutdown(shCtx)
+	}
+}
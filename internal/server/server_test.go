@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"AIAgent/internal/agent"
+	"AIAgent/internal/events"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// newTestServer — сервер без браузера и без обработчика очереди: задачи
+// остаются в очереди, и состояние можно менять из теста.
+func newTestServer(t *testing.T, queue int) (*Server, *httptest.Server) {
+	t.Helper()
+	s := New(agent.Config{}, nil, queue)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func do(t *testing.T, method, url, body string) (*http.Response, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp, out
+}
+
+func TestHandlers(t *testing.T) {
+	s, ts := newTestServer(t, 8)
+	if _, err := s.Submit("первая", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+		field  string
+		want   string
+	}{
+		{"submit", "POST", "/tasks", `{"task":" открой письмо ","start_url":"https://mail.example"}`, http.StatusAccepted, "task", "открой письмо"},
+		{"submit bad json", "POST", "/tasks", `{`, http.StatusBadRequest, "", ""},
+		{"submit empty task", "POST", "/tasks", `{"task":"  "}`, http.StatusBadRequest, "error", "task is required"},
+		{"get", "GET", "/tasks/1", "", http.StatusOK, "state", StateQueued},
+		{"get unknown", "GET", "/tasks/99", "", http.StatusNotFound, "error", "task not found"},
+		{"screenshot not running", "GET", "/tasks/1/screenshot", "", http.StatusConflict, "error", "task is not running"},
+		{"answer without question", "POST", "/tasks/1/answer", `{"answer":"да"}`, http.StatusConflict, "error", errNoQuestion.Error()},
+		{"cancel queued", "POST", "/tasks/1/cancel", "", http.StatusAccepted, "state", agent.StatusCanceled},
+		{"events of unknown task", "GET", "/tasks/99/events", "", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, out := do(t, tt.method, ts.URL+tt.path, tt.body)
+			if resp.StatusCode != tt.code {
+				t.Fatalf("status = %d, want %d (%v)", resp.StatusCode, tt.code, out)
+			}
+			if tt.field != "" && out[tt.field] != tt.want {
+				t.Errorf("%s = %v, want %q", tt.field, out[tt.field], tt.want)
+			}
+		})
+	}
+
+	resp, err := http.Get(ts.URL + "/tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list []taskView
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != "1" || list[1].ID != "2" || list[1].StartURL != "https://mail.example" {
+		t.Errorf("list = %+v", list)
+	}
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	_, ts := newTestServer(t, 1)
+	if resp, _ := do(t, "POST", ts.URL+"/tasks", `{"task":"a"}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("first submit: %d", resp.StatusCode)
+	}
+	resp, out := do(t, "POST", ts.URL+"/tasks", `{"task":"b"}`)
+	if resp.StatusCode != http.StatusServiceUnavailable || out["error"] != "queue is full" {
+		t.Errorf("second submit: %d %v", resp.StatusCode, out)
+	}
+}
+
+func TestEventsLongPoll(t *testing.T) {
+	s, ts := newTestServer(t, 8)
+	task, _ := s.Submit("задача", "")
+	task.record(events.Event{Kind: events.KindRunStarted, Step: 0})
+
+	_, out := do(t, "GET", ts.URL+"/tasks/1/events?after=0", "")
+	if evs, _ := out["events"].([]any); len(evs) != 1 || out["next"] != 1.0 || out["done"] != false {
+		t.Fatalf("events = %v", out)
+	}
+
+	// Новое событие приходит, пока клиент ждёт.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		task.record(events.Event{Kind: events.KindDecision, Step: 1})
+	}()
+	_, out = do(t, "GET", ts.URL+"/tasks/1/events?after=1&wait=5s", "")
+	evs, _ := out["events"].([]any)
+	if len(evs) != 1 || out["next"] != 2.0 {
+		t.Fatalf("long-poll events = %v", out)
+	}
+	if kind := evs[0].(map[string]any)["kind"]; kind != string(events.KindDecision) {
+		t.Errorf("kind = %v", kind)
+	}
+	_, out = do(t, "GET", ts.URL+"/tasks/1", "")
+	if out["step"] != 1.0 || out["events"] != 2.0 {
+		t.Errorf("task = %v", out)
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	s, ts := newTestServer(t, 8)
+	task, _ := s.Submit("задача", "")
+	task.update(func(t *Task) { t.State = StateRunning })
+
+	got := make(chan string, 1)
+	go func() {
+		a, err := task.ask(context.Background(), "какой ящик?")
+		if err != nil {
+			a = "error: " + err.Error()
+		}
+		got <- a
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for task.view().State != StateWaiting {
+		if time.Now().After(deadline) {
+			t.Fatal("task did not start waiting for an answer")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, out := do(t, "GET", ts.URL+"/tasks/1", ""); out["question"] != "какой ящик?" {
+		t.Errorf("question = %v", out["question"])
+	}
+	if resp, out := do(t, "POST", ts.URL+"/tasks/1/answer", `{"answer":"рабочий"}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("answer: %d %v", resp.StatusCode, out)
+	}
+	select {
+	case a := <-got:
+		if a != "рабочий" {
+			t.Errorf("ask returned %q", a)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ask did not return")
+	}
+	if st := task.view().State; st != StateRunning {
+		t.Errorf("state after answer = %s", st)
+	}
+}
+
+func TestEvictFinished(t *testing.T) {
+	s, ts := newTestServer(t, 8)
+	s.Keep = 2
+	finish := func(t *Task) { t.update(func(t *Task) { t.State = agent.StatusDone }) }
+	for i := 0; i < 4; i++ {
+		task, err := s.Submit("задача", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i != 1 { // вторая задача ещё в очереди и не забывается
+			finish(task)
+		}
+	}
+	if _, err := s.Submit("ещё одна", ""); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		id   string
+		code int
+	}{
+		{"1", http.StatusNotFound},
+		{"2", http.StatusOK},
+		{"3", http.StatusOK},
+		{"4", http.StatusOK},
+		{"5", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if resp, _ := do(t, "GET", ts.URL+"/tasks/"+tt.id, ""); resp.StatusCode != tt.code {
+			t.Errorf("GET /tasks/%s = %d, want %d", tt.id, resp.StatusCode, tt.code)
+		}
+	}
+	if len(s.order) != 4 {
+		t.Errorf("order = %v", s.order)
+	}
+}
+
+// hungPage — вкладка, у которой снимок экрана не возвращается.
+type hungPage struct {
+	playwright.Page
+	release chan struct{}
+}
+
+func (p hungPage) Screenshot(...playwright.PageScreenshotOptions) ([]byte, error) {
+	<-p.release
+	return nil, nil
+}
+
+func TestScreenshotTimeout(t *testing.T) {
+	defer func(d time.Duration) { screenshotTimeout = d }(screenshotTimeout)
+	screenshotTimeout = 50 * time.Millisecond
+
+	page := hungPage{release: make(chan struct{})}
+	defer close(page.release)
+	s := New(agent.Config{}, page, 1)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	task, _ := s.Submit("задача", "")
+	task.update(func(t *Task) { t.State = StateRunning })
+
+	resp, out := do(t, "GET", ts.URL+"/tasks/1/screenshot", "")
+	if resp.StatusCode != http.StatusGatewayTimeout || out["error"] != "screenshot timed out" {
+		t.Errorf("screenshot: %d %v", resp.StatusCode, out)
+	}
+}
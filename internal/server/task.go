@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"AIAgent/internal/agent"
+	"AIAgent/internal/events"
+)
+
+// Состояния задачи в очереди (дополняют agent.Status*).
+const (
+	StateQueued  = "queued"
+	StateRunning = "running"
+	StateWaiting = "waiting_answer"
+)
+
+var errNoQuestion = errors.New("task is not waiting for an answer")
+
+// Task — задача, поставленная через API.
+type Task struct {
+	mu sync.Mutex
+
+	ID       string
+	Text     string
+	StartURL string
+	State    string
+	Question string
+	Result   *agent.Result
+	Created  time.Time
+	Started  time.Time
+	Finished time.Time
+
+	events  []events.Event
+	step    int
+	changed chan struct{} // закрывается и пересоздаётся при каждом изменении
+	answer  chan string
+	cancel  context.CancelFunc
+}
+
+func newTask(id, text, startURL string) *Task {
+	return &Task{
+		ID:       id,
+		Text:     text,
+		StartURL: startURL,
+		State:    StateQueued,
+		Created:  time.Now(),
+		changed:  make(chan struct{}),
+		answer:   make(chan string, 1),
+	}
+}
+
+// taskView — JSON-представление задачи.
+type taskView struct {
+	ID       string        `json:"id"`
+	Task     string        `json:"task"`
+	StartURL string        `json:"start_url,omitempty"`
+	State    string        `json:"state"`
+	Question string        `json:"question,omitempty"`
+	Step     int           `json:"step"`
+	Events   int           `json:"events"`
+	Result   *agent.Result `json:"result,omitempty"`
+	Created  time.Time     `json:"created"`
+	Started  *time.Time    `json:"started,omitempty"`
+	Finished *time.Time    `json:"finished,omitempty"`
+}
+
+func (t *Task) view() taskView {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v := taskView{
+		ID: t.ID, Task: t.Text, StartURL: t.StartURL, State: t.State,
+		Question: t.Question, Step: t.step, Events: len(t.events), Result: t.Result, Created: t.Created,
+	}
+	if !t.Started.IsZero() {
+		s := t.Started
+		v.Started = &s
+	}
+	if !t.Finished.IsZero() {
+		f := t.Finished
+		v.Finished = &f
+	}
+	return v
+}
+
+// update меняет задачу под замком и будит ожидающих событий.
+func (t *Task) update(fn func(t *Task)) {
+	t.mu.Lock()
+	fn(t)
+	close(t.changed)
+	t.changed = make(chan struct{})
+	t.mu.Unlock()
+}
+
+func (t *Task) record(e events.Event) {
+	t.update(func(t *Task) {
+		t.events = append(t.events, e)
+		if e.Step > t.step {
+			t.step = e.Step
+		}
+	})
+}
+
+// eventsAfter возвращает события с позиции after, канал изменений и признак завершения.
+func (t *Task) eventsAfter(after int) ([]events.Event, <-chan struct{}, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []events.Event
+	if after < len(t.events) {
+		out = append(out, t.events[after:]...)
+	}
+	return out, t.changed, t.finished()
+}
+
+// done — finished под замком задачи.
+func (t *Task) done() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.finished()
+}
+
+func (t *Task) finished() bool {
+	return t.State != StateQueued && t.State != StateRunning && t.State != StateWaiting
+}
+
+// ask вызывается из цикла агента: публикует вопрос и ждёт ответа через API.
+func (t *Task) ask(ctx context.Context, question string) (string, error) {
+	t.update(func(t *Task) {
+		t.State = StateWaiting
+		t.Question = question
+	})
+	select {
+	case a := <-t.answer:
+		t.update(func(t *Task) {
+			t.State = StateRunning
+			t.Question = ""
+		})
+		return a, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (t *Task) reply(answer string) error {
+	t.mu.Lock()
+	waiting := t.State == StateWaiting
+	t.mu.Unlock()
+	if !waiting {
+		return errNoQuestion
+	}
+	select {
+	case t.answer <- answer:
+		return nil
+	default:
+		return errNoQuestion
+	}
+}
@@ -0,0 +1,123 @@
+package skill
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"AIAgent/internal/redact"
+	"AIAgent/internal/trajectory"
+	"AIAgent/internal/workflow"
+)
+
+// FromTrajectory строит навык из успешных действий записанного запуска.
+// params (имя → значение) превращают конкретные значения в параметры:
+// каждое вхождение значения в аргументах заменяется на ${имя}, а само
+// значение становится значением параметра по умолчанию. Токены редактора
+// ([EMAIL_1]) заменяются обязательными параметрами (${email_1}): настоящее
+// значение в записи не сохранилось, его передаёт вызывающий навык.
+func FromTrajectory(t *trajectory.Trajectory, name, description string, params map[string]string) (*Skill, error) {
+	sk := &Skill{Name: name, Description: description}
+	if sk.Description == "" {
+		sk.Description = t.Meta.Task
+	}
+	names := make([]string, 0, len(params))
+	for n := range params {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		sk.Params = append(sk.Params, Param{Name: n, Default: params[n]})
+	}
+	// Длинные значения подставляются первыми, чтобы не разрезать их короткими.
+	sort.Slice(names, func(i, j int) bool { return len(params[names[i]]) > len(params[names[j]]) })
+	param := func(s string) string {
+		for _, n := range names {
+			if v := params[n]; v != "" {
+				s = strings.ReplaceAll(s, v, "${"+n+"}")
+			}
+		}
+		for _, tok := range redact.Tokens(s) {
+			n := tokenParam(tok)
+			if !slices.ContainsFunc(sk.Params, func(p Param) bool { return p.Name == n }) {
+				sk.Params = append(sk.Params, Param{Name: n, Description: "скрыто в записи как " + tok, Required: true})
+			}
+			s = strings.ReplaceAll(s, tok, "${"+n+"}")
+		}
+		return s
+	}
+	var paramArgs func(v any) any
+	paramArgs = func(v any) any {
+		switch x := v.(type) {
+		case string:
+			return param(x)
+		case map[string]any:
+			out := make(map[string]any, len(x))
+			for k, e := range x {
+				out[k] = paramArgs(e)
+			}
+			return out
+		case []any:
+			out := make([]any, len(x))
+			for i, e := range x {
+				out[i] = paramArgs(e)
+			}
+			return out
+		}
+		return v
+	}
+
+	for _, st := range t.Steps {
+		if st.Error != "" {
+			continue
+		}
+		a := st.Action
+		str := func(k string) string {
+			s, _ := a.Args[k].(string)
+			return param(s)
+		}
+		var ws workflow.Step
+		switch a.Tool {
+		case "goto_url":
+			ws.Goto = str("url")
+		case "click":
+			ws.Click = &workflow.Element{Target: workflow.Target{Selector: str("selector")}}
+		case "type":
+			enter, _ := a.Args["pressEnter"].(bool)
+			ws.Type = &workflow.Input{Target: workflow.Target{Selector: str("selector")}, Value: str("text"), Enter: enter}
+		case "press":
+			ws.Press = str("key")
+			if ws.Press == "" {
+				ws.Press = "Escape"
+			}
+		case "scroll":
+			sc := &workflow.Scroll{Target: workflow.Target{Selector: str("selector")}}
+			sc.Y, _ = a.Args["y"].(float64)
+			ws.Scroll = sc
+		case "answer_or_ask_user", "extract":
+			// Ответ пользователю и чтение страницы не меняют её состояние.
+			continue
+		default:
+			args, _ := paramArgs(a.Args).(map[string]any)
+			ws.Tool = &workflow.ToolCall{Name: a.Tool, Args: args}
+		}
+		if c := strings.TrimSpace(a.Comment); c != "" {
+			ws.Name = c
+		}
+		sk.Steps = append(sk.Steps, ws)
+	}
+	if len(sk.Steps) == 0 {
+		return nil, errors.New("skill: no successful actions in trajectory")
+	}
+	if err := sk.Validate(); err != nil {
+		return nil, fmt.Errorf("skill: %w", err)
+	}
+	return sk, nil
+}
+
+// tokenParam — имя параметра для токена: [EMAIL_1] → email_1, [CONTRACT-ID_2] → contract_id_2.
+func tokenParam(tok string) string {
+	return strings.ToLower(strings.ReplaceAll(tok[1:len(tok)-1], "-", "_"))
+}
@@ -0,0 +1,85 @@
+package skill
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"AIAgent/internal/trajectory"
+	"AIAgent/internal/workflow"
+)
+
+func TestFromTrajectory(t *testing.T) {
+	tr := &trajectory.Trajectory{
+		Meta: trajectory.Meta{Task: "переслать письмо из папки Спам"},
+		Steps: []trajectory.Step{
+			{Action: trajectory.Action{Tool: "click", Args: map[string]any{"selector": "role=link[name=\"Спам\"]"}, Comment: " открываем Спам "}},
+			{Action: trajectory.Action{Tool: "click", Args: map[string]any{"selector": "#missing"}}, Error: "timeout"},
+			{Action: trajectory.Action{Tool: "extract"}},
+			{Action: trajectory.Action{Tool: "type", Args: map[string]any{"selector": "#to", "text": "[EMAIL_1], [EMAIL_2]", "pressEnter": true}}},
+			{Action: trajectory.Action{Tool: "press"}},
+			{Action: trajectory.Action{Tool: "send_sms", Args: map[string]any{
+				"to":   "[PHONE_1]",
+				"body": map[string]any{"text": "копия [EMAIL_1] из Спам"},
+				"n":    2.0,
+			}}},
+		},
+	}
+	sk, err := FromTrajectory(tr, "forward_spam", "", map[string]string{"folder": "Спам"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sk.Description != tr.Meta.Task {
+		t.Errorf("Description = %q", sk.Description)
+	}
+	wantParams := []Param{
+		{Name: "folder", Default: "Спам"},
+		{Name: "email_1", Description: "скрыто в записи как [EMAIL_1]", Required: true},
+		{Name: "email_2", Description: "скрыто в записи как [EMAIL_2]", Required: true},
+		{Name: "phone_1", Description: "скрыто в записи как [PHONE_1]", Required: true},
+	}
+	if !reflect.DeepEqual(sk.Params, wantParams) {
+		t.Errorf("Params = %+v, want %+v", sk.Params, wantParams)
+	}
+	wantSteps := []workflow.Step{
+		{Name: "открываем Спам", Click: &workflow.Element{Target: workflow.Target{Selector: "role=link[name=\"${folder}\"]"}}},
+		{Type: &workflow.Input{Target: workflow.Target{Selector: "#to"}, Value: "${email_1}, ${email_2}", Enter: true}},
+		{Press: "Escape"},
+		{Tool: &workflow.ToolCall{Name: "send_sms", Args: map[string]any{
+			"to":   "${phone_1}",
+			"body": map[string]any{"text": "копия ${email_1} из ${folder}"},
+			"n":    2.0,
+		}}},
+	}
+	if !reflect.DeepEqual(sk.Steps, wantSteps) {
+		t.Errorf("Steps:\n got %+v\nwant %+v", sk.Steps, wantSteps)
+	}
+	// Навык со скрытыми значениями без параметров не запускается.
+	if _, err := sk.Vars(map[string]any{"folder": "Входящие"}); err == nil || !strings.Contains(err.Error(), "missing param email_1") {
+		t.Errorf("Vars without redacted params: %v", err)
+	}
+}
+
+func TestFromTrajectoryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		skill string
+		steps []trajectory.Step
+		want  string
+	}{
+		{"only failed and read-only steps", "s", []trajectory.Step{
+			{Action: trajectory.Action{Tool: "click", Args: map[string]any{"selector": "#a"}}, Error: "timeout"},
+			{Action: trajectory.Action{Tool: "answer_or_ask_user"}},
+		}, "no successful actions"},
+		{"bad name", "Bad-Name", []trajectory.Step{{Action: trajectory.Action{Tool: "press", Args: map[string]any{"key": "Enter"}}}}, "name must match"},
+		{"click without selector", "s", []trajectory.Step{{Action: trajectory.Action{Tool: "click"}}}, "click: no target"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromTrajectory(&trajectory.Trajectory{Steps: tt.steps}, tt.skill, "d", nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
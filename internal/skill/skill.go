@@ -0,0 +1,245 @@
+// Package skill — библиотека именованных навыков: параметризованных
+// последовательностей шагов сценария (см. internal/workflow) с пред- и
+// постусловиями. Планировщик вызывает навык одним действием run_skill.
+//
+// Навыки лежат YAML-файлами в каталоге библиотеки (по умолчанию
+// ~/.aiagent/skills); встроенные навыки можно переопределить файлом с тем же
+// именем.
+//
+//	name: go_to_folder
+//	description: открыть папку почты по названию
+//	params:
+//	  - {name: folder, required: true}
+//	steps:
+//	  - click: {role: link, name: "${folder}"}
+//	post: {title_contains: "${folder}"}
+package skill
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"AIAgent/internal/workflow"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Skill — один навык.
+type Skill struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description"`
+	Params      []Param `yaml:"params,omitempty"`
+	// Pre проверяется перед шагами: навык неприменим на этой странице.
+	Pre *workflow.Assert `yaml:"pre,omitempty"`
+	// Post проверяется после шагов: навык не достиг результата.
+	Post  *workflow.Assert `yaml:"post,omitempty"`
+	Steps []workflow.Step  `yaml:"steps"`
+	// Source — файл, из которого загружен навык ("builtin" для встроенных).
+	Source string `yaml:"-"`
+}
+
+// Param — параметр навыка, доступный в шагах как ${name}.
+type Param struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+	Default     string `yaml:"default,omitempty"`
+}
+
+var reName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Validate проверяет имя, параметры и шаги навыка.
+func (s *Skill) Validate() error {
+	if !reName.MatchString(s.Name) {
+		return fmt.Errorf("skill %q: name must match %s", s.Name, reName)
+	}
+	seen := map[string]bool{}
+	for _, p := range s.Params {
+		if p.Name == "" || seen[p.Name] {
+			return fmt.Errorf("skill %s: bad or duplicate param %q", s.Name, p.Name)
+		}
+		seen[p.Name] = true
+	}
+	if err := workflow.Validate(s.Steps); err != nil {
+		return fmt.Errorf("skill %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// Vars собирает значения параметров: переданные, затем значения по умолчанию.
+// Неизвестные параметры и отсутствие обязательных — ошибка.
+func (s *Skill) Vars(params map[string]any) (map[string]string, error) {
+	vars := map[string]string{}
+	known := map[string]bool{}
+	for _, p := range s.Params {
+		known[p.Name] = true
+		if v, ok := params[p.Name]; ok && v != nil {
+			vars[p.Name] = fmt.Sprint(v)
+			continue
+		}
+		if p.Required {
+			return nil, fmt.Errorf("skill %s: missing param %s", s.Name, p.Name)
+		}
+		vars[p.Name] = p.Default
+	}
+	for k := range params {
+		if !known[k] {
+			return nil, fmt.Errorf("skill %s: unknown param %s", s.Name, k)
+		}
+	}
+	return vars, nil
+}
+
+// Usage — имя с параметрами: name(folder*, query); * — обязательный.
+func (s *Skill) Usage() string {
+	var ps []string
+	for _, p := range s.Params {
+		n := p.Name
+		if p.Required {
+			n += "*"
+		}
+		ps = append(ps, n)
+	}
+	return fmt.Sprintf("%s(%s)", s.Name, strings.Join(ps, ", "))
+}
+
+// Signature — строка для промпта: name(folder*, query): описание.
+func (s *Skill) Signature() string {
+	return s.Usage() + ": " + s.Description
+}
+
+// Parse разбирает и проверяет навык.
+func Parse(b []byte) (*Skill, error) {
+	var s Skill
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Marshal сериализует навык в YAML.
+func Marshal(s *Skill) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+//go:embed builtin/*.yaml
+var builtinFS embed.FS
+
+// Library — набор навыков: встроенные плюс файлы из Dir.
+type Library struct {
+	Dir    string
+	skills map[string]*Skill
+}
+
+// ErrNotFound — навыка с таким именем нет.
+var ErrNotFound = errors.New("skill not found")
+
+// Open загружает встроенные навыки и *.yaml из dir (отсутствующий каталог — не ошибка).
+func Open(dir string) (*Library, error) {
+	l := &Library{Dir: dir, skills: map[string]*Skill{}}
+	builtin, _ := fs.Glob(builtinFS, "builtin/*.yaml")
+	for _, name := range builtin {
+		b, _ := builtinFS.ReadFile(name)
+		s, err := Parse(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		s.Source = "builtin"
+		l.skills[s.Name] = s
+	}
+	if dir == "" {
+		return l, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		s, err := Parse(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		s.Source = f
+		l.skills[s.Name] = s
+	}
+	return l, nil
+}
+
+// Get возвращает навык по имени.
+func (l *Library) Get(name string) (*Skill, error) {
+	if l != nil {
+		if s, ok := l.skills[name]; ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// List — навыки в алфавитном порядке.
+func (l *Library) List() []*Skill {
+	if l == nil {
+		return nil
+	}
+	out := make([]*Skill, 0, len(l.skills))
+	for _, s := range l.skills {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Save записывает навык в Dir/<name>.yaml и добавляет его в библиотеку.
+func (l *Library) Save(s *Skill) (string, error) {
+	if err := s.Validate(); err != nil {
+		return "", err
+	}
+	if l.Dir == "" {
+		return "", errors.New("skill: library dir is not set")
+	}
+	if err := os.MkdirAll(l.Dir, 0o755); err != nil {
+		return "", err
+	}
+	b, err := Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(l.Dir, s.Name+".yaml")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return "", err
+	}
+	s.Source = path
+	l.skills[s.Name] = s
+	return path, nil
+}
+
+// DefaultDir — ~/.aiagent/skills.
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".aiagent", "skills"), nil
+}
@@ -0,0 +1,43 @@
+package skill
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVars(t *testing.T) {
+	sk := &Skill{Name: "search", Params: []Param{
+		{Name: "query", Required: true},
+		{Name: "folder", Default: "Входящие"},
+	}}
+	tests := []struct {
+		name    string
+		params  map[string]any
+		want    map[string]string
+		wantErr string
+	}{
+		{"defaults", map[string]any{"query": "счёт"}, map[string]string{"query": "счёт", "folder": "Входящие"}, ""},
+		{"override default", map[string]any{"query": "счёт", "folder": "Спам"}, map[string]string{"query": "счёт", "folder": "Спам"}, ""},
+		{"non-string value", map[string]any{"query": 42.0}, map[string]string{"query": "42", "folder": "Входящие"}, ""},
+		{"nil is missing", map[string]any{"query": nil}, nil, "skill search: missing param query"},
+		{"missing required", nil, nil, "skill search: missing param query"},
+		{"unknown param", map[string]any{"query": "x", "limit": 3}, nil, "skill search: unknown param limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sk.Vars(tt.params)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Vars = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
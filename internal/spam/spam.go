@@ -0,0 +1,285 @@
+// Package spam — локальный наивный байесовский классификатор писем.
+// Признаки берутся из отправителя, темы и текста; модель обучается на
+// размеченных пользователем примерах, которые хранятся в
+// <dir>/examples.jsonl, плюс небольшой встроенный набор для холодного старта.
+// Метки произвольные: кроме spam и ham можно размечать, например, important.
+package spam
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Стандартные метки.
+const (
+	Spam = "spam"
+	Ham  = "ham"
+)
+
+// Example — размеченное письмо (для Score метка не нужна).
+type Example struct {
+	Label    string    `json:"label,omitempty"`
+	From     string    `json:"from,omitempty"`
+	FromAddr string    `json:"from_addr,omitempty"`
+	Subject  string    `json:"subject,omitempty"`
+	Body     string    `json:"body,omitempty"`
+	Added    time.Time `json:"added,omitempty"`
+}
+
+// Verdict — результат классификации.
+type Verdict struct {
+	Label string `json:"label"`
+	// Confidence — апостериорная вероятность метки.
+	Confidence float64            `json:"confidence"`
+	Scores     map[string]float64 `json:"scores"`
+	// Reasons — признаки письма, сильнее всего повлиявшие на выбор метки.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Model — мультиномиальный наивный Байес со сглаживанием Лапласа.
+type Model struct {
+	classes map[string]*class
+	vocab   map[string]struct{}
+	docs    int
+}
+
+type class struct {
+	docs   int
+	tokens int
+	counts map[string]int
+}
+
+// Train строит модель по примерам.
+func Train(examples []Example) *Model {
+	m := &Model{classes: map[string]*class{}, vocab: map[string]struct{}{}}
+	for _, ex := range examples {
+		if ex.Label == "" {
+			continue
+		}
+		c := m.classes[ex.Label]
+		if c == nil {
+			c = &class{counts: map[string]int{}}
+			m.classes[ex.Label] = c
+		}
+		c.docs++
+		m.docs++
+		for _, f := range Features(ex) {
+			c.counts[f]++
+			c.tokens++
+			m.vocab[f] = struct{}{}
+		}
+	}
+	return m
+}
+
+// Labels — известные модели метки.
+func (m *Model) Labels() map[string]int {
+	out := map[string]int{}
+	for l, c := range m.classes {
+		out[l] = c.docs
+	}
+	return out
+}
+
+func (m *Model) logLikelihood(c *class, f string) float64 {
+	return math.Log(float64(c.counts[f]+1) / float64(c.tokens+len(m.vocab)+1))
+}
+
+// Score классифицирует письмо. Пустая модель отвечает ham с нулевой уверенностью.
+func (m *Model) Score(ex Example) Verdict {
+	if m.docs == 0 {
+		return Verdict{Label: Ham, Scores: map[string]float64{}}
+	}
+	feats := Features(ex)
+	logp := map[string]float64{}
+	for l, c := range m.classes {
+		lp := math.Log(float64(c.docs) / float64(m.docs))
+		for _, f := range feats {
+			lp += m.logLikelihood(c, f)
+		}
+		logp[l] = lp
+	}
+
+	// Нормировка через log-sum-exp.
+	maxLP := math.Inf(-1)
+	for _, lp := range logp {
+		maxLP = math.Max(maxLP, lp)
+	}
+	var sum float64
+	for _, lp := range logp {
+		sum += math.Exp(lp - maxLP)
+	}
+	v := Verdict{Scores: map[string]float64{}}
+	for l, lp := range logp {
+		p := math.Exp(lp-maxLP) / sum
+		v.Scores[l] = round(p)
+		if p > v.Confidence || (p == v.Confidence && l < v.Label) {
+			v.Label, v.Confidence = l, p
+		}
+	}
+	v.Confidence = round(v.Confidence)
+	v.Reasons = m.reasons(feats, v.Label)
+	return v
+}
+
+// reasons — до трёх признаков с наибольшим отношением правдоподобия
+// выбранной метки к остальным.
+func (m *Model) reasons(feats []string, label string) []string {
+	win := m.classes[label]
+	type contrib struct {
+		f string
+		w float64
+	}
+	seen := map[string]bool{}
+	var cs []contrib
+	for _, f := range feats {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		if _, known := m.vocab[f]; !known {
+			continue
+		}
+		best := math.Inf(-1)
+		for l, c := range m.classes {
+			if l != label {
+				best = math.Max(best, m.logLikelihood(c, f))
+			}
+		}
+		if w := m.logLikelihood(win, f) - best; w > 0.5 {
+			cs = append(cs, contrib{f, w})
+		}
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].w > cs[j].w })
+	var out []string
+	for i := 0; i < len(cs) && i < 3; i++ {
+		out = append(out, Describe(cs[i].f))
+	}
+	return out
+}
+
+func round(p float64) float64 { return math.Round(p*1000) / 1000 }
+
+// bodyChars — сколько текста письма учитывать.
+const bodyChars = 2000
+
+// Features — признаки письма: f: отправитель, s: слова темы, b: слова текста,
+// x: флаги (ссылки, деньги, капслок, восклицания, noreply).
+func Features(ex Example) []string {
+	var fs []string
+	addr := strings.ToLower(strings.TrimSpace(ex.FromAddr))
+	if addr == "" && strings.Contains(ex.From, "@") {
+		addr = strings.ToLower(strings.Trim(lastField(ex.From), "<>"))
+	}
+	if addr != "" {
+		fs = append(fs, "f:"+addr)
+		if i := strings.LastIndexByte(addr, '@'); i >= 0 {
+			fs = append(fs, "d:"+addr[i+1:])
+		}
+		if strings.Contains(addr, "noreply") || strings.Contains(addr, "no-reply") {
+			fs = append(fs, "x:noreply")
+		}
+	}
+	for _, w := range words(ex.From) {
+		if !strings.Contains(w, "@") {
+			fs = append(fs, "n:"+w)
+		}
+	}
+	for _, w := range words(ex.Subject) {
+		fs = append(fs, "s:"+w)
+	}
+	body := ex.Body
+	if len(body) > bodyChars {
+		body = body[:bodyChars]
+	}
+	for _, w := range words(body) {
+		fs = append(fs, "b:"+w)
+	}
+	all := strings.ToLower(ex.Subject + " " + body)
+	if strings.Contains(all, "http://") || strings.Contains(all, "https://") || strings.Contains(all, "www.") {
+		fs = append(fs, "x:url")
+	}
+	if strings.ContainsAny(all, "₽$€") || strings.Contains(all, "руб") {
+		fs = append(fs, "x:money")
+	}
+	if strings.Contains(ex.Subject, "!") {
+		fs = append(fs, "x:excl")
+	}
+	if capsRatio(ex.Subject) > 0.6 {
+		fs = append(fs, "x:caps")
+	}
+	return fs
+}
+
+// Describe переводит признак в понятное человеку объяснение.
+func Describe(f string) string {
+	kind, v, _ := strings.Cut(f, ":")
+	switch kind {
+	case "f":
+		return "отправитель " + v
+	case "d":
+		return "домен отправителя " + v
+	case "n":
+		return "имя отправителя «" + v + "»"
+	case "s":
+		return "слово «" + v + "» в теме"
+	case "b":
+		return "слово «" + v + "» в тексте"
+	}
+	switch f {
+	case "x:url":
+		return "ссылки в письме"
+	case "x:money":
+		return "упоминание денег"
+	case "x:excl":
+		return "восклицательный знак в теме"
+	case "x:caps":
+		return "тема заглавными буквами"
+	case "x:noreply":
+		return "адрес noreply"
+	}
+	return f
+}
+
+func words(s string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '@' && r != '.' && r != '-'
+	}) {
+		w = strings.Trim(w, ".-")
+		if w == "http" || w == "https" || w == "www" {
+			continue // ссылки учитываются флагом x:url
+		}
+		if n := len([]rune(w)); n >= 3 && n <= 30 {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func lastField(s string) string {
+	f := strings.Fields(s)
+	if len(f) == 0 {
+		return ""
+	}
+	return f[len(f)-1]
+}
+
+func capsRatio(s string) float64 {
+	var letters, upper int
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters < 6 {
+		return 0
+	}
+	return float64(upper) / float64(letters)
+}
@@ -0,0 +1,155 @@
+package spam
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	tests := []struct {
+		name string
+		ex   Example
+		has  []string
+		not  []string
+	}{
+		{
+			"address from display name",
+			Example{From: "Промо <Promo@Deals.example>"},
+			[]string{"f:promo@deals.example", "d:deals.example", "n:промо"},
+			[]string{"x:noreply"},
+		},
+		{
+			"flags",
+			Example{FromAddr: "no-reply@shop.example", Subject: "СКИДКИ ВСЕМ!", Body: "всего 100 руб на https://shop.example"},
+			[]string{"x:noreply", "x:url", "x:money", "x:excl", "x:caps", "s:скидки", "b:руб", "b:shop.example"},
+			[]string{"b:https", "b:на"},
+		},
+		{
+			"short subject is not caps",
+			Example{Subject: "ОК"},
+			nil,
+			[]string{"x:caps"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := Features(tt.ex)
+			for _, f := range tt.has {
+				if !slices.Contains(fs, f) {
+					t.Errorf("missing %q in %v", f, fs)
+				}
+			}
+			for _, f := range tt.not {
+				if slices.Contains(fs, f) {
+					t.Errorf("unexpected %q in %v", f, fs)
+				}
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	c, err := Open("", 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		ex    Example
+		label string
+	}{
+		{"prize", Example{FromAddr: "promo@win.example", Subject: "ВЫ ВЫИГРАЛИ ПРИЗ!!!", Body: "Перейдите по ссылке https://win.example и подтвердите данные карты"}, Spam},
+		{"colleague", Example{From: "Ольга Смирнова", FromAddr: "olga@company.example", Subject: "Встреча завтра", Body: "Давай обсудим отчёт завтра в 11, я забронирую переговорную"}, Ham},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.Score(tt.ex)
+			if v.Label != tt.label {
+				t.Errorf("Label = %s (%v), want %s", v.Label, v.Scores, tt.label)
+			}
+			if v.Confidence < 0.5 || v.Confidence > 1 {
+				t.Errorf("Confidence = %v", v.Confidence)
+			}
+			if len(v.Reasons) == 0 || len(v.Reasons) > 3 {
+				t.Errorf("Reasons = %v", v.Reasons)
+			}
+		})
+	}
+}
+
+func TestEmptyModel(t *testing.T) {
+	v := Train(nil).Score(Example{Subject: "что угодно"})
+	if v.Label != Ham || v.Confidence != 0 {
+		t.Errorf("Score = %+v", v)
+	}
+}
+
+func TestLearn(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spam")
+	c, err := Open(dir, 0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := Example{From: "Дайджест", FromAddr: "digest@news.example", Subject: "Новости недели", Body: "Главное за неделю в нашем сообществе"}
+	if err := c.Learn(Example{From: digest.From}); err == nil {
+		t.Error("Learn accepted an example without label")
+	}
+	for i := 0; i < 3; i++ {
+		ex := digest
+		ex.Label = "newsletter"
+		if err := c.Learn(ex); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := c.Score(digest); v.Label != "newsletter" {
+		t.Errorf("after Learn: Label = %s (%v)", v.Label, v.Scores)
+	}
+	_, user := c.Stats()
+	if !reflect.DeepEqual(user, map[string]int{"newsletter": 3}) {
+		t.Errorf("user stats = %v", user)
+	}
+
+	// Примеры переживают перезапуск.
+	c2, err := Open(dir, 0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c2.Score(digest); v.Label != "newsletter" {
+		t.Errorf("after reopen: Label = %s", v.Label)
+	}
+	total, _ := c2.Stats()
+	if total["newsletter"] != 3 || total[Spam] == 0 || total[Ham] == 0 {
+		t.Errorf("total stats = %v", total)
+	}
+}
+
+func TestProposeDelete(t *testing.T) {
+	c := &Classifier{Threshold: 0.9}
+	tests := []struct {
+		v    Verdict
+		want bool
+	}{
+		{Verdict{Label: Spam, Confidence: 0.95}, true},
+		{Verdict{Label: Spam, Confidence: 0.9}, true},
+		{Verdict{Label: Spam, Confidence: 0.6}, false},
+		{Verdict{Label: Ham, Confidence: 0.99}, false},
+	}
+	for _, tt := range tests {
+		if got := c.ProposeDelete(tt.v); got != tt.want {
+			t.Errorf("ProposeDelete(%+v) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestOpenBadExamples(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "examples.jsonl"), []byte("{\"label\":\"spam\"}\n\n{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir, 0.9); err == nil {
+		t.Error("Open accepted a broken examples.jsonl")
+	}
+}
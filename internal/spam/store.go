@@ -0,0 +1,146 @@
+package spam
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//go:embed seed.jsonl
+var seed []byte
+
+// Classifier — модель вместе с хранилищем примеров; Learn сразу дообучает её.
+type Classifier struct {
+	// Dir — каталог с examples.jsonl ("" — только встроенные примеры).
+	Dir string
+	// Threshold — уверенность, с которой письмо с меткой spam предлагается удалить.
+	Threshold float64
+
+	mu    sync.Mutex
+	user  []Example
+	model *Model
+}
+
+// Open читает примеры пользователя и обучает модель.
+func Open(dir string, threshold float64) (*Classifier, error) {
+	c := &Classifier{Dir: dir, Threshold: threshold}
+	if dir != "" {
+		var err error
+		if c.user, err = readExamples(filepath.Join(dir, "examples.jsonl")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	c.retrain()
+	return c, nil
+}
+
+// Seed — встроенные примеры для холодного старта.
+func Seed() []Example {
+	ex, _ := parseExamples(seed)
+	return ex
+}
+
+func (c *Classifier) retrain() {
+	c.model = Train(append(Seed(), c.user...))
+}
+
+// Score классифицирует письмо.
+func (c *Classifier) Score(ex Example) Verdict {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.model.Score(ex)
+}
+
+// ProposeDelete — письмо уверенно признано спамом.
+func (c *Classifier) ProposeDelete(v Verdict) bool {
+	return v.Label == Spam && v.Confidence >= c.Threshold
+}
+
+// Learn сохраняет размеченный пример и дообучает модель.
+func (c *Classifier) Learn(ex Example) error {
+	if ex.Label == "" {
+		return errors.New("spam: example without label")
+	}
+	if ex.Added.IsZero() {
+		ex.Added = time.Now()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Dir != "" {
+		if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(filepath.Join(c.Dir, "examples.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		b, _ := json.Marshal(ex)
+		_, err = f.Write(append(b, '\n'))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	c.user = append(c.user, ex)
+	c.retrain()
+	return nil
+}
+
+// Stats — число примеров по меткам: всего и размеченных пользователем.
+func (c *Classifier) Stats() (total, user map[string]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	user = map[string]int{}
+	for _, ex := range c.user {
+		user[ex.Label]++
+	}
+	return c.model.Labels(), user
+}
+
+func readExamples(path string) ([]Example, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ex, err := parseExamples(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ex, nil
+}
+
+func parseExamples(b []byte) ([]Example, error) {
+	var out []Example
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var ex Example
+		if err := json.Unmarshal(line, &ex); err != nil {
+			return out, fmt.Errorf("line %d: %w", n, err)
+		}
+		out = append(out, ex)
+	}
+	return out, sc.Err()
+}
+
+// DefaultDir — ~/.aiagent/spam.
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".aiagent", "spam"), nil
+}
@@ -0,0 +1,211 @@
+// Package trajectory записывает ход запуска агента (наблюдения, промпты,
+// сырые ответы LLM, действия, результаты, скриншоты) в каталог
+// <dir>/<run-id>/ и читает его обратно для воспроизведения.
+//
+// Формат каталога:
+//
+//	meta.json          — задача, стартовая страница, модель, итог
+//	steps.jsonl        — по записи Step на строку
+//	screenshots/NNN.png — скриншот после действия с порядковым номером NNN
+package trajectory
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"AIAgent/internal/dom"
+)
+
+// Meta — заголовок траектории.
+type Meta struct {
+	RunID    string    `json:"run_id"`
+	Task     string    `json:"task"`
+	StartURL string    `json:"start_url"`
+	Model    string    `json:"model,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitempty"`
+	Status   string    `json:"status,omitempty"`
+	Answer   string    `json:"answer,omitempty"`
+	Steps    int       `json:"steps"`
+}
+
+// PageState — состояние страницы до или после действия.
+type PageState struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Hash — отпечаток текстового снимка страницы (как в проверке прогресса).
+	Hash     string `json:"hash"`
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// Action — выбранное действие.
+type Action struct {
+	Tool    string         `json:"tool"`
+	Args    map[string]any `json:"args,omitempty"`
+	Comment string         `json:"comment,omitempty"`
+	// Source: llm, heuristic, operator или fallback.
+	Source string `json:"source"`
+}
+
+// Step — одна запись steps.jsonl.
+type Step struct {
+	// Seq — сквозной номер действия; Step — номер шага агента (на одном шаге
+	// может быть несколько действий, например принудительный fallback).
+	Seq        int             `json:"seq"`
+	Step       int             `json:"step"`
+	Time       time.Time       `json:"time"`
+	Before     PageState       `json:"before"`
+	Candidates []dom.Candidate `json:"candidates,omitempty"`
+	Prompt     string          `json:"prompt,omitempty"`
+	Response   string          `json:"response,omitempty"`
+	Action     Action          `json:"action"`
+	Result     string          `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	After      PageState       `json:"after"`
+	Screenshot string          `json:"screenshot,omitempty"`
+}
+
+// Trajectory — прочитанная с диска траектория.
+type Trajectory struct {
+	Dir   string
+	Meta  Meta
+	Steps []Step
+}
+
+// Recorder дописывает шаги в каталог траектории.
+type Recorder struct {
+	mu   sync.Mutex
+	dir  string
+	meta Meta
+	f    *os.File
+	w    *bufio.Writer
+	seq  int
+}
+
+// DefaultDir — ~/.aiagent/trajectories.
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".aiagent", "trajectories"), nil
+}
+
+// Create открывает новую траекторию в root/<meta.RunID>.
+func Create(root string, meta Meta) (*Recorder, error) {
+	if meta.RunID == "" {
+		return nil, fmt.Errorf("trajectory: empty run id")
+	}
+	dir := filepath.Join(root, meta.RunID)
+	if err := os.MkdirAll(filepath.Join(dir, "screenshots"), 0o700); err != nil {
+		return nil, err
+	}
+	// При продолжении запуска (resume) шаги дописываются в тот же файл.
+	f, err := os.OpenFile(filepath.Join(dir, "steps.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{dir: dir, meta: meta, f: f, w: bufio.NewWriter(f)}
+	if old, err := Load(dir); err == nil {
+		if !old.Meta.Started.IsZero() {
+			r.meta.Started, r.meta.StartURL = old.Meta.Started, old.Meta.StartURL
+		}
+		if n := len(old.Steps); n > 0 {
+			r.seq = old.Steps[n-1].Seq
+		}
+	}
+	if err := r.writeMeta(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) Dir() string { return r.dir }
+
+// Record дописывает действие, присваивая ему Seq. png (если есть) сохраняется как скриншот.
+func (r *Recorder) Record(st Step, png []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	st.Seq = r.seq
+	if len(png) > 0 {
+		name := filepath.Join("screenshots", fmt.Sprintf("%03d.png", st.Seq))
+		if err := os.WriteFile(filepath.Join(r.dir, name), png, 0o600); err == nil {
+			st.Screenshot = name
+		}
+	}
+	js, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if _, err := r.w.Write(append(js, '\n')); err != nil {
+		return err
+	}
+	r.meta.Steps = st.Step
+	return r.w.Flush()
+}
+
+// Close записывает итог запуска в meta.json.
+func (r *Recorder) Close(status, answer string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.meta.Finished = time.Now()
+	r.meta.Status = status
+	r.meta.Answer = answer
+	err := r.writeMeta()
+	if ferr := r.w.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (r *Recorder) writeMeta() error {
+	js, err := json.MarshalIndent(r.meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.dir, "meta.json"), js, 0o600)
+}
+
+func readMeta(dir string) (Meta, error) {
+	var m Meta
+	js, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		return m, err
+	}
+	return m, json.Unmarshal(js, &m)
+}
+
+// Load читает траекторию из каталога.
+func Load(dir string) (*Trajectory, error) {
+	meta, err := readMeta(dir)
+	if err != nil {
+		return nil, fmt.Errorf("trajectory %s: %w", dir, err)
+	}
+	f, err := os.Open(filepath.Join(dir, "steps.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := &Trajectory{Dir: dir, Meta: meta}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 256*1024), 16<<20)
+	for n := 1; sc.Scan(); n++ {
+		var st Step
+		if err := json.Unmarshal(sc.Bytes(), &st); err != nil {
+			return nil, fmt.Errorf("%s/steps.jsonl:%d: %w", dir, n, err)
+		}
+		t.Steps = append(t.Steps, st)
+	}
+	return t, sc.Err()
+}
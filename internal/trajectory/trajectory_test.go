@@ -0,0 +1,128 @@
+package trajectory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordLoad(t *testing.T) {
+	root := t.TempDir()
+	started := time.Date(2026, 10, 18, 15, 4, 5, 0, time.UTC)
+	r, err := Create(root, Meta{RunID: "run1", Task: "найти письмо", StartURL: "https://mail.example", Started: started})
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		st  Step
+		png []byte
+	}{
+		{Step{Step: 1, Action: Action{Tool: "click", Args: map[string]any{"selector": "#inbox"}, Source: "llm"}, Result: "ok"}, []byte("png")},
+		{Step{Step: 1, Action: Action{Tool: "scroll", Source: "fallback"}, Error: "timeout"}, nil},
+		{Step{Step: 2, Action: Action{Tool: "done", Source: "llm"}}, nil},
+	}
+	for _, s := range steps {
+		if err := r.Record(s.st, s.png); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close("success", "готово"); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := Load(r.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := tr.Meta; m.Task != "найти письмо" || m.Status != "success" || m.Answer != "готово" || m.Steps != 2 || m.Finished.IsZero() {
+		t.Errorf("Meta = %+v", m)
+	}
+	if len(tr.Steps) != len(steps) {
+		t.Fatalf("len(Steps) = %d, want %d", len(tr.Steps), len(steps))
+	}
+	for i, st := range tr.Steps {
+		if st.Seq != i+1 || st.Action.Tool != steps[i].st.Action.Tool {
+			t.Errorf("step %d = %+v", i, st)
+		}
+	}
+	if got := tr.Steps[0].Screenshot; got != filepath.Join("screenshots", "001.png") {
+		t.Errorf("Screenshot = %q", got)
+	}
+	if png, err := os.ReadFile(filepath.Join(r.Dir(), tr.Steps[0].Screenshot)); err != nil || string(png) != "png" {
+		t.Errorf("screenshot file = %q, %v", png, err)
+	}
+	if tr.Steps[1].Screenshot != "" || tr.Steps[1].Error != "timeout" {
+		t.Errorf("step 2 = %+v", tr.Steps[1])
+	}
+}
+
+func TestResumeAppends(t *testing.T) {
+	root := t.TempDir()
+	started := time.Date(2026, 10, 18, 15, 4, 5, 0, time.UTC)
+	r, err := Create(root, Meta{RunID: "run1", StartURL: "https://a.example", Started: started})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Record(Step{Step: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close("canceled", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Продолжение сохраняет начало запуска и сквозную нумерацию.
+	r, err = Create(root, Meta{RunID: "run1", StartURL: "https://b.example", Started: started.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Record(Step{Step: 2}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close("success", ""); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := Load(r.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tr.Meta.Started.Equal(started) || tr.Meta.StartURL != "https://a.example" {
+		t.Errorf("Meta = %+v", tr.Meta)
+	}
+	if len(tr.Steps) != 2 || tr.Steps[1].Seq != 2 {
+		t.Errorf("Steps = %+v", tr.Steps)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		meta  string
+		steps string
+		want  string
+	}{
+		{"no meta", "", "", "meta.json"},
+		{"bad step", `{"run_id":"x"}`, "{}\n{", "steps.jsonl:2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.meta != "" {
+				if err := os.WriteFile(filepath.Join(dir, "meta.json"), []byte(tt.meta), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "steps.jsonl"), []byte(tt.steps), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := Load(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := Create(t.TempDir(), Meta{}); err == nil {
+		t.Error("Create accepted empty run id")
+	}
+}
@@ -0,0 +1,395 @@
+// Package workflow описывает сценарии рутинных задач в YAML: последовательность
+// шагов (goto, click, type, press, scroll, extract, assert, wait, each, tool), которые
+// исполняет agent.RunWorkflow через обычные инструменты агента. Упавший шаг
+// может быть передан планировщику LLM для восстановления.
+//
+// Пример:
+//
+//	name: first-message
+//	task: открыть первое письмо во входящих
+//	start_url: https://mail.yandex.ru/
+//	vars: {folder: Входящие}
+//	steps:
+//	  - click: {role: link, name: "${folder}"}
+//	  - assert: {url_contains: "#inbox"}
+//	  - each:
+//	      rows: "[role=row]"
+//	      limit: 3
+//	      steps:
+//	        - extract: {selector: "${row.selector}", as: subject}
+package workflow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow — сценарий целиком.
+type Workflow struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	// Task — цель сценария человеческим языком; передаётся LLM при восстановлении.
+	Task     string            `yaml:"task,omitempty"`
+	StartURL string            `yaml:"start_url,omitempty"`
+	Vars     map[string]string `yaml:"vars,omitempty"`
+	Fallback Fallback          `yaml:"fallback"`
+	Steps    []Step            `yaml:"steps"`
+}
+
+// Fallback — восстановление упавших шагов через планировщик.
+type Fallback struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxSteps — лимит шагов агента на одно восстановление.
+	MaxSteps int `yaml:"max_steps"`
+}
+
+// Step — один шаг; заполнено ровно одно из полей действия.
+type Step struct {
+	Name    string        `yaml:"name,omitempty"`
+	Goto    string        `yaml:"goto,omitempty"`
+	Click   *Element      `yaml:"click,omitempty"`
+	Type    *Input        `yaml:"type,omitempty"`
+	Press   string        `yaml:"press,omitempty"`
+	Scroll  *Scroll       `yaml:"scroll,omitempty"`
+	Extract *Extract      `yaml:"extract,omitempty"`
+	Assert  *Assert       `yaml:"assert,omitempty"`
+	Wait    time.Duration `yaml:"wait,omitempty"`
+	Each    *Each         `yaml:"each,omitempty"`
+	// Tool — прямой вызов инструмента агента по имени, например open_first_main_item.
+	Tool *ToolCall `yaml:"tool,omitempty"`
+	// Fallback: false запрещает восстановление именно этого шага.
+	Fallback *bool `yaml:"fallback,omitempty"`
+}
+
+// Target — элемент страницы: CSS/Playwright-селектор, либо роль и доступное
+// имя, либо видимый текст.
+type Target struct {
+	Selector string `yaml:"selector,omitempty"`
+	Role     string `yaml:"role,omitempty"`
+	Name     string `yaml:"name,omitempty"`
+	Text     string `yaml:"text,omitempty"`
+	// Exact — точное совпадение имени/текста вместо поиска подстроки без учёта регистра.
+	Exact bool `yaml:"exact,omitempty"`
+}
+
+// Input — ввод текста в поле.
+type Input struct {
+	Target Target `yaml:",inline"`
+	Value  string `yaml:"value"`
+	Enter  bool   `yaml:"enter,omitempty"`
+}
+
+// Scroll — прокрутка на Y пикселей или до элемента.
+type Scroll struct {
+	Target Target  `yaml:",inline"`
+	Y      float64 `yaml:"y,omitempty"`
+}
+
+// Extract сохраняет текст (или атрибут) элемента в переменную As.
+// Без элемента сохраняется текст всей страницы.
+type Extract struct {
+	Target Target `yaml:",inline"`
+	Attr   string `yaml:"attr,omitempty"`
+	As     string `yaml:"as"`
+}
+
+// Assert — проверка состояния страницы; все заданные условия должны выполниться
+// в течение Timeout.
+type Assert struct {
+	URLContains   string   `yaml:"url_contains,omitempty"`
+	TitleContains string   `yaml:"title_contains,omitempty"`
+	TextContains  string   `yaml:"text_contains,omitempty"`
+	Visible       *Element `yaml:"visible,omitempty"`
+	Hidden        *Element `yaml:"hidden,omitempty"`
+	// Equals — два значения (обычно ${переменная} и ожидаемое), которые должны совпасть.
+	Equals  []string      `yaml:"equals,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	Message string        `yaml:"message,omitempty"`
+}
+
+// Each выполняет вложенные шаги для каждой строки списка Rows. Внутри доступны
+// переменные ${<as>.index} (с 1), ${<as>.text} и ${<as>.selector}.
+type Each struct {
+	Rows  string `yaml:"rows"`
+	As    string `yaml:"as,omitempty"`
+	Limit int    `yaml:"limit,omitempty"`
+	Steps []Step `yaml:"steps"`
+}
+
+// ToolCall — вызов инструмента; строковые аргументы проходят подстановку переменных.
+type ToolCall struct {
+	Name string         `yaml:"name"`
+	Args map[string]any `yaml:"args,omitempty"`
+}
+
+// DefaultFallbackSteps — лимит шагов восстановления по умолчанию.
+const DefaultFallbackSteps = 8
+
+// Element — Target, который в YAML можно записать просто строкой: она считается
+// видимым текстом (click: Входящие).
+type Element struct {
+	Target
+}
+
+func (e *Element) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		e.Text = n.Value
+		return nil
+	}
+	return n.Decode(&e.Target)
+}
+
+func (e Element) MarshalYAML() (any, error) {
+	return e.Target, nil
+}
+
+// IsZero — элемент не задан.
+func (t Target) IsZero() bool {
+	return t.Selector == "" && t.Role == "" && t.Text == "" && t.Name == ""
+}
+
+// Locator переводит Target в селектор Playwright, понятный инструментам агента.
+func (t Target) Locator() string {
+	switch {
+	case t.Selector != "":
+		return t.Selector
+	case t.Role != "":
+		name := t.Name
+		if name == "" {
+			name = t.Text
+		}
+		if name == "" {
+			return "role=" + t.Role
+		}
+		sel := "role=" + t.Role + "[name=" + strconv.Quote(name)
+		if t.Exact {
+			sel += "s"
+		}
+		return sel + "]"
+	case t.Text != "" || t.Name != "":
+		text := t.Text
+		if text == "" {
+			text = t.Name
+		}
+		if t.Exact {
+			return "text=" + strconv.Quote(text)
+		}
+		return "text=" + text
+	}
+	return ""
+}
+
+// Expand подставляет переменные во все поля.
+func (t Target) Expand(vars map[string]string) (Target, error) {
+	var err error
+	for _, f := range []*string{&t.Selector, &t.Role, &t.Name, &t.Text} {
+		if *f, err = Expand(*f, vars); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
+func (t Target) String() string {
+	switch {
+	case t.Selector != "":
+		return t.Selector
+	case t.Role != "":
+		return fmt.Sprintf("%s %q", t.Role, t.Name+t.Text)
+	default:
+		return strconv.Quote(t.Text + t.Name)
+	}
+}
+
+// Kind — имя действия шага.
+func (s Step) Kind() string {
+	switch {
+	case s.Goto != "":
+		return "goto"
+	case s.Click != nil:
+		return "click"
+	case s.Type != nil:
+		return "type"
+	case s.Press != "":
+		return "press"
+	case s.Scroll != nil:
+		return "scroll"
+	case s.Extract != nil:
+		return "extract"
+	case s.Assert != nil:
+		return "assert"
+	case s.Wait != 0:
+		return "wait"
+	case s.Each != nil:
+		return "each"
+	case s.Tool != nil:
+		return "tool"
+	}
+	return ""
+}
+
+// String — краткое описание шага для журнала и промпта восстановления.
+func (s Step) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	switch s.Kind() {
+	case "goto":
+		return "goto " + s.Goto
+	case "click":
+		return "click " + s.Click.String()
+	case "type":
+		return fmt.Sprintf("type %q into %s", s.Type.Value, s.Type.Target.String())
+	case "press":
+		return "press " + s.Press
+	case "scroll":
+		if !s.Scroll.Target.IsZero() {
+			return "scroll to " + s.Scroll.Target.String()
+		}
+		return fmt.Sprintf("scroll %v", s.Scroll.Y)
+	case "extract":
+		return "extract " + s.Extract.As
+	case "assert":
+		if s.Assert.Message != "" {
+			return "assert " + s.Assert.Message
+		}
+		return "assert"
+	case "wait":
+		return "wait " + s.Wait.String()
+	case "each":
+		return "each " + s.Each.Rows
+	case "tool":
+		return "tool " + s.Tool.Name
+	}
+	return "?"
+}
+
+func (s Step) count() int {
+	n := 0
+	for _, set := range []bool{s.Goto != "", s.Click != nil, s.Type != nil, s.Press != "", s.Scroll != nil,
+		s.Extract != nil, s.Assert != nil, s.Wait != 0, s.Each != nil, s.Tool != nil} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// Load читает сценарий из файла.
+func Load(path string) (*Workflow, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	wf, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return wf, nil
+}
+
+// Validate проверяет шаги: в каждом ровно одно действие с обязательными полями.
+func Validate(steps []Step) error {
+	return validate(steps, "steps")
+}
+
+// Parse разбирает и проверяет сценарий. Восстановление по умолчанию включено.
+func Parse(b []byte) (*Workflow, error) {
+	wf := &Workflow{Fallback: Fallback{Enabled: true, MaxSteps: DefaultFallbackSteps}}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(wf); err != nil {
+		return nil, err
+	}
+	if wf.Fallback.MaxSteps <= 0 {
+		wf.Fallback.MaxSteps = DefaultFallbackSteps
+	}
+	if err := Validate(wf.Steps); err != nil {
+		return nil, err
+	}
+	return wf, nil
+}
+
+func validate(steps []Step, path string) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("%s: no steps", path)
+	}
+	for i, s := range steps {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		if n := s.count(); n != 1 {
+			return fmt.Errorf("%s: expected exactly one action, got %d", p, n)
+		}
+		switch {
+		case s.Click != nil && s.Click.IsZero(),
+			s.Type != nil && s.Type.Target.IsZero():
+			return fmt.Errorf("%s: %s: no target", p, s.Kind())
+		case s.Tool != nil && s.Tool.Name == "":
+			return fmt.Errorf("%s: tool: name is required", p)
+		case s.Extract != nil && s.Extract.As == "":
+			return fmt.Errorf("%s: extract: as is required", p)
+		case s.Assert != nil && s.Assert.empty():
+			return fmt.Errorf("%s: assert: no conditions", p)
+		case s.Assert != nil && len(s.Assert.Equals) != 0 && len(s.Assert.Equals) != 2:
+			return fmt.Errorf("%s: assert: equals needs exactly two values", p)
+		case s.Each != nil:
+			if s.Each.Rows == "" {
+				return fmt.Errorf("%s: each: rows is required", p)
+			}
+			if err := validate(s.Each.Steps, p+".each.steps"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Assert) empty() bool {
+	return a.URLContains == "" && a.TitleContains == "" && a.TextContains == "" &&
+		a.Visible == nil && a.Hidden == nil && len(a.Equals) == 0
+}
+
+var reVar = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`)
+
+// ExpandArgs подставляет переменные в строковые значения аргументов.
+func ExpandArgs(args map[string]any, vars map[string]string) (map[string]any, error) {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if s, ok := v.(string); ok {
+			e, err := Expand(s, vars)
+			if err != nil {
+				return nil, err
+			}
+			v = e
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+// ErrUnknownVar — в строке есть ${имя}, для которого нет значения.
+var ErrUnknownVar = errors.New("unknown variable")
+
+// Expand подставляет ${имя} из vars.
+func Expand(s string, vars map[string]string) (string, error) {
+	var missing []string
+	out := reVar.ReplaceAllStringFunc(s, func(m string) string {
+		name := m[2 : len(m)-1]
+		v, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return out, fmt.Errorf("%w: %s", ErrUnknownVar, strings.Join(missing, ", "))
+	}
+	return out, nil
+}
@@ -0,0 +1,190 @@
+package workflow
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	src := `
+name: first-message
+task: открыть первое письмо
+start_url: https://mail.example/
+vars: {folder: Входящие}
+steps:
+  - click: Входящие
+  - type: {selector: "#q", value: "${folder}", enter: true}
+  - wait: 2s
+  - each:
+      rows: "[role=row]"
+      limit: 3
+      steps:
+        - extract: {selector: "${row.selector}", as: subject}
+  - tool: {name: open_first_main_item, args: {n: 1}}
+    fallback: false
+`
+	wf, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wf.Name != "first-message" || wf.StartURL != "https://mail.example/" || wf.Vars["folder"] != "Входящие" {
+		t.Errorf("header = %+v", wf)
+	}
+	if want := (Fallback{Enabled: true, MaxSteps: DefaultFallbackSteps}); wf.Fallback != want {
+		t.Errorf("Fallback = %+v, want %+v", wf.Fallback, want)
+	}
+	var kinds []string
+	for _, s := range wf.Steps {
+		kinds = append(kinds, s.Kind())
+	}
+	if want := []string{"click", "type", "wait", "each", "tool"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("kinds = %v, want %v", kinds, want)
+	}
+	if got := wf.Steps[0].Click.Text; got != "Входящие" {
+		t.Errorf("click as a string: Text = %q", got)
+	}
+	if got := wf.Steps[2].Wait; got != 2*time.Second {
+		t.Errorf("wait = %v", got)
+	}
+	if e := wf.Steps[3].Each; e.Limit != 3 || len(e.Steps) != 1 || e.Steps[0].Extract.As != "subject" {
+		t.Errorf("each = %+v", e)
+	}
+	if s := wf.Steps[4]; s.Tool.Name != "open_first_main_item" || s.Fallback == nil || *s.Fallback {
+		t.Errorf("tool step = %+v", s)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"unknown step kind", "steps:\n  - hover: '#a'", "field hover not found"},
+		{"unknown field", "name: x\ntimeout: 1s\nsteps:\n  - goto: https://a.example/", "field timeout not found"},
+		{"no steps", "name: x", "steps: no steps"},
+		{"empty step", "steps:\n  - name: пусто", "steps[0]: expected exactly one action, got 0"},
+		{"two actions", "steps:\n  - goto: https://a.example/\n    press: Enter", "steps[0]: expected exactly one action, got 2"},
+		{"click without target", "steps:\n  - click: {exact: true}", "steps[0]: click: no target"},
+		{"type without target", "steps:\n  - type: {value: x}", "steps[0]: type: no target"},
+		{"tool without name", "steps:\n  - tool: {args: {a: 1}}", "steps[0]: tool: name is required"},
+		{"extract without as", "steps:\n  - extract: {selector: h1}", "steps[0]: extract: as is required"},
+		{"assert without conditions", "steps:\n  - assert: {timeout: 1s}", "steps[0]: assert: no conditions"},
+		{"assert equals arity", "steps:\n  - assert: {equals: [a]}", "steps[0]: assert: equals needs exactly two values"},
+		{"each without rows", "steps:\n  - each: {steps: [{press: Enter}]}", "steps[0]: each: rows is required"},
+		{"nested error path", "steps:\n  - press: Enter\n  - each:\n      rows: li\n      steps: [{extract: {}}]", "steps[1].each.steps[0]: extract: as is required"},
+		{"nested no steps", "steps:\n  - each: {rows: li}", "steps[0].each.steps: no steps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFallbackMaxSteps(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Fallback
+	}{
+		{"fallback: {enabled: false}\nsteps: [{press: Enter}]", Fallback{MaxSteps: DefaultFallbackSteps}},
+		{"fallback: {enabled: true, max_steps: 3}\nsteps: [{press: Enter}]", Fallback{Enabled: true, MaxSteps: 3}},
+		{"fallback: {max_steps: -1}\nsteps: [{press: Enter}]", Fallback{Enabled: true, MaxSteps: DefaultFallbackSteps}},
+	}
+	for _, tt := range tests {
+		wf, err := Parse([]byte(tt.src))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.src, err)
+		}
+		if wf.Fallback != tt.want {
+			t.Errorf("%q: Fallback = %+v, want %+v", tt.src, wf.Fallback, tt.want)
+		}
+	}
+}
+
+func TestLocator(t *testing.T) {
+	tests := []struct {
+		name string
+		t    Target
+		want string
+	}{
+		{"selector wins", Target{Selector: "#a", Role: "button", Text: "OK"}, "#a"},
+		{"role only", Target{Role: "textbox"}, "role=textbox"},
+		{"role and name", Target{Role: "link", Name: "Входящие"}, `role=link[name="Входящие"]`},
+		{"role and text", Target{Role: "button", Text: "OK"}, `role=button[name="OK"]`},
+		{"role exact", Target{Role: "link", Name: "Спам", Exact: true}, `role=link[name="Спам"s]`},
+		{"text", Target{Text: "Написать"}, "text=Написать"},
+		{"name without role", Target{Name: "Написать"}, "text=Написать"},
+		{"text exact", Target{Text: `Он сказал "да"`, Exact: true}, `text="Он сказал \"да\""`},
+		{"zero", Target{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.t.Locator(); got != tt.want {
+				t.Errorf("Locator() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"folder": "Входящие", "row.selector": "li >> nth=0", "n": "2"}
+	tests := []struct {
+		in      string
+		want    string
+		unknown bool
+	}{
+		{"без переменных", "без переменных", false},
+		{"${folder}", "Входящие", false},
+		{"${row.selector} >> a", "li >> nth=0 >> a", false},
+		{"${n}/${n}", "2/2", false},
+		{"$folder {folder} $ {folder}", "$folder {folder} $ {folder}", false},
+		{"${missing} и ${folder}", " и Входящие", true},
+	}
+	for _, tt := range tests {
+		got, err := Expand(tt.in, vars)
+		if got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if errors.Is(err, ErrUnknownVar) != tt.unknown {
+			t.Errorf("Expand(%q) error = %v", tt.in, err)
+		}
+	}
+	if _, err := Expand("${a} ${b}", nil); err == nil || err.Error() != "unknown variable: a, b" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	vars := map[string]string{"to": "a@b.io"}
+	got, err := ExpandArgs(map[string]any{"text": "to ${to}", "n": 1, "enter": true}, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"text": "to a@b.io", "n": 1, "enter": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandArgs = %v, want %v", got, want)
+	}
+	if _, err := ExpandArgs(map[string]any{"text": "${cc}"}, vars); !errors.Is(err, ErrUnknownVar) {
+		t.Errorf("error = %v, want ErrUnknownVar", err)
+	}
+}
+
+func TestTargetExpand(t *testing.T) {
+	vars := map[string]string{"role": "link", "folder": "Спам"}
+	got, err := Target{Role: "${role}", Name: "${folder}", Exact: true}.Expand(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Locator() != `role=link[name="Спам"s]` {
+		t.Errorf("Locator() = %q", got.Locator())
+	}
+	if _, err := (Target{Selector: "#${id}"}).Expand(vars); !errors.Is(err, ErrUnknownVar) {
+		t.Errorf("error = %v, want ErrUnknownVar", err)
+	}
+}